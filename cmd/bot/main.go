@@ -1,19 +1,30 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"sort"
+	"syscall"
+	"time"
+	_ "time/tzdata" // база часовых поясов для app.timezone, если в образе ее нет
 
 	"bronivik/internal/bot"
 	"bronivik/internal/config"
 	"bronivik/internal/database"
+	"bronivik/internal/events"
 	"bronivik/internal/google"
-	"bronivik/internal/models"
-	"gopkg.in/yaml.v2"
+	"bronivik/internal/metrics"
+	"bronivik/internal/repository"
 )
 
+// availabilityCacheTTL ограничивает время жизни закэшированной занятости на случай пропущенной инвалидации
+const availabilityCacheTTL = 30 * time.Second
+
+// shutdownTimeout сколько ждать завершения задач синхронизации с Google Sheets при остановке
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// Загрузка конфигурации
 	configPath := os.Getenv("CONFIG_PATH")
@@ -29,39 +40,21 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
-
-	if _, err := os.Stat("configs/items.yaml"); os.IsNotExist(err) {
-		log.Fatalf("Config file does not exist: %s", "configs/items.yaml")
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
 	}
 
-	// Загрузка позиций из отдельного файла
-	itemsData, err := os.ReadFile("configs/items.yaml")
-	if err != nil {
-		log.Fatal("Ошибка чтения items.yaml:", err)
+	itemsPath := config.ItemsPath()
+	if _, err := os.Stat(itemsPath); os.IsNotExist(err) {
+		log.Fatalf("Config file does not exist: %s", itemsPath)
 	}
 
-	var itemsConfig struct {
-		Items []models.Item `yaml:"items"`
-	}
-	if err := yaml.Unmarshal(itemsData, &itemsConfig); err != nil {
-		log.Fatal("Ошибка парсинга items.yaml:", err)
+	// Загрузка позиций из отдельного файла (отсортированы по Order)
+	items, err := config.LoadItems(itemsPath)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки %s: %v", itemsPath, err)
 	}
 
-	// Сортируем items по полю Order (по возрастанию)
-	sort.Slice(itemsConfig.Items, func(i, j int) bool {
-		// Если Order не задан, считаем его 0 (будет в начале)
-		orderI := itemsConfig.Items[i].Order
-		orderJ := itemsConfig.Items[j].Order
-
-		// Сначала сортируем по Order
-		if orderI != orderJ {
-			return orderI < orderJ
-		}
-
-		// Если Order одинаковый, сортируем по ID для стабильности
-		return itemsConfig.Items[i].ID < itemsConfig.Items[j].ID
-	})
-
 	// Создаем необходимые директории
 	if cfg == nil {
 		log.Fatal("Cfg configuration is missing in config")
@@ -82,7 +75,7 @@ func main() {
 	defer db.Close()
 
 	// Устанавливаем items в базу данных
-	db.SetItems(itemsConfig.Items)
+	db.SetItems(items)
 
 	if cfg.Telegram.BotToken == "YOUR_BOT_TOKEN_HERE" {
 		log.Fatal("Задайте токен бота в config.yaml")
@@ -108,15 +101,62 @@ func main() {
 		log.Fatalf("Warning: Google Sheets connection test failed: %v", err)
 	} else {
 		sheetsService = service
+		sheetsService.SetDryRun(cfg.Google.DryRun)
 		log.Println("Google Sheets service initialized successfully")
+		if cfg.Google.DryRun {
+			log.Println("Google Sheets dry-run mode: writes are only logged")
+		}
 	}
 
 	// Создание и запуск бота
-	telegramBot, err := bot.NewBot(cfg.Telegram.BotToken, cfg, itemsConfig.Items, db, sheetsService)
+	telegramBot, err := bot.NewBot(cfg.Telegram.BotToken, cfg, items, db, sheetsService)
 	if err != nil {
 		log.Fatal("Ошибка создания бота:", err)
 	}
 
+	telegramBot.SetItemsPath(itemsPath)
+
+	if cfg.Webhooks.URL != "" {
+		telegramBot.SetEventPublisher(events.NewWebhookPublisher(cfg.Webhooks.URL, cfg.Webhooks.Secret))
+		log.Printf("Webhook events enabled: %s", cfg.Webhooks.URL)
+	}
+
+	// Redis используется для ограничения частоты заявок и кэша доступности, если доступен
+	if cfg.Redis.Address != "" {
+		redisClient := repository.NewRedisClient(cfg.Redis)
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		err := repository.Ping(ctx, redisClient)
+		cancel()
+		if err != nil {
+			log.Printf("Warning: Redis unavailable, using in-memory rate limiter: %v", err)
+			repository.Close(redisClient)
+		} else {
+			defer repository.Close(redisClient)
+			telegramBot.SetRedisClient(redisClient)
+			db.SetAvailabilityCache(repository.NewRedisAvailabilityCache(redisClient, availabilityCacheTTL))
+			log.Println("Redis connected")
+		}
+	}
+
+	metrics.Register()
+	if cfg.Monitoring.PrometheusEnabled {
+		metrics.Serve(cfg.Monitoring.PrometheusPort)
+	}
+
+	// По SIGINT/SIGTERM дожидаемся задач Google Sheets, затем Start возвращает управление
+	// и отложенные вызовы закрывают Redis и БД
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-signals
+
+		log.Printf("Получен сигнал %v, остановка бота...", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		telegramBot.Shutdown(ctx)
+	}()
+
 	log.Println("Бот запущен...")
 	telegramBot.Start()
+	log.Println("Бот остановлен")
 }
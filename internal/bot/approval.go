@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// requiresDualApproval проверяет, что заявки на аппарат подтверждают два разных менеджера
+func (b *Bot) requiresDualApproval(itemID int64) bool {
+	item, ok := b.findItemByID(itemID)
+	return ok && item.RequiresDualApproval
+}
+
+// firstApproval записывает первое подтверждение заявки по кнопке менеджера и сообщает ему результат
+func (b *Bot) firstApproval(booking *models.Booking, managerID int64) {
+	if err := b.startDualApproval(booking, managerID); err != nil {
+		log.Printf("Error approving booking %d: %v", booking.ID, err)
+		b.sendMessage(managerID, "Ошибка при подтверждении заявки")
+		return
+	}
+
+	b.sendMessage(managerID, "☑️ Первое подтверждение принято. Заявка ждет подтверждения другого менеджера.")
+}
+
+// startDualApproval переводит заявку в статус awaiting_approval и отправляет ее остальным менеджерам
+func (b *Bot) startDualApproval(booking *models.Booking, managerID int64) error {
+	err := b.db.SetBookingAwaitingApprovalWithVersion(context.Background(), booking.ID, booking.Version, managerID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Manager %d gave first approval to booking %d", managerID, booking.ID)
+
+	booking.Status = "awaiting_approval"
+	booking.FirstApprovedBy = managerID
+	b.notifySecondApprovers(booking)
+
+	b.UpdateBookingStatusInSheets(booking.ID)
+	b.SyncScheduleToSheets()
+	return nil
+}
+
+// notifySecondApprovers просит подтвердить заявку всех менеджеров, кроме подтвердившего ее первым
+func (b *Bot) notifySecondApprovers(booking *models.Booking) {
+	text := fmt.Sprintf("👥 Нужно второе подтверждение заявки #%d\n\n🏢 Позиция: %s\n📅 Дата: %s\n👤 Клиент: %s\n📱 Телефон: %s\n☑️ Первое подтверждение: %s",
+		booking.ID, booking.ItemName, b.bookingPeriod(*booking), booking.UserName, booking.Phone,
+		b.managerDisplayName(booking.FirstApprovedBy))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", fmt.Sprintf("confirm_%d", booking.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Отклонить", fmt.Sprintf("reject_%d", booking.ID)),
+	))
+
+	notified := 0
+	for _, managerID := range b.config.Managers {
+		if managerID == booking.FirstApprovedBy {
+			continue
+		}
+		msg := tgbotapi.NewMessage(managerID, text)
+		msg.ReplyMarkup = keyboard
+		b.bot.Send(msg)
+		notified++
+	}
+
+	if notified == 0 {
+		log.Printf("Booking %d awaits second approval, but there are no other managers in config", booking.ID)
+	}
+}
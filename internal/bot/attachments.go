@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// messageAttachment возвращает FileID и тип фото или документа из сообщения; пустой FileID - вложения нет
+func messageAttachment(message *tgbotapi.Message) (string, string) {
+	if len(message.Photo) > 0 {
+		// Telegram присылает несколько размеров фото, последний - самый крупный
+		return message.Photo[len(message.Photo)-1].FileID, models.AttachmentPhoto
+	}
+	if message.Document != nil {
+		return message.Document.FileID, models.AttachmentDocument
+	}
+	return "", ""
+}
+
+// acceptsAttachment проверяет, можно ли на текущем шаге приложить файл к заявке
+func acceptsAttachment(state *models.UserState) bool {
+	return state != nil && (state.CurrentStep == StatePhoneNumber || state.CurrentStep == StateEnterComment)
+}
+
+// handleBookingAttachment сохраняет присланное клиентом фото или документ в состоянии заявки.
+// Возвращает true, если подпись к файлу нужно обработать как обычный ввод текущего шага.
+// На шагах, где вложения не ожидаются, файл молча игнорируется.
+func (b *Bot) handleBookingAttachment(update tgbotapi.Update, state *models.UserState, fileID, attachmentType string) bool {
+	if !acceptsAttachment(state) {
+		return false
+	}
+
+	userID := update.Message.From.ID
+	state.TempData["attachment_file_id"] = fileID
+	state.TempData["attachment_type"] = attachmentType
+	b.setUserState(userID, state.CurrentStep, state.TempData)
+	b.sendMessage(update.Message.Chat.ID, b.t(userID, "attachment_saved"))
+
+	caption := strings.TrimSpace(update.Message.Caption)
+	if caption == "" {
+		return false
+	}
+	update.Message.Text = canonicalButton(caption)
+	return true
+}
+
+// attachmentTitle название типа вложения для менеджера
+func attachmentTitle(attachmentType string) string {
+	if attachmentType == models.AttachmentPhoto {
+		return "фото"
+	}
+	return "документ"
+}
+
+// handleShowAttachment отправляет менеджеру вложение заявки: attachment:<id>
+func (b *Bot) handleShowAttachment(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	bookingID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "attachment:"), 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
+
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil || booking.AttachmentFileID == "" {
+		b.sendMessage(callback.Message.Chat.ID, "Вложение не найдено")
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	caption := fmt.Sprintf("📎 Вложение к заявке #%d", booking.ID)
+	file := tgbotapi.FileID(booking.AttachmentFileID)
+
+	var sendErr error
+	if booking.AttachmentType == models.AttachmentPhoto {
+		photo := tgbotapi.NewPhoto(chatID, file)
+		photo.Caption = caption
+		_, sendErr = b.bot.Send(photo)
+	} else {
+		document := tgbotapi.NewDocument(chatID, file)
+		document.Caption = caption
+		_, sendErr = b.bot.Send(document)
+	}
+	if sendErr != nil {
+		log.Printf("Error sending attachment of booking %d: %v", booking.ID, sendErr)
+		b.sendMessage(chatID, "Не удалось отправить вложение")
+	}
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"bronivik/internal/config"
+	"bronivik/internal/database"
+)
+
+func TestIsBlacklistedCombinesConfigAndDB(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "bookings.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	b := &Bot{config: &config.Config{Blacklist: []int64{100}}, db: db}
+	ctx := context.Background()
+
+	if err := db.SetUserBlacklist(ctx, 200, true); err != nil {
+		t.Fatalf("SetUserBlacklist: %v", err)
+	}
+
+	tests := []struct {
+		userID     int64
+		blacklist  bool
+		fromConfig bool
+	}{
+		{100, true, true},
+		{200, true, false},
+		{300, false, false},
+	}
+	for _, tt := range tests {
+		if got := b.isBlacklisted(tt.userID); got != tt.blacklist {
+			t.Errorf("isBlacklisted(%d) = %v, want %v", tt.userID, got, tt.blacklist)
+		}
+		if got := b.isConfigBlacklisted(tt.userID); got != tt.fromConfig {
+			t.Errorf("isConfigBlacklisted(%d) = %v, want %v", tt.userID, got, tt.fromConfig)
+		}
+	}
+
+	// Снятие флага в БД не разблокирует пользователя из config.yaml
+	if err := db.SetUserBlacklist(ctx, 100, false); err != nil {
+		t.Fatalf("SetUserBlacklist: %v", err)
+	}
+	if !b.isBlacklisted(100) {
+		t.Error("user from config blacklist was unblocked by the DB flag")
+	}
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// broadcastInterval пауза между сообщениями рассылки: Telegram допускает около 30 сообщений в секунду
+const broadcastInterval = time.Second / 25
+
+// handleBroadcastCommand показывает текст рассылки и просит менеджера подтвердить отправку
+func (b *Bot) handleBroadcastCommand(update tgbotapi.Update, text string) {
+	if text == "" {
+		b.sendMessage(update.Message.Chat.ID, "Укажите текст рассылки, например: /broadcast Мы закрыты 1 мая")
+		return
+	}
+
+	b.setUserState(update.Message.From.ID, "manager_broadcast_confirm", map[string]interface{}{
+		"broadcast_text": text,
+	})
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+		fmt.Sprintf("📢 Отправить всем пользователям сообщение?\n\n%s\n\nОтменить рассылку после отправки будет нельзя.", text))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Отправить", "broadcast:send"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отмена", "broadcast:cancel"),
+		),
+	)
+	b.bot.Send(msg)
+}
+
+// handleBroadcastConfirm запускает или отменяет подготовленную рассылку.
+// Формат callback: broadcast:<send|cancel>
+func (b *Bot) handleBroadcastConfirm(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	state := b.getUserState(callback.From.ID)
+	if state == nil || state.CurrentStep != "manager_broadcast_confirm" {
+		b.sendMessage(callback.Message.Chat.ID, "Рассылка устарела. Начните заново: /broadcast <текст>")
+		return
+	}
+
+	text, _ := state.TempData["broadcast_text"].(string)
+	b.clearUserState(callback.From.ID)
+
+	// Убираем кнопки, чтобы рассылку нельзя было запустить повторно
+	b.bot.Send(tgbotapi.NewEditMessageReplyMarkup(callback.Message.Chat.ID, callback.Message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+
+	if strings.TrimPrefix(callback.Data, "broadcast:") != "send" || text == "" {
+		b.sendMessage(callback.Message.Chat.ID, "Рассылка отменена")
+		return
+	}
+
+	b.sendMessage(callback.Message.Chat.ID, "📤 Рассылка началась, по завершении придет отчет")
+	go b.runBroadcast(callback.Message.Chat.ID, callback.From.ID, text)
+}
+
+// runBroadcast отправляет сообщение всем пользователям, кроме заблокированных,
+// и сообщает менеджеру итог
+func (b *Bot) runBroadcast(chatID, managerID int64, text string) {
+	users, err := b.db.GetAllUsers(context.Background())
+	if err != nil {
+		log.Printf("Error getting users for broadcast: %v", err)
+		b.sendMessage(chatID, "Ошибка при получении списка пользователей")
+		return
+	}
+
+	log.Printf("Manager %d started broadcast to %d users", managerID, len(users))
+
+	delivered, failed, skipped := 0, 0, 0
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	for _, user := range users {
+		if user.IsBlacklisted || b.isBlacklisted(user.TelegramID) {
+			skipped++
+			continue
+		}
+		blocked, err := b.db.IsUserBlockedBot(context.Background(), user.TelegramID)
+		if err != nil {
+			log.Printf("Error checking if user %d blocked the bot: %v", user.TelegramID, err)
+		}
+		if blocked {
+			skipped++
+			continue
+		}
+
+		<-ticker.C
+		if _, err := b.bot.Send(tgbotapi.NewMessage(user.TelegramID, text)); err != nil {
+			b.handleSendError(user.TelegramID, err)
+			failed++
+			continue
+		}
+		delivered++
+	}
+
+	log.Printf("Broadcast by manager %d finished: delivered %d, failed %d, skipped %d", managerID, delivered, failed, skipped)
+	b.sendMessage(chatID, fmt.Sprintf("📢 Рассылка завершена\n\n✅ Доставлено: %d\n❌ Не доставлено: %d\n⏭ Пропущено (черный список или бот заблокирован): %d",
+		delivered, failed, skipped))
+}
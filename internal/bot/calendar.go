@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// calendarIgnore callback для некликабельных кнопок календаря
+const calendarIgnore = "cal_ignore"
+
+var calendarMonths = []string{
+	"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь",
+}
+
+var calendarWeekdays = []string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
+
+// sendDateCalendar отправляет календарь для выбора даты бронирования аппарата
+func (b *Bot) sendDateCalendar(chatID int64, item models.Item) {
+	now := b.now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	msg := tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("📅 Выберите дату в календаре или введите её вручную в формате %s:", b.dateFormatHint(chatID)))
+	msg.ReplyMarkup = b.buildCalendarKeyboard(item, month)
+	b.bot.Send(msg)
+}
+
+// buildCalendarKeyboard строит сетку месяца. Выходные дни (isClosedDay) отмечаются 🚫,
+// прошедшие даты и даты вне окна бронирования - точкой; ни те, ни другие не выбираются.
+func (b *Bot) buildCalendarKeyboard(item models.Item, month time.Time) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s %d", calendarMonths[month.Month()-1], month.Year()), calendarIgnore),
+	))
+
+	var weekdays []tgbotapi.InlineKeyboardButton
+	for _, day := range calendarWeekdays {
+		weekdays = append(weekdays, tgbotapi.NewInlineKeyboardButtonData(day, calendarIgnore))
+	}
+	rows = append(rows, weekdays)
+
+	// Понедельник - первый день недели
+	offset := (int(month.Weekday()) + 6) % 7
+	daysInMonth := month.AddDate(0, 1, -1).Day()
+
+	var week []tgbotapi.InlineKeyboardButton
+	for i := 0; i < offset; i++ {
+		week = append(week, tgbotapi.NewInlineKeyboardButtonData(" ", calendarIgnore))
+	}
+
+	for day := 1; day <= daysInMonth; day++ {
+		date := time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, time.UTC)
+
+		switch {
+		case b.isClosedDay(date):
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData("🚫", calendarIgnore))
+		case b.validateBookingDate(item, date) == nil:
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(day),
+				fmt.Sprintf("cal_day:%d:%s", item.ID, date.Format("2006-01-02"))))
+		default:
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData("·", calendarIgnore))
+		}
+
+		if len(week) == 7 {
+			rows = append(rows, week)
+			week = nil
+		}
+	}
+	if len(week) > 0 {
+		for len(week) < 7 {
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData(" ", calendarIgnore))
+		}
+		rows = append(rows, week)
+	}
+
+	// Следующий месяц показываем, только если в нем есть доступные дни
+	nextMonth := month.AddDate(0, 1, 0)
+	hasNext := b.validateBookingDate(item, nextMonth) == nil ||
+		b.validateBookingDate(item, nextMonth.AddDate(0, 1, -1)) == nil
+
+	now := b.now()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	hasPrev := month.After(currentMonth)
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if hasPrev {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀️",
+			"cal_nav:"+month.AddDate(0, -1, 0).Format("2006-01")))
+	}
+	if hasNext {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("▶️",
+			"cal_nav:"+nextMonth.Format("2006-01")))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleCalendarNav переключает месяц в календаре
+func (b *Bot) handleCalendarNav(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+
+	month, err := time.Parse("2006-01", strings.TrimPrefix(callback.Data, "cal_nav:"))
+	if err != nil {
+		log.Printf("Error parsing calendar month: %v", err)
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if state == nil || state.CurrentStep != StateWaitingDate {
+		b.sendMessage(callback.Message.Chat.ID, "Сессия устарела. Начните заново.")
+		return
+	}
+
+	item, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(callback.Message.Chat.ID, b.t(callback.From.ID, "err_item_not_selected"))
+		return
+	}
+
+	editMarkup := tgbotapi.NewEditMessageReplyMarkup(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		b.buildCalendarKeyboard(item, month),
+	)
+	b.bot.Send(editMarkup)
+}
+
+// handleCalendarDay обрабатывает выбор дня в календаре так же, как ввод даты текстом
+func (b *Bot) handleCalendarDay(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 3 {
+		return
+	}
+
+	itemID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		log.Printf("Error parsing calendar item id: %v", err)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", parts[2])
+	if err != nil {
+		log.Printf("Error parsing calendar date: %v", err)
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if state == nil || state.CurrentStep != StateWaitingDate {
+		b.sendMessage(callback.Message.Chat.ID, "Сессия устарела. Начните заново.")
+		return
+	}
+
+	item, ok := state.TempData["selected_item"].(models.Item)
+	if !ok || item.ID != itemID {
+		b.sendMessage(callback.Message.Chat.ID, "Календарь относится к другому аппарату. Выберите аппарат заново.")
+		return
+	}
+
+	// Переиспользуем обработку текстового ввода даты
+	messageUpdate := tgbotapi.Update{
+		Message: &tgbotapi.Message{
+			From: callback.From,
+			Chat: callback.Message.Chat,
+			Text: date.Format(b.dateLayout()),
+		},
+	}
+	b.handleDateInput(messageUpdate, date.Format(b.dateLayout()), state)
+
+	if newState := b.getUserState(callback.From.ID); newState != nil && newState.CurrentStep != StateWaitingDate {
+		editMsg := tgbotapi.NewEditMessageText(
+			callback.Message.Chat.ID,
+			callback.Message.MessageID,
+			fmt.Sprintf("📅 Выбрана дата: %s", date.Format(b.dateLayout())),
+		)
+		b.bot.Send(editMsg)
+	}
+}
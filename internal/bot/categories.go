@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// otherCategory категория для аппаратов без category, если у остальных она задана
+const otherCategory = "Другое"
+
+// hasItemCategories проверяет, задана ли категория хотя бы у одного аппарата
+func (b *Bot) hasItemCategories() bool {
+	for _, item := range b.bookableItems() {
+		if item.Category != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// itemCategories возвращает категории в порядке первого появления в items.yaml.
+// Аппараты без категории собираются в "Другое" в конце списка.
+func (b *Bot) itemCategories() []string {
+	var categories []string
+	seen := make(map[string]bool)
+	hasOther := false
+
+	for _, item := range b.bookableItems() {
+		if item.Category == "" {
+			hasOther = true
+			continue
+		}
+		if !seen[item.Category] {
+			seen[item.Category] = true
+			categories = append(categories, item.Category)
+		}
+	}
+
+	if hasOther {
+		categories = append(categories, otherCategory)
+	}
+	return categories
+}
+
+// itemsInCategory возвращает аппараты категории с сохранением порядка
+func (b *Bot) itemsInCategory(category string) []models.Item {
+	var items []models.Item
+	for _, item := range b.bookableItems() {
+		itemCategory := item.Category
+		if itemCategory == "" {
+			itemCategory = otherCategory
+		}
+		if itemCategory == category {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// categoriesPage формирует список категорий. В callback передается индекс категории,
+// потому что название может не поместиться в 64 байта callback data.
+func (b *Bot) categoriesPage() (string, tgbotapi.InlineKeyboardMarkup) {
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for i, category := range b.itemCategories() {
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%s (%d)", category, len(b.itemsInCategory(category))),
+				fmt.Sprintf("item_category:%d", i),
+			),
+		))
+	}
+
+	keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад в меню", "back_to_main"),
+	))
+
+	return "🗂 *Выберите категорию аппаратов:*", tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+}
+
+// handleItemCategory показывает аппараты выбранной категории.
+// Формат callback: item_category:<индекс>[:<страница>]
+func (b *Bot) handleItemCategory(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "item_category:"), ":")
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		log.Printf("Error parsing category index: %v", err)
+		return
+	}
+
+	categories := b.itemCategories()
+	if index < 0 || index >= len(categories) {
+		text, markup := b.categoriesPage()
+		b.editItemsMessage(callback, text, markup)
+		return
+	}
+
+	page := 0
+	if len(parts) > 1 {
+		page, err = strconv.Atoi(parts[1])
+		if err != nil {
+			log.Printf("Error parsing page: %v", err)
+			return
+		}
+	}
+
+	text, markup := itemsPage(b.itemsInCategory(categories[index]), page,
+		fmt.Sprintf("item_category:%d:", index), true)
+	text = fmt.Sprintf("🗂 *%s*\n\n%s", categories[index], text)
+	b.editItemsMessage(callback, text, markup)
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"bronivik/internal/database"
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// checkinTokenBytes 6 случайных байт дают код из 10 символов base32
+const checkinTokenBytes = 6
+
+// checkinQRSize размер стороны PNG с QR-кодом регистрации в пикселях
+const checkinQRSize = 256
+
+// newCheckinToken генерирует случайный код регистрации клиента
+func newCheckinToken() (string, error) {
+	buf := make([]byte, checkinTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf), nil
+}
+
+// issueCheckinToken выдает код регистрации подтвержденной заявке. Пустая строка - код выдать не удалось.
+func (b *Bot) issueCheckinToken(booking *models.Booking) string {
+	token, err := newCheckinToken()
+	if err != nil {
+		log.Printf("Error generating checkin token for booking %d: %v", booking.ID, err)
+		return ""
+	}
+	if err := b.db.SetBookingCheckinToken(context.Background(), booking.ID, token); err != nil {
+		log.Printf("Error saving checkin token for booking %d: %v", booking.ID, err)
+		return ""
+	}
+	return token
+}
+
+// checkinQRCode кодирует код регистрации в PNG с QR-кодом
+func checkinQRCode(token string) ([]byte, error) {
+	return qrcode.Encode(token, qrcode.Medium, checkinQRSize)
+}
+
+// sendCheckinQR отправляет клиенту подтверждение text фотографией с QR-кодом регистрации.
+// false - фото не отправлено (нет чата клиента, тихие часы или ошибка кодирования), и text нужно
+// отправить обычным уведомлением: отложенные уведомления хранятся только текстом.
+func (b *Bot) sendCheckinQR(booking *models.Booking, text, token string) bool {
+	if !hasClientChat(booking) {
+		return false
+	}
+	if _, deferred := b.notificationDeferredUntil(true); deferred {
+		return false
+	}
+
+	png, err := checkinQRCode(token)
+	if err != nil {
+		log.Printf("Error generating checkin QR code for booking %d: %v", booking.ID, err)
+		return false
+	}
+
+	photo := tgbotapi.NewPhoto(booking.UserID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("checkin_%d.png", booking.ID),
+		Bytes: png,
+	})
+	photo.Caption = text
+	b.sendNotification(booking.UserID, photo)
+	return true
+}
+
+// handleCheckinCommand отмечает приход клиента по коду из подтверждения и завершает заявку.
+// Код одноразовый. Формат: /checkin <код>
+func (b *Bot) handleCheckinCommand(update tgbotapi.Update, arg string) {
+	token := strings.ToUpper(strings.TrimSpace(arg))
+	if token == "" {
+		b.sendMessage(update.Message.Chat.ID, "Укажите код из подтверждения клиента, например: /checkin ABCDE12345")
+		return
+	}
+
+	booking, err := b.db.ConsumeCheckinToken(context.Background(), token)
+	if errors.Is(err, database.ErrCheckinTokenNotFound) {
+		b.sendMessage(update.Message.Chat.ID, "❌ Код не найден, уже использован или заявка не подтверждена")
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking in by token: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при регистрации клиента")
+		return
+	}
+
+	log.Printf("Manager %d checked in booking %d", update.Message.From.ID, booking.ID)
+	b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("🎫 Заявка #%d: %s, %s\n👤 %s, 📱 %s",
+		booking.ID, booking.ItemName, b.bookingPeriod(*booking), booking.UserName, b.formatPhoneForDisplay(booking.Phone)))
+	b.completeBooking(booking, update.Message.From.ID)
+}
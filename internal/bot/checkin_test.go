@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"bronivik/internal/config"
+	"bronivik/internal/models"
+)
+
+func TestCheckinQRCodeIsPNG(t *testing.T) {
+	data, err := checkinQRCode("ABCDE12345")
+	if err != nil {
+		t.Fatalf("checkinQRCode: %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("QR code is not a PNG: %v", err)
+	}
+	if size := img.Bounds().Dx(); size != checkinQRSize {
+		t.Errorf("QR code width = %d, want %d", size, checkinQRSize)
+	}
+}
+
+func TestSendCheckinQRFallsBackToTextInQuietHours(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	setTestNow(t, time.Date(2030, 3, 10, 23, 30, 0, 0, loc))
+
+	cfg := &config.Config{}
+	cfg.Notifications.QuietHours = config.QuietHoursConfig{Start: 22, End: 8}
+	b := &Bot{config: cfg, location: loc}
+
+	// Отложенные уведомления хранятся текстом, поэтому фото в тихие часы не отправляется,
+	// а обращения к Telegram API (b.bot не задан) не происходит
+	booking := &models.Booking{ID: 1, UserID: 100}
+	if b.sendCheckinQR(booking, "✅ Заявка подтверждена", "ABCDE12345") {
+		t.Error("sendCheckinQR must not send a photo during quiet hours")
+	}
+
+	// Заявка без Telegram клиента
+	if b.sendCheckinQR(&models.Booking{ID: 2}, "✅ Заявка подтверждена", "ABCDE12345") {
+		t.Error("sendCheckinQR must not send a photo without a client chat")
+	}
+}
@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// defaultDateFormat формат дат, если booking.date_format не задан
+const defaultDateFormat = "02.01.2006"
+
+// alternateDateFormats принимаются при вводе даты помимо основного формата
+var alternateDateFormats = []string{"02.01.2006", "2006-01-02", "02/01/2006", "2.1.2006"}
+
+// datePlaceholders переводят layout Go в подсказку для пользователя: 02.01.2006 -> ДД.ММ.ГГГГ
+var datePlaceholders = map[string]*strings.Replacer{
+	"ru": strings.NewReplacer("2006", "ГГГГ", "01", "ММ", "02", "ДД"),
+	"en": strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD"),
+}
+
+var errInvalidDate = errors.New("invalid date format")
+
+// relativeDayOffsets относительные даты, которые принимаются вместо отформатированной даты
+var relativeDayOffsets = map[string]int{
+	"сегодня":     0,
+	"завтра":      1,
+	"послезавтра": 2,
+}
+
+// weekdayNames названия дней недели в именительном и винительном падеже и сокращения
+var weekdayNames = map[string]time.Weekday{
+	"понедельник": time.Monday, "пн": time.Monday,
+	"вторник": time.Tuesday, "вт": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "ср": time.Wednesday,
+	"четверг": time.Thursday, "чт": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пт": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "сб": time.Saturday,
+	"воскресенье": time.Sunday, "вс": time.Sunday,
+}
+
+// defaultMaxRangeDays максимальная длина периода менеджерской заявки, если booking.max_range_days не задан
+const defaultMaxRangeDays = 31
+
+// timeNow источник текущего времени, подменяется в тестах
+var timeNow = time.Now
+
+// now возвращает текущее время в часовом поясе app.timezone
+func (b *Bot) now() time.Time {
+	return timeNow().In(b.location)
+}
+
+// today возвращает сегодняшнюю дату в часовом поясе app.timezone.
+// Как и даты из parseDate, это полночь UTC: заявка привязана к календарному дню, а не к моменту времени.
+func (b *Bot) today() time.Time {
+	now := b.now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dateLayout возвращает формат дат из booking.date_format
+func (b *Bot) dateLayout() string {
+	if b.config.Booking.DateFormat != "" {
+		return b.config.Booking.DateFormat
+	}
+	return defaultDateFormat
+}
+
+// maxRangeDays возвращает максимальную длину периода, который менеджер бронирует за один раз
+func (b *Bot) maxRangeDays() int {
+	if b.config.Booking.MaxRangeDays > 0 {
+		return b.config.Booking.MaxRangeDays
+	}
+	return defaultMaxRangeDays
+}
+
+// maxWeeklyCount возвращает максимальное количество повторений еженедельной заявки из booking.max_weekly_count
+func (b *Bot) maxWeeklyCount() int {
+	if b.config.Booking.MaxWeeklyCount > 0 {
+		return b.config.Booking.MaxWeeklyCount
+	}
+	return maxWeeklyOccurrences
+}
+
+// validateRangeLength проверяет, что период с start по end включительно не длиннее booking.max_range_days
+func (b *Bot) validateRangeLength(start, end time.Time) error {
+	if days, limit := rangeDays(start, end), b.maxRangeDays(); days > limit {
+		return fmt.Errorf("Период слишком длинный: %d дн., максимум %d дн.", days, limit)
+	}
+	return nil
+}
+
+// isoWeekday возвращает день недели в нумерации конфига: 1 - понедельник ... 7 - воскресенье
+func isoWeekday(date time.Time) int {
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		return 7
+	}
+	return weekday
+}
+
+// rangeDays возвращает количество календарных дней в периоде с start по end включительно
+func rangeDays(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// parseDate разбирает дату в основном формате или в одном из распространенных вариантов
+func (b *Bot) parseDate(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+
+	if date, err := time.Parse(b.dateLayout(), text); err == nil {
+		return date, nil
+	}
+	for _, layout := range alternateDateFormats {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+
+	if date, ok := parseRelativeDate(text, b.today()); ok {
+		return date, nil
+	}
+
+	return time.Time{}, errInvalidDate
+}
+
+// parseRelativeDate разбирает "сегодня", "завтра", "послезавтра" и дни недели ("пт", "в пятницу").
+// День недели означает ближайший такой день после today. Фразы из нескольких дат не принимаются.
+func parseRelativeDate(text string, today time.Time) (time.Time, bool) {
+	text = strings.Trim(strings.ToLower(strings.TrimSpace(text)), ".!")
+	text = strings.TrimPrefix(text, "во ")
+	text = strings.TrimPrefix(text, "в ")
+	text = strings.TrimSpace(text)
+
+	if offset, ok := relativeDayOffsets[text]; ok {
+		return today.AddDate(0, 0, offset), true
+	}
+
+	if weekday, ok := weekdayNames[text]; ok {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), true
+	}
+
+	return time.Time{}, false
+}
+
+// dateFormatHint подсказка с форматом и примером даты на языке пользователя
+func (b *Bot) dateFormatHint(userID int64) string {
+	lang := b.userLanguage(userID)
+	replacer, ok := datePlaceholders[lang]
+	if !ok {
+		replacer = datePlaceholders[defaultLanguage]
+	}
+
+	example := time.Date(2024, time.December, 25, 0, 0, 0, 0, time.UTC).Format(b.dateLayout())
+	return b.t(userID, "date_format_hint", replacer.Replace(b.dateLayout()), example)
+}
+
+// invalidDateMessage сообщение об ошибке разбора даты с ожидаемым форматом
+func (b *Bot) invalidDateMessage(userID int64) string {
+	return b.t(userID, "err_invalid_date", b.dateFormatHint(userID))
+}
+
+// formatBookingPeriod форматирует дату заявки, для многодневной - диапазон с количеством дней
+func formatBookingPeriod(start, end time.Time, layout string) string {
+	if end.IsZero() || !end.After(start) {
+		return start.Format(layout)
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	return fmt.Sprintf("%s – %s (%d дн.)", start.Format(layout), end.Format(layout), days)
+}
+
+// bookingPeriod форматирует дату или период заявки в формате booking.date_format
+func (b *Bot) bookingPeriod(booking models.Booking) string {
+	return formatBookingPeriod(booking.Date, booking.EndDate, b.dateLayout())
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"bronivik/internal/config"
+)
+
+// setTestNow подменяет текущее время до конца теста
+func setTestNow(t *testing.T, now time.Time) {
+	t.Helper()
+	original := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = original })
+}
+
+// newDatesTestBot создает бота только с часовым поясом и настройками заявок
+func newDatesTestBot(t *testing.T, timezone string) *Bot {
+	t.Helper()
+	cfg := &config.Config{App: config.AppConfig{Timezone: timezone}}
+	loc := cfg.App.Location()
+	if loc.String() != timezone {
+		t.Fatalf("timezone %s was not loaded", timezone)
+	}
+	return &Bot{config: cfg, location: loc}
+}
+
+func TestTodayUsesConfiguredTimezone(t *testing.T) {
+	// 22:30 UTC 10 марта: в Москве (UTC+3) уже 11 марта, в Нью-Йорке (UTC-4) еще 10 марта
+	setTestNow(t, time.Date(2030, 3, 10, 22, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		timezone string
+		want     time.Time
+	}{
+		{"Europe/Moscow", time.Date(2030, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"America/New_York", time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		b := newDatesTestBot(t, tt.timezone)
+		if got := b.today(); !got.Equal(tt.want) {
+			t.Errorf("today() in %s = %s, want %s", tt.timezone, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+
+		// Относительные даты считаются от сегодняшнего дня в настроенном поясе
+		tomorrow, err := b.parseDate("завтра")
+		if err != nil {
+			t.Fatalf("parseDate(завтра): %v", err)
+		}
+		if want := tt.want.AddDate(0, 0, 1); !tomorrow.Equal(want) {
+			t.Errorf("parseDate(завтра) in %s = %s, want %s", tt.timezone, tomorrow.Format("2006-01-02"), want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestValidateRangeLengthBoundary(t *testing.T) {
+	start := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		maxRangeDays int
+		days         int
+		wantErr      bool
+	}{
+		{"default limit", 0, defaultMaxRangeDays, false},
+		{"default limit exceeded", 0, defaultMaxRangeDays + 1, true},
+		{"configured limit", 7, 7, false},
+		{"configured limit exceeded", 7, 8, true},
+		{"single day", 1, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDatesTestBot(t, "Europe/Moscow")
+			b.config.Booking.MaxRangeDays = tt.maxRangeDays
+
+			end := start.AddDate(0, 0, tt.days-1)
+			if err := b.validateRangeLength(start, end); (err != nil) != tt.wantErr {
+				t.Errorf("validateRangeLength(%d days, limit %d) = %v, wantErr %v", tt.days, b.maxRangeDays(), err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxWeeklyCount(t *testing.T) {
+	b := newDatesTestBot(t, "Europe/Moscow")
+
+	if got := b.maxWeeklyCount(); got != maxWeeklyOccurrences {
+		t.Errorf("maxWeeklyCount() with default limit = %d, want %d", got, maxWeeklyOccurrences)
+	}
+
+	// Количество недель не зависит от лимита диапазона в днях
+	b.config.Booking.MaxRangeDays = 2
+	if got := b.maxWeeklyCount(); got != maxWeeklyOccurrences {
+		t.Errorf("maxWeeklyCount() with max_range_days=2 = %d, want %d", got, maxWeeklyOccurrences)
+	}
+
+	b.config.Booking.MaxWeeklyCount = 4
+	if got := b.maxWeeklyCount(); got != 4 {
+		t.Errorf("maxWeeklyCount() with max_weekly_count=4 = %d, want 4", got)
+	}
+}
+
+func TestParseRelativeDate(t *testing.T) {
+	// 13 марта 2030 - среда
+	today := time.Date(2030, 3, 13, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2030, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		text   string
+		want   time.Time
+		wantOK bool
+	}{
+		{"сегодня", day(13), true},
+		{"Завтра", day(14), true},
+		{" послезавтра. ", day(15), true},
+		{"пятница", day(15), true},
+		{"в пятницу", day(15), true},
+		{"пт", day(15), true},
+		{"во вторник", day(19), true},
+		{"понедельник", day(18), true},
+		// Тот же день недели означает следующую неделю, а не сегодня
+		{"среда", day(20), true},
+		{"в среду", day(20), true},
+		// Неоднозначный ввод не угадывается
+		{"завтра или послезавтра", time.Time{}, false},
+		{"пт сб", time.Time{}, false},
+		{"следующая пятница", time.Time{}, false},
+		{"вчера", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRelativeDate(tt.text, today)
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("parseRelativeDate(%q) = %s, %v; want %s, %v",
+				tt.text, got.Format("2006-01-02"), ok, tt.want.Format("2006-01-02"), tt.wantOK)
+		}
+	}
+}
+
+func TestParseDatePrefersStrictFormat(t *testing.T) {
+	setTestNow(t, time.Date(2030, 3, 13, 12, 0, 0, 0, time.UTC))
+	b := newDatesTestBot(t, "Europe/Moscow")
+
+	got, err := b.parseDate("25.12.2030")
+	if err != nil {
+		t.Fatalf("parseDate: %v", err)
+	}
+	if want := time.Date(2030, 12, 25, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("parseDate(25.12.2030) = %s, want %s", got, want)
+	}
+
+	if _, err := b.parseDate("на днях"); err != errInvalidDate {
+		t.Errorf("parseDate(на днях) error = %v, want errInvalidDate", err)
+	}
+}
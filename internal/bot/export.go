@@ -2,30 +2,145 @@ package bot
 
 import (
 	"context"
+	"encoding/csv"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"time"
 
 	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/xuri/excelize/v2"
 )
 
-// exportToExcel создает Excel файл с данными о бронированиях
-func (b *Bot) exportToExcel(startDate, endDate time.Time) (string, error) {
+// exportCell данные ячейки выгрузки: заявки аппарата на дату и число занятых единиц
+type exportCell struct {
+	Bookings    []models.Booking
+	BookedCount int
+}
+
+// bookingsExport данные выгрузки заявок, общие для Excel и CSV
+type bookingsExport struct {
+	Dates []time.Time
+	// Cells дата (2006-01-02) -> ID аппарата -> ячейка; дни без заявок отсутствуют
+	Cells map[string]map[int64]exportCell
+}
+
+// collectBookingsExport собирает заявки за период по дням и аппаратам.
+// При includeArchived в выгрузку попадают и заявки из архива.
+func (b *Bot) collectBookingsExport(startDate, endDate time.Time, includeArchived bool) (*bookingsExport, error) {
+	dailyBookings, err := b.db.GetDailyBookings(context.Background(), startDate, endDate, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bookings: %v", err)
+	}
+
+	export := &bookingsExport{Cells: make(map[string]map[int64]exportCell)}
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		export.Dates = append(export.Dates, date)
+	}
+
+	for dateKey, bookings := range dailyBookings {
+		// Группируем бронирования по аппаратам
+		bookingsByItem := make(map[int64][]models.Booking)
+		for _, booking := range bookings {
+			bookingsByItem[booking.ItemID] = append(bookingsByItem[booking.ItemID], booking)
+		}
+
+		cells := make(map[int64]exportCell)
+		for _, item := range b.getItems() {
+			// Получаем количество занятых аппаратов (только активные заявки)
+			bookedCount, err := b.db.GetBookedCount(context.Background(), item.ID, parseDate(dateKey))
+			if err != nil {
+				log.Printf("Error getting booked count: %v", err)
+				bookedCount = 0
+			}
+			cells[item.ID] = exportCell{Bookings: bookingsByItem[item.ID], BookedCount: bookedCount}
+		}
+		export.Cells[dateKey] = cells
+	}
+
+	return export, nil
+}
+
+// exportItemTitle название аппарата в первом столбце выгрузки
+func exportItemTitle(item models.Item) string {
+	return fmt.Sprintf("%s (%d)", item.Name, item.TotalQuantity)
+}
+
+// exportCellText текст ячейки выгрузки: заявки со статусами и загрузка аппарата
+func exportCellText(cell exportCell, item models.Item) string {
+	if len(cell.Bookings) == 0 {
+		return fmt.Sprintf("Свободно\n\nДоступно: %d/%d", item.TotalQuantity, item.TotalQuantity)
+	}
+
+	var cellValue string
+	for _, booking := range cell.Bookings {
+		status := "❓"
+		switch booking.Status {
+		case "confirmed", "completed":
+			status = "✅"
+		case "pending", "awaiting_approval", "changed":
+			status = "⏳"
+		case "cancelled":
+			status = "❌"
+		}
+		cellValue += fmt.Sprintf("%s %s (%s)", status, booking.UserName, booking.Phone)
+		if booking.SecondaryPhone != "" {
+			cellValue += fmt.Sprintf(" (запасной %s)", booking.SecondaryPhone)
+		}
+		// Источник помечаем только у заявок не из бота, чтобы не загромождать ячейки
+		if booking.Source != "" && booking.Source != models.BookingSourceUser {
+			cellValue += fmt.Sprintf(" [%s]", bookingSourceTitle(booking.Source))
+		}
+		cellValue += "\n"
+		if booking.Comment != "" {
+			cellValue += fmt.Sprintf("   💬 %s\n", booking.Comment)
+		}
+		if booking.Price > 0 {
+			cellValue += fmt.Sprintf("   💰 %d ₽, залог %s\n", booking.Price, depositTitle(booking.DepositPaid))
+		}
+	}
+	cellValue += fmt.Sprintf("\nЗанято: %d/%d", cell.BookedCount, item.TotalQuantity)
+	return cellValue
+}
+
+// bookingSourceTitle название источника заявки для менеджеров и выгрузок
+func bookingSourceTitle(source string) string {
+	switch source {
+	case models.BookingSourceManager:
+		return "менеджер"
+	case models.BookingSourceAPI:
+		return "API"
+	default:
+		return "бот"
+	}
+}
+
+// exportFileName имя файла выгрузки заявок за период
+func exportFileName(startDate, endDate time.Time, ext string) string {
+	return fmt.Sprintf("export_%s_to_%s.%s",
+		startDate.Format("2006-01-02"),
+		endDate.Format("2006-01-02"),
+		ext)
+}
+
+// exportToExcel создает Excel файл с данными о бронированиях.
+// При includeArchived в файл попадают и заявки из архива.
+func (b *Bot) exportToExcel(startDate, endDate time.Time, includeArchived bool) (string, error) {
 	// Создаем папку для экспорта, если не существует
 	if err := os.MkdirAll(b.config.Exports.Path, 0755); err != nil {
 		return "", fmt.Errorf("error creating export directory: %v", err)
 	}
 
 	// Получаем данные из БД
-	dailyBookings, err := b.db.GetDailyBookings(context.Background(), startDate, endDate)
+	export, err := b.collectBookingsExport(startDate, endDate, includeArchived)
 	if err != nil {
-		return "", fmt.Errorf("error getting bookings: %v", err)
+		return "", err
 	}
 
-	items := b.items
+	items := b.getItems()
 
 	// Создаем новый Excel файл
 	f := excelize.NewFile()
@@ -39,14 +154,13 @@ func (b *Bot) exportToExcel(startDate, endDate time.Time) (string, error) {
 
 	// Устанавливаем заголовок периода
 	f.SetCellValue("Бронирования", "A1", fmt.Sprintf("Период: %s - %s",
-		startDate.Format("02.01.2006"), endDate.Format("02.01.2006")))
+		startDate.Format(b.dateLayout()), endDate.Format(b.dateLayout())))
 
 	// Заголовки - даты (начинаем с строки 2)
 	col := 2
-	currentDate := startDate
 	dateHeaders := make(map[string]int)
 
-	for !currentDate.After(endDate) {
+	for _, currentDate := range export.Dates {
 		cell, _ := excelize.CoordinatesToCellName(col, 2)
 		dateStr := currentDate.Format("02.01")
 		f.SetCellValue("Бронирования", cell, dateStr)
@@ -61,14 +175,13 @@ func (b *Bot) exportToExcel(startDate, endDate time.Time) (string, error) {
 		f.SetCellStyle("Бронирования", cell, cell, style)
 
 		col++
-		currentDate = currentDate.AddDate(0, 0, 1)
 	}
 
 	// Названия аппаратов в первом столбце
 	row := 3
 	for _, item := range items {
 		cell, _ := excelize.CoordinatesToCellName(1, row)
-		f.SetCellValue("Бронирования", cell, fmt.Sprintf("%s (%d)", item.Name, item.TotalQuantity))
+		f.SetCellValue("Бронирования", cell, exportItemTitle(item))
 
 		style, _ := f.NewStyle(&excelize.Style{
 			Fill: excelize.Fill{Type: "pattern", Color: []string{"#E2EFDA"}, Pattern: 1},
@@ -80,57 +193,21 @@ func (b *Bot) exportToExcel(startDate, endDate time.Time) (string, error) {
 	}
 
 	// Заполняем данные по бронированиям
-	for dateKey, bookings := range dailyBookings {
+	for dateKey, cells := range export.Cells {
 		col, exists := dateHeaders[dateKey]
 		if !exists {
 			continue
 		}
 
-		// Группируем бронирования по аппаратам
-		bookingsByItem := make(map[int64][]models.Booking)
-		for _, booking := range bookings {
-			bookingsByItem[booking.ItemID] = append(bookingsByItem[booking.ItemID], booking)
-		}
-
 		// Заполняем данные для каждого аппарата
 		row := 3
 		for _, item := range items {
 			cell, _ := excelize.CoordinatesToCellName(col, row)
-			itemBookings := bookingsByItem[item.ID]
-
-			// Получаем количество занятых аппаратов (только активные заявки)
-			bookedCount, err := b.db.GetBookedCount(context.Background(), item.ID, parseDate(dateKey))
-			if err != nil {
-				log.Printf("Error getting booked count: %v", err)
-				bookedCount = 0
-			}
-
-			if len(itemBookings) > 0 {
-				var cellValue string
-				for _, booking := range itemBookings {
-					status := "❓"
-					switch booking.Status {
-					case "confirmed", "completed":
-						status = "✅"
-					case "pending", "changed":
-						status = "⏳"
-					case "cancelled":
-						status = "❌"
-					}
-					cellValue += fmt.Sprintf("%s %s (%s)\n", status, booking.UserName, booking.Phone)
-					if booking.Comment != "" {
-						cellValue += fmt.Sprintf("   💬 %s\n", booking.Comment)
-					}
-				}
-				cellValue += fmt.Sprintf("\nЗанято: %d/%d", bookedCount, item.TotalQuantity)
-				f.SetCellValue("Бронирования", cell, cellValue)
-			} else {
-				cellValue := fmt.Sprintf("Свободно\n\nДоступно: %d/%d", item.TotalQuantity, item.TotalQuantity)
-				f.SetCellValue("Бронирования", cell, cellValue)
-			}
+			itemCell := cells[item.ID]
+			f.SetCellValue("Бронирования", cell, exportCellText(itemCell, item))
 
 			// Определяем цвет заливки
-			styleID, err := b.getCellStyle(f, itemBookings, bookedCount, int(item.TotalQuantity))
+			styleID, err := b.getCellStyle(f, itemCell.Bookings, itemCell.BookedCount, int(item.TotalQuantity))
 			if err == nil {
 				f.SetCellStyle("Бронирования", cell, cell, styleID)
 			}
@@ -160,10 +237,7 @@ func (b *Bot) exportToExcel(startDate, endDate time.Time) (string, error) {
 	f.DeleteSheet("Sheet1")
 
 	// Сохраняем файл
-	fileName := fmt.Sprintf("export_%s_to_%s.xlsx",
-		startDate.Format("2006-01-02"),
-		endDate.Format("2006-01-02"))
-	filePath := filepath.Join(b.config.Exports.Path, fileName)
+	filePath := filepath.Join(b.config.Exports.Path, exportFileName(startDate, endDate, "xlsx"))
 
 	if err := f.SaveAs(filePath); err != nil {
 		return "", fmt.Errorf("error saving file: %v", err)
@@ -173,6 +247,88 @@ func (b *Bot) exportToExcel(startDate, endDate time.Time) (string, error) {
 	return filePath, nil
 }
 
+// exportToCSV создает CSV файл с теми же столбцами, что и exportToExcel: аппарат и по столбцу на каждый день.
+// Файл в UTF-8 с BOM, чтобы Excel правильно открывал кириллицу.
+func (b *Bot) exportToCSV(startDate, endDate time.Time, includeArchived bool) (string, error) {
+	if err := os.MkdirAll(b.config.Exports.Path, 0755); err != nil {
+		return "", fmt.Errorf("error creating export directory: %v", err)
+	}
+
+	export, err := b.collectBookingsExport(startDate, endDate, includeArchived)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(b.config.Exports.Path, exportFileName(startDate, endDate, "csv"))
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("error creating file: %v", err)
+	}
+	defer file.Close()
+
+	if err := writeBookingsCSV(file, export, b.getItems()); err != nil {
+		return "", fmt.Errorf("error writing csv: %v", err)
+	}
+
+	log.Printf("CSV file created: %s", filePath)
+	return filePath, nil
+}
+
+// writeBookingsCSV записывает выгрузку заявок в CSV с BOM
+func writeBookingsCSV(w io.Writer, export *bookingsExport, items []models.Item) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+
+	header := []string{"Аппарат"}
+	for _, date := range export.Dates {
+		header = append(header, date.Format("02.01"))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		record := []string{exportItemTitle(item)}
+		for _, date := range export.Dates {
+			cells, exists := export.Cells[date.Format("2006-01-02")]
+			if !exists {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, exportCellText(cells[item.ID], item))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// sendDocument отправляет файл выгрузки в чат
+func (b *Bot) sendDocument(chatID int64, filePath, caption string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("error opening file: %v", err)
+	}
+	defer file.Close()
+
+	fileReader := tgbotapi.FileReader{
+		Name:   filepath.Base(filePath),
+		Reader: file,
+	}
+
+	doc := tgbotapi.NewDocument(chatID, fileReader)
+	doc.Caption = caption
+
+	_, err = b.bot.Send(doc)
+	return err
+}
+
 // parseDate преобразует строку в time.Time
 func parseDate(dateStr string) time.Time {
 	date, err := time.Parse("2006-01-02", dateStr)
@@ -216,7 +372,7 @@ func (b *Bot) getCellStyle(f *excelize.File, itemBookings []models.Booking, book
 	// 3. Проверяем статусы активных заявок
 	hasUnconfirmed := false
 	for _, booking := range activeBookings {
-		if booking.Status == "pending" || booking.Status == "changed" {
+		if booking.Status == "pending" || booking.Status == "awaiting_approval" || booking.Status == "changed" {
 			hasUnconfirmed = true
 			break
 		}
@@ -262,12 +418,12 @@ func (b *Bot) filterActiveBookings(bookings []models.Booking) []models.Booking {
 func getLastColumn(colCount int) string {
 	// Базовые колонки A-Z
 	if colCount <= 26 {
-		return string('A' + colCount - 1)
+		return string(rune('A' + colCount - 1))
 	}
 
 	// Для большего количества колонок (AA, AB, etc.)
-	firstChar := string('A' + (colCount-1)/26 - 1)
-	secondChar := string('A' + (colCount-1)%26)
+	firstChar := string(rune('A' + (colCount-1)/26 - 1))
+	secondChar := string(rune('A' + (colCount-1)%26))
 	return firstChar + secondChar
 }
 
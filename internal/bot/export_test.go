@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+func TestWriteBookingsCSV(t *testing.T) {
+	day1 := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	items := []models.Item{{ID: 1, Name: "Аппарат A", TotalQuantity: 2}}
+	export := &bookingsExport{
+		Dates: []time.Time{day1, day2},
+		Cells: map[string]map[int64]exportCell{
+			"2030-03-10": {1: {
+				Bookings:    []models.Booking{{UserName: "Иван", Phone: "79001234567", Status: "confirmed"}},
+				BookedCount: 1,
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeBookingsCSV(&buf, export, items); err != nil {
+		t.Fatalf("writeBookingsCSV: %v", err)
+	}
+
+	data := buf.String()
+	if !strings.HasPrefix(data, "\xEF\xBB\xBF") {
+		t.Fatal("CSV must start with a UTF-8 BOM")
+	}
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, "\xEF\xBB\xBF"))).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want header and one item row", len(records))
+	}
+
+	wantHeader := []string{"Аппарат", "10.03", "11.03"}
+	if strings.Join(records[0], "|") != strings.Join(wantHeader, "|") {
+		t.Errorf("header = %q, want %q", records[0], wantHeader)
+	}
+
+	row := records[1]
+	if len(row) != 3 {
+		t.Fatalf("row has %d columns, want 3: %q", len(row), row)
+	}
+	if row[0] != "Аппарат A (2)" {
+		t.Errorf("item column = %q, want %q", row[0], "Аппарат A (2)")
+	}
+	if want := "✅ Иван (79001234567)\n\nЗанято: 1/2"; row[1] != want {
+		t.Errorf("booked day = %q, want %q", row[1], want)
+	}
+	if row[2] != "" {
+		t.Errorf("day without bookings = %q, want empty", row[2])
+	}
+}
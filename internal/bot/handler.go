@@ -2,28 +2,48 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"bronivik/internal/config"
 	"bronivik/internal/database"
+	"bronivik/internal/events"
 	"bronivik/internal/google"
+	"bronivik/internal/metrics"
 	"bronivik/internal/models"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Bot struct {
-	bot           *tgbotapi.BotAPI
-	config        *config.Config
-	items         []models.Item
-	db            *database.DB
-	userStates    map[int64]*models.UserState
+	bot      *tgbotapi.BotAPI
+	config   *config.Config
+	location *time.Location
+	// items список аппаратов; читается через getItems, заменяется через setItems/updateItems
+	items      []models.Item
+	itemsMu    sync.RWMutex
+	itemsPath  string
+	db         *database.DB
+	userStates map[int64]*models.UserState
+	// userStatesMu защищает userStates. Обновления Telegram обрабатываются последовательно в Start,
+	// но рядом с этим циклом работают фоновые горутины (планировщик, рассылка, синхронизация таблиц),
+	// и доступ к состояниям из них не должен гоняться с обработчиком
+	userStatesMu  sync.RWMutex
 	sheetsService *google.SheetsService
+	rateLimiter   RateLimiter
+	events        events.EventPublisher
+	sheetsWorker  *SheetsWorker
+	slotLocker    *redisSlotLocker
+	// scheduleSyncMu не дает менеджерам запустить несколько синхронизаций расписания с прогрессом одновременно
+	scheduleSyncMu sync.Mutex
+	// bulkConfirmPages ожидающие заявки, показанные в сообщении со списком, для кнопки «Подтвердить все на странице»
+	bulkConfirmPages   map[bulkConfirmPage][]int64
+	bulkConfirmPagesMu sync.Mutex
 }
 
 func NewBot(token string, config *config.Config, items []models.Item, db *database.DB, googleService *google.SheetsService) (*Bot, error) {
@@ -32,14 +52,30 @@ func NewBot(token string, config *config.Config, items []models.Item, db *databa
 		return nil, err
 	}
 
-	return &Bot{
-		bot:           botAPI,
-		config:        config,
-		items:         items,
-		db:            db,
-		userStates:    make(map[int64]*models.UserState),
-		sheetsService: googleService,
-	}, nil
+	var limiter RateLimiter
+	if config.Booking.MaxPerMinute > 0 {
+		limiter = newMemoryRateLimiter(config.Booking.MaxPerMinute, rateLimitWindow)
+	}
+
+	b := &Bot{
+		bot:              botAPI,
+		config:           config,
+		location:         config.App.Location(),
+		items:            items,
+		db:               db,
+		userStates:       make(map[int64]*models.UserState),
+		bulkConfirmPages: make(map[bulkConfirmPage][]int64),
+		sheetsService:    googleService,
+		rateLimiter:      limiter,
+	}
+
+	if googleService != nil {
+		b.sheetsWorker = NewSheetsWorker(db, b.processSheetTask)
+	}
+
+	b.loadItemMaintenance()
+
+	return b, nil
 }
 
 const (
@@ -48,13 +84,18 @@ const (
 	StateSelectDate          = "select_date"
 	StateViewSchedule        = "view_schedule"
 	StatePersonalData        = "personal_data"
+	StateEnterDuration       = "enter_duration"
 	StateEnterName           = "enter_name"
 	StatePhoneNumber         = "phone_number"
+	StateEnterComment        = "enter_comment"
 	StateConfirmation        = "confirmation"
 	StateWaitingDate         = "waiting_date"
 	StateWaitingSpecificDate = "waiting_specific_date"
 )
 
+// maxWeeklyOccurrences максимальное количество повторений еженедельного бронирования, если booking.max_weekly_count не задан
+const maxWeeklyOccurrences = 12
+
 func (b *Bot) Start() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -63,6 +104,8 @@ func (b *Bot) Start() {
 
 	log.Printf("Authorized on account %s", b.bot.Self.UserName)
 
+	b.startBackgroundJobs()
+
 	for update := range updates {
 		if update.CallbackQuery != nil {
 			b.handleCallbackQuery(update)
@@ -81,11 +124,30 @@ func (b *Bot) Start() {
 	}
 }
 
+// Shutdown дожидается выполняемых задач синхронизации с Google Sheets (не дольше ctx)
+// и останавливает получение обновлений, после чего Start возвращает управление
+func (b *Bot) Shutdown(ctx context.Context) {
+	if b.sheetsWorker != nil {
+		if err := b.sheetsWorker.Shutdown(ctx); err != nil {
+			log.Printf("Sheets worker did not stop in time: %v", err)
+		}
+	}
+	b.bot.StopReceivingUpdates()
+}
+
 func (b *Bot) handleMessage(update tgbotapi.Update) {
+	// Кнопки на других языках приводим к русским подписям
+	update.Message.Text = canonicalButton(update.Message.Text)
+
 	userID := update.Message.From.ID
 	text := update.Message.Text
 
-	if b.isBlacklisted(userID) {
+	// /menu возвращает клавиатуру главного меню из любого шага, прерывая начатый сценарий.
+	// В отличие от /start не пересохраняет пользователя. Кнопка главного меню на любом языке
+	// сюда приходит уже в русском варианте после canonicalButton.
+	if text == "/menu" || text == messages[defaultLanguage]["btn_main_menu"] {
+		b.clearUserState(userID)
+		b.handleMainMenu(update)
 		return
 	}
 
@@ -98,11 +160,22 @@ func (b *Bot) handleMessage(update tgbotapi.Update) {
 
 	state := b.getUserState(userID)
 
+	// Фото или документ принимаются только на шагах телефона и комментария, подпись идет как обычный ввод
+	if fileID, attachmentType := messageAttachment(update.Message); fileID != "" {
+		if !b.handleBookingAttachment(update, state, fileID, attachmentType) {
+			return
+		}
+		text = update.Message.Text
+	}
+
 	switch {
-	case text == "/start" || strings.ToLower(text) == "сброс" || strings.ToLower(text) == "reset":
+	case text == "/start" || strings.HasPrefix(text, "/start ") || strings.ToLower(text) == "сброс" || strings.ToLower(text) == "reset":
 		b.clearUserState(update.Message.From.ID)
 		b.handleStartWithUserTracking(update)
 
+	case text == "/whoami":
+		b.handleWhoAmI(update)
+
 	case text == "📞 Контакты менеджеров":
 		b.showManagerContacts(update)
 
@@ -145,7 +218,12 @@ func (b *Bot) handleMessage(update tgbotapi.Update) {
 	case text == "📋 СОЗДАТЬ ЗАЯВКУ НА ЭТОТ АППАРАТ":
 		state := b.getUserState(update.Message.From.ID)
 		if state != nil && state.TempData["selected_item"] != nil {
-			selectedItem := state.TempData["selected_item"].(models.Item)
+			selectedItem, ok := state.TempData["selected_item"].(models.Item)
+			if !ok {
+				b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+				b.clearUserState(update.Message.From.ID)
+				break
+			}
 			// Сохраняем выбранный аппарат для создания заявки
 			tempData := map[string]interface{}{
 				"selected_item": selectedItem,
@@ -153,8 +231,8 @@ func (b *Bot) handleMessage(update tgbotapi.Update) {
 			b.setUserState(update.Message.From.ID, StateWaitingDate, tempData)
 
 			msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-				fmt.Sprintf("Вы выбрали: %s\n\nВведите дату бронирования в формате ДД.ММ.ГГГГ (например, 25.12.2024):",
-					selectedItem.Name))
+				fmt.Sprintf("Вы выбрали: %s\n\nВведите дату бронирования в формате %s:",
+					selectedItem.Name, b.dateFormatHint(update.Message.From.ID)))
 
 			keyboard := tgbotapi.NewReplyKeyboard(
 				tgbotapi.NewKeyboardButtonRow(
@@ -163,18 +241,31 @@ func (b *Bot) handleMessage(update tgbotapi.Update) {
 			)
 			msg.ReplyMarkup = keyboard
 			b.bot.Send(msg)
+
+			b.sendDateCalendar(update.Message.Chat.ID, selectedItem)
 		}
 
 	case text == "⬅️ Назад":
-		if state != nil {
+		if state != nil && isEditingBooking(state) {
+			// Правка поля из сводки отменяется возвратом к сводке
+			b.showBookingConfirmation(update, state)
+		} else if state != nil {
 			// Возвращаемся к предыдущему шагу в зависимости от текущего состояния
 			switch state.CurrentStep {
+			case StateEnterDuration:
+				b.handleDateRequest(update, state)
 			case StateEnterName:
 				b.handleMainMenu(update)
 			case StatePhoneNumber:
 				b.handleNameRequest(update)
-			case StateConfirmation:
+			case StateEnterComment:
 				b.handlePhoneRequest(update)
+			case StateConfirmation:
+				if b.config.Booking.AskComment {
+					b.handleCommentRequest(update)
+				} else {
+					b.handlePhoneRequest(update)
+				}
 			default:
 				b.handleMainMenu(update)
 			}
@@ -192,9 +283,8 @@ func (b *Bot) handleMessage(update tgbotapi.Update) {
 	case state != nil && state.CurrentStep == StateEnterName:
 		if text == "👤 Использовать имя из Telegram" {
 			// Используем имя из Telegram
-			state.TempData["user_name"] = update.Message.From.FirstName + " " + update.Message.From.LastName
-			b.setUserState(update.Message.From.ID, StatePhoneNumber, state.TempData)
-			b.handlePhoneRequest(update)
+			state.TempData["user_name"] = sanitizeInput(update.Message.From.FirstName+" "+update.Message.From.LastName, false)
+			b.continueAfterName(update, state)
 		} else if text == "📞 Контакты менеджеров" {
 			b.showManagerContacts(update)
 		} else if text == "❌ Отмена" {
@@ -202,17 +292,13 @@ func (b *Bot) handleMessage(update tgbotapi.Update) {
 			b.handleMainMenu(update)
 		} else {
 			// Сохраняем введенное имя
-			if len(text) < 2 {
-				b.sendMessage(update.Message.Chat.ID, "Имя слишком короткое. Введите имя длиной от 2 символов.")
+			name, err := userNameField.clean(text)
+			if err != nil {
+				b.sendMessage(update.Message.Chat.ID, err.Error())
 				return
 			}
-			if len(text) > 150 {
-				b.sendMessage(update.Message.Chat.ID, "Имя слишком длинное. Введите имя до 150 символов.")
-				return
-			}
-			state.TempData["user_name"] = text
-			b.setUserState(update.Message.From.ID, StatePhoneNumber, state.TempData)
-			b.handlePhoneRequest(update)
+			state.TempData["user_name"] = name
+			b.continueAfterName(update, state)
 		}
 
 	case state != nil && state.CurrentStep == StatePhoneNumber:
@@ -222,12 +308,40 @@ func (b *Bot) handleMessage(update tgbotapi.Update) {
 			b.handlePhoneReceived(update, text)
 		}
 
+	case state != nil && state.CurrentStep == StateEnterComment:
+		if text == "⏭ Пропустить" {
+			delete(state.TempData, "comment")
+		} else {
+			comment, err := commentField.clean(text)
+			if err != nil {
+				b.sendMessage(update.Message.Chat.ID, err.Error())
+				return
+			}
+			state.TempData["comment"] = comment
+		}
+		b.showBookingConfirmation(update, state)
+
 	case state != nil && state.CurrentStep == StateConfirmation && text == "✅ Подтвердить заявку":
 		b.finalizeBooking(update)
 
+	case state != nil && state.CurrentStep == StateConfirmation && text == "✏️ Изменить дату":
+		b.editBookingField(update, state, "date")
+
+	case state != nil && state.CurrentStep == StateConfirmation && text == "✏️ Изменить имя":
+		b.editBookingField(update, state, "name")
+
+	case state != nil && state.CurrentStep == StateConfirmation && text == "✏️ Изменить телефон":
+		b.editBookingField(update, state, "phone")
+
+	case state != nil && state.CurrentStep == StateConfirmation && text == "✏️ Изменить комментарий":
+		b.editBookingField(update, state, "comment")
+
 	case state != nil && state.CurrentStep == StateWaitingDate:
 		b.handleDateInput(update, text, state)
 
+	case state != nil && state.CurrentStep == StateEnterDuration:
+		b.handleDurationInput(update, text, state)
+
 	case state != nil && state.CurrentStep == StateWaitingSpecificDate:
 		b.handleSpecificDateInput(update, text)
 
@@ -255,6 +369,10 @@ func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
 	case data == "export_users":
 		b.handleExportUsers(update)
 
+	// reschedule_to_ должен проверяться раньше reschedule_
+	case strings.HasPrefix(data, "reschedule_to_"):
+		b.startRescheduleTo(update)
+
 	case strings.HasPrefix(data, "confirm_"),
 		strings.HasPrefix(data, "reject_"),
 		strings.HasPrefix(data, "reschedule_"),
@@ -266,9 +384,58 @@ func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
 	case strings.HasPrefix(data, "change_to_"):
 		b.handleChangeItem(update)
 
+	case strings.HasPrefix(data, "user_bookings:"):
+		b.handleUserBookingsPage(update)
+
+	case strings.HasPrefix(data, "user_cancel:"):
+		b.handleUserCancelBooking(update)
+
+	case strings.HasPrefix(data, "remind_ok:"), strings.HasPrefix(data, "remind_cancel:"):
+		b.handleReminderResponse(update)
+
+	case strings.HasPrefix(data, "waitlist:"):
+		b.handleWaitlistJoin(update)
+
+	case strings.HasPrefix(data, "reassign:"):
+		b.startReassignBooking(update)
+
+	case strings.HasPrefix(data, "add_note:"):
+		b.startManagerNote(update)
+
+	case strings.HasPrefix(data, "user_history:"):
+		b.handleUserHistory(update)
+
+	case strings.HasPrefix(data, "manager_bookings_page:"):
+		b.handleManagerBookingsPage(update)
+
+	case strings.HasPrefix(data, "manager_bulk_confirm:"):
+		b.handleManagerBulkConfirm(update)
+
+	case strings.HasPrefix(data, "attachment:"):
+		b.handleShowAttachment(update)
+
+	case strings.HasPrefix(data, "cancel_group:"):
+		b.handleCancelBookingGroup(update)
+
+	case strings.HasPrefix(data, "deposit_paid:"):
+		b.handleDepositPaid(update)
+
+	case strings.HasPrefix(data, "broadcast:"):
+		b.handleBroadcastConfirm(update)
+
+	case strings.HasPrefix(data, "export_format:"):
+		b.handleExportFormat(update)
+
 	case strings.HasPrefix(data, "select_item:"):
 		b.handleItemSelectionFromCallback(update)
 
+	case data == "item_categories":
+		text, markup := b.categoriesPage()
+		b.editItemsMessage(callback, text, markup)
+
+	case strings.HasPrefix(data, "item_category:"):
+		b.handleItemCategory(update)
+
 	case strings.HasPrefix(data, "items_page:"):
 		pageStr := strings.TrimPrefix(data, "items_page:")
 		page, err := strconv.Atoi(pageStr)
@@ -299,6 +466,9 @@ func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
 		}
 		b.handleMainMenu(tempUpdate)
 
+	case strings.HasPrefix(data, "call_template:"):
+		b.handleManagerTemplate(update)
+
 	case strings.HasPrefix(data, "call_booking"):
 		b.handleCallButton(update)
 
@@ -321,7 +491,12 @@ func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
 	case data == "start_the_order_item":
 		state := b.getUserState(callback.From.ID)
 		if state != nil && state.TempData["selected_item"] != nil {
-			selectedItem := state.TempData["selected_item"].(models.Item)
+			selectedItem, ok := state.TempData["selected_item"].(models.Item)
+			if !ok {
+				b.sendMessage(callback.Message.Chat.ID, b.t(callback.From.ID, "err_session_expired"))
+				b.clearUserState(callback.From.ID)
+				break
+			}
 			// Сохраняем выбранный аппарат для создания заявки
 			tempData := map[string]interface{}{
 				"selected_item": selectedItem,
@@ -329,8 +504,8 @@ func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
 			b.setUserState(callback.From.ID, StateWaitingDate, tempData)
 
 			msg := tgbotapi.NewMessage(callback.Message.Chat.ID,
-				fmt.Sprintf("Вы выбрали: %s\n\nВведите дату бронирования в формате ДД.ММ.ГГГГ (например, 25.12.2024):",
-					selectedItem.Name))
+				fmt.Sprintf("Вы выбрали: %s\n\nВведите дату бронирования в формате %s:",
+					selectedItem.Name, b.dateFormatHint(callback.From.ID)))
 
 			keyboard := tgbotapi.NewReplyKeyboard(
 				tgbotapi.NewKeyboardButtonRow(
@@ -339,8 +514,18 @@ func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
 			)
 			msg.ReplyMarkup = keyboard
 			b.bot.Send(msg)
+
+			b.sendDateCalendar(callback.Message.Chat.ID, selectedItem)
 		}
 
+	case strings.HasPrefix(data, "cal_day:"):
+		b.handleCalendarDay(update)
+
+	case strings.HasPrefix(data, "cal_nav:"):
+		b.handleCalendarNav(update)
+
+	case data == calendarIgnore:
+
 	default:
 		log.Printf("Unknown callback data: %s", callback.Data)
 	}
@@ -360,6 +545,8 @@ func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
 		b.handleManagerDateType(update, "single")
 	} else if data == "manager_date_range" {
 		b.handleManagerDateType(update, "range")
+	} else if data == "manager_weekly" {
+		b.handleManagerDateType(update, "weekly")
 	}
 
 	// Ответ на callback (убирает "часики" на кнопке)
@@ -382,7 +569,7 @@ func (b *Bot) handleScheduleItemSelection(update tgbotapi.Update) {
 
 	// Находим выбранный аппарат
 	var selectedItem models.Item
-	for _, item := range b.items {
+	for _, item := range b.getItems() {
 		if item.ID == itemID {
 			selectedItem = item
 			break
@@ -394,6 +581,12 @@ func (b *Bot) handleScheduleItemSelection(update tgbotapi.Update) {
 		return
 	}
 
+	if selectedItem.InMaintenance(b.now()) {
+		b.bot.Request(tgbotapi.NewCallback(callback.ID,
+			fmt.Sprintf("🔧 %s на обслуживании до %s", selectedItem.Name, selectedItem.UnavailableUntil.Format(b.dateLayout()))))
+		return
+	}
+
 	// Сохраняем выбранный аппарат в состоянии
 	b.setUserState(callback.From.ID, "schedule_view_menu", map[string]interface{}{
 		"selected_item": selectedItem,
@@ -420,7 +613,12 @@ func (b *Bot) sendScheduleMenu(chatID, userID int64) {
 		return
 	}
 
-	selectedItem := state.TempData["selected_item"].(models.Item)
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(chatID, b.t(userID, "err_session_expired"))
+		b.clearUserState(userID)
+		return
+	}
 
 	msg := tgbotapi.NewMessage(chatID,
 		fmt.Sprintf("📅 *Расписание для %s*\n\nВыберите период, используя клавиатуру ниже:", selectedItem.Name))
@@ -449,20 +647,21 @@ func (b *Bot) sendScheduleMenu(chatID, userID int64) {
 // editScheduleItemsPage редактирует страницу с аппаратами для расписания
 func (b *Bot) editScheduleItemsPage(update tgbotapi.Update, page int) {
 	callback := update.CallbackQuery
+	items := b.getItems()
 	itemsPerPage := 8
 	startIdx := page * itemsPerPage
 	endIdx := startIdx + itemsPerPage
-	if endIdx > len(b.items) {
-		endIdx = len(b.items)
+	if endIdx > len(items) {
+		endIdx = len(items)
 	}
 
 	var message strings.Builder
 	message.WriteString("🏢 *Выберите аппарат для просмотра расписания:*\n\n")
-	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(b.items)+itemsPerPage-1)/itemsPerPage))
+	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(items)+itemsPerPage-1)/itemsPerPage))
 
-	currentItems := b.items[startIdx:endIdx]
+	currentItems := items[startIdx:endIdx]
 	for i, item := range currentItems {
-		message.WriteString(fmt.Sprintf("%d. *%s*\n", startIdx+i+1, item.Name))
+		message.WriteString(fmt.Sprintf("%d. *%s*%s\n", startIdx+i+1, item.Name, photoMark(item)))
 		message.WriteString(fmt.Sprintf("   📝 %s\n", item.Description))
 	}
 
@@ -482,7 +681,7 @@ func (b *Bot) editScheduleItemsPage(update tgbotapi.Update, page int) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("schedule_items_page:%d", page-1)))
 	}
 
-	if endIdx < len(b.items) {
+	if endIdx < len(items) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("schedule_items_page:%d", page+1)))
 	}
 
@@ -522,7 +721,7 @@ func (b *Bot) handleItemSelectionFromCallback(update tgbotapi.Update) {
 
 	// Находим выбранный аппарат
 	var selectedItem models.Item
-	for _, item := range b.items {
+	for _, item := range b.getItems() {
 		if item.ID == itemID {
 			selectedItem = item
 			break
@@ -549,11 +748,14 @@ func (b *Bot) handleItemSelectionFromCallback(update tgbotapi.Update) {
 	editMsg := tgbotapi.NewEditMessageText(
 		callback.Message.Chat.ID,
 		callback.Message.MessageID,
-		fmt.Sprintf("✅ Вы выбрали: *%s*\n\nВведите дату бронирования в формате ДД.ММ.ГГГГ (например, 25.12.2024):", selectedItem.Name),
+		fmt.Sprintf("✅ Вы выбрали: *%s*\n\nВведите дату бронирования в формате %s:",
+			selectedItem.Name, b.dateFormatHint(callback.From.ID)),
 	)
 	editMsg.ParseMode = "Markdown"
 	b.bot.Send(editMsg)
 
+	b.sendItemPhoto(callback.Message.Chat.ID, selectedItem)
+
 	// Отправляем кнопку "Назад"
 	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Или используйте кнопку ниже:")
 	msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(
@@ -563,71 +765,28 @@ func (b *Bot) handleItemSelectionFromCallback(update tgbotapi.Update) {
 	)
 	b.bot.Send(msg)
 
+	b.sendDateCalendar(callback.Message.Chat.ID, selectedItem)
+
 	b.bot.Send(tgbotapi.NewCallback(callback.ID, fmt.Sprintf("Выбрано: %s", selectedItem.Name)))
 }
 
 // editItemsPage редактирует сообщение с новой страницей аппаратов
 func (b *Bot) editItemsPage(update tgbotapi.Update, page int) {
-	callback := update.CallbackQuery
-	itemsPerPage := 8
-	startIdx := page * itemsPerPage
-	endIdx := startIdx + itemsPerPage
-	if endIdx > len(b.items) {
-		endIdx = len(b.items)
-	}
-
-	var message strings.Builder
-	message.WriteString("🏢 *Доступные аппараты*\n\n")
-	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(b.items)+itemsPerPage-1)/itemsPerPage))
-
-	currentItems := b.items[startIdx:endIdx]
-	for i, item := range currentItems {
-		message.WriteString(fmt.Sprintf("%d. *%s*\n", startIdx+i+1, item.Name))
-		message.WriteString(fmt.Sprintf("   📝 %s\n", item.Description))
-	}
-
-	var keyboard [][]tgbotapi.InlineKeyboardButton
-
-	// Кнопки выбора аппаратов
-	for i, item := range currentItems {
-		btn := tgbotapi.NewInlineKeyboardButtonData(
-			fmt.Sprintf("%d. %s", startIdx+i+1, item.Name),
-			fmt.Sprintf("select_item:%d", item.ID),
-		)
-		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{btn})
-	}
-
-	// Кнопки навигации
-	var navButtons []tgbotapi.InlineKeyboardButton
-
-	if page > 0 {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("items_page:%d", page-1)))
-	}
-
-	if endIdx < len(b.items) {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("items_page:%d", page+1)))
-	}
-
-	if len(navButtons) > 0 {
-		keyboard = append(keyboard, navButtons)
-	}
-
-	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
-		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад в меню", "back_to_main"),
-	})
-
-	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	text, markup := itemsPage(b.bookableItems(), page, "items_page:", false)
+	b.editItemsMessage(update.CallbackQuery, text, markup)
+}
 
+// editItemsMessage заменяет текст и клавиатуру сообщения со списком аппаратов
+func (b *Bot) editItemsMessage(callback *tgbotapi.CallbackQuery, text string, markup tgbotapi.InlineKeyboardMarkup) {
 	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
 		callback.Message.Chat.ID,
 		callback.Message.MessageID,
-		message.String(),
+		text,
 		markup,
 	)
 	editMsg.ParseMode = "Markdown"
 
 	b.bot.Send(editMsg)
-	b.bot.Send(tgbotapi.NewCallback(callback.ID, ""))
 }
 
 // saveUser сохраняет/обновляет информацию о пользователе
@@ -693,10 +852,39 @@ func (b *Bot) handleStartWithUserTracking(update tgbotapi.Update) {
 	// Обновляем активность
 	b.updateUserActivity(update.Message.From.ID)
 
+	// Ссылка вида t.me/<бот>?start=item_<id> сразу открывает бронирование аппарата
+	if item, ok := b.deepLinkItem(update.Message.CommandArguments()); ok {
+		b.setUserState(update.Message.From.ID, StateWaitingDate, map[string]interface{}{
+			"selected_item": item,
+		})
+		b.handleDateRequest(update, b.getUserState(update.Message.From.ID))
+		return
+	}
+
 	// Показываем главное меню
 	b.handleMainMenu(update)
 }
 
+// deepLinkItem находит аппарат по параметру /start вида item_<id>.
+// Неизвестный или некорректный параметр не считается ошибкой.
+func (b *Bot) deepLinkItem(payload string) (models.Item, bool) {
+	idStr, ok := strings.CutPrefix(strings.TrimSpace(payload), "item_")
+	if !ok {
+		return models.Item{}, false
+	}
+
+	itemID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return models.Item{}, false
+	}
+
+	item, ok := b.findItemByID(itemID)
+	if !ok || item.InMaintenance(b.now()) {
+		return models.Item{}, false
+	}
+	return item, true
+}
+
 // getUserStats возвращает статистику пользователей (для менеджеров)
 func (b *Bot) getUserStats(update tgbotapi.Update) {
 	if !b.isManager(update.Message.From.ID) {
@@ -740,6 +928,24 @@ func (b *Bot) getUserStats(update tgbotapi.Update) {
 	message.WriteString(fmt.Sprintf("👨‍💼 Менеджеров: *%d*\n", len(managers)))
 	message.WriteString(fmt.Sprintf("🚫 В черном списке: *%d*\n\n", blacklistedCount))
 
+	// Заявки, оформленные менеджерами вручную
+	managerBookings, err := b.db.CountManagerCreatedBookings(ctx, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		log.Printf("Error counting manager bookings: %v", err)
+	} else if len(managerBookings) > 0 {
+		managerIDs := make([]int64, 0, len(managerBookings))
+		for managerID := range managerBookings {
+			managerIDs = append(managerIDs, managerID)
+		}
+		sort.Slice(managerIDs, func(i, j int) bool { return managerIDs[i] < managerIDs[j] })
+
+		message.WriteString("📝 *Оформлено менеджерами (30 дней):*\n")
+		for _, managerID := range managerIDs {
+			message.WriteString(fmt.Sprintf("👨‍💼 %s - %d\n", b.managerDisplayName(managerID), managerBookings[managerID]))
+		}
+		message.WriteString("\n")
+	}
+
 	// Последние 5 пользователей
 	message.WriteString("📈 *Последние пользователи:*\n")
 	count := 5
@@ -760,18 +966,26 @@ func (b *Bot) getUserStats(update tgbotapi.Update) {
 			emoji,
 			user.FirstName,
 			user.LastName,
-			user.LastActivity.Format("02.01.2006")))
+			user.LastActivity.Format(b.dateLayout())))
 	}
 
 	msg := tgbotapi.NewMessage(update.Message.Chat.ID, message.String())
 	msg.ParseMode = "Markdown"
 
-	// Добавляем кнопку для экспорта пользователей
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📤 Экспорт пользователей", "export_users"),
-		),
-	)
+	// Кнопки истории заявок последних пользователей и экспорта
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < count; i++ {
+		user := allUsers[i]
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("📜 %s %s", user.FirstName, user.LastName),
+				fmt.Sprintf("user_history:%d", user.TelegramID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📤 Экспорт пользователей", "export_users"),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 	msg.ReplyMarkup = &keyboard
 
 	b.bot.Send(msg)
@@ -799,23 +1013,7 @@ func (b *Bot) handleExportUsers(update tgbotapi.Update) {
 	}
 
 	// Отправляем файл
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.Printf("Error opening file: %v", err)
-		b.sendMessage(callback.Message.Chat.ID, "Ошибка при открытии файла")
-		return
-	}
-	defer file.Close()
-
-	fileReader := tgbotapi.FileReader{
-		Name:   filepath.Base(filePath),
-		Reader: file,
-	}
-
-	doc := tgbotapi.NewDocument(callback.Message.Chat.ID, fileReader)
-	doc.Caption = "📊 Экспорт данных пользователей"
-
-	_, err = b.bot.Send(doc)
+	err = b.sendDocument(callback.Message.Chat.ID, filePath, "📊 Экспорт данных пользователей")
 	if err != nil {
 		log.Printf("Error sending document: %v", err)
 		b.sendMessage(callback.Message.Chat.ID, "Ошибка при отправке файла")
@@ -863,21 +1061,22 @@ func (b *Bot) SyncUsersToSheets() {
 	}
 }
 
-// SyncBookingsToSheets синхронизирует бронирования с Google Sheets
+// SyncBookingsToSheets ставит в очередь синхронизацию бронирований и расписания с Google Sheets
 func (b *Bot) SyncBookingsToSheets() {
-	if b.sheetsService == nil {
-		log.Println("Google Sheets service not initialized")
-		return
-	}
+	b.enqueueSheetTask(SheetTaskSyncBookings, 0)
+	b.enqueueSheetTask(SheetTaskSyncSchedule, 0)
+}
 
+// syncBookingsSheet обновляет на годовых листах Google Sheets строки заявок за период синхронизации
+func (b *Bot) syncBookingsSheet() error {
 	// Получаем бронирования за период: один месяц назад и два месяца вперед
-	startDate := time.Now().AddDate(0, -1, 0) // 1 месяц назад
-	endDate := time.Now().AddDate(0, 2, 0)    // 2 месяца вперед
+	startDate := b.today().AddDate(0, -1, 0) // 1 месяц назад
+	endDate := b.today().AddDate(0, 2, 0)    // 2 месяца вперед
 
 	bookings, err := b.db.GetBookingsByDateRange(context.Background(), startDate, endDate)
 	if err != nil {
 		log.Printf("Failed to get bookings for Google Sheets sync: %v", err)
-		return
+		return err
 	}
 
 	log.Printf("Syncing %d bookings to Google Sheets", len(bookings))
@@ -886,56 +1085,95 @@ func (b *Bot) SyncBookingsToSheets() {
 	var googleBookings []*models.Booking
 	for _, booking := range bookings {
 		googleBookings = append(googleBookings, &models.Booking{
-			ID:           booking.ID,
-			UserID:       booking.UserID,
-			ItemID:       booking.ItemID,
-			Date:         booking.Date,
-			Status:       booking.Status,
-			UserName:     booking.UserName,
-			Phone:        booking.Phone,
-			ItemName:     booking.ItemName,
-			Comment:      booking.Comment,
-			UserNickname: booking.UserNickname,
-			CreatedAt:    booking.CreatedAt,
-			UpdatedAt:    booking.UpdatedAt,
+			ID:             booking.ID,
+			UserID:         booking.UserID,
+			ItemID:         booking.ItemID,
+			Date:           booking.Date,
+			EndDate:        booking.EndDate,
+			Status:         booking.Status,
+			UserName:       booking.UserName,
+			Phone:          booking.Phone,
+			SecondaryPhone: booking.SecondaryPhone,
+			ItemName:       booking.ItemName,
+			Comment:        booking.Comment,
+			Source:         booking.Source,
+			Price:          booking.Price,
+			DepositPaid:    booking.DepositPaid,
+			UserNickname:   booking.UserNickname,
+			CreatedAt:      booking.CreatedAt,
+			UpdatedAt:      booking.UpdatedAt,
 		})
 	}
 
-	// Полностью перезаписываем лист с заявками
+	// Обновляем строки заявок периода, остальная история на листах сохраняется
+	start := time.Now()
 	err = b.sheetsService.ReplaceBookingsSheet(googleBookings)
+	metrics.ObserveSheetsSync(metrics.SheetsOperationReplace, start, err)
 	if err != nil {
 		log.Printf("Failed to sync bookings to Google Sheets: %v", err)
-	} else {
-		log.Printf("Bookings successfully synced to Google Sheets: %d records", len(googleBookings))
+		return err
 	}
 
-	// Также синхронизируем расписание
-	b.SyncScheduleToSheets()
+	log.Printf("Bookings successfully synced to Google Sheets: %d records", len(googleBookings))
+	return nil
 }
 
-// AppendBookingToSheets добавляет одно бронирование в Google Sheets
+// AppendBookingToSheets ставит в очередь добавление или обновление одного бронирования в Google Sheets
 func (b *Bot) AppendBookingToSheets(booking *models.Booking) {
-	if b.sheetsService == nil {
-		return
-	}
+	b.enqueueSheetTask(SheetTaskUpsertBooking, booking.ID)
+}
 
+// upsertBookingSheet добавляет или обновляет строку бронирования в Google Sheets
+func (b *Bot) upsertBookingSheet(booking *models.Booking) error {
 	googleBooking := &models.Booking{
-		ID:        booking.ID,
-		UserID:    booking.UserID,
-		ItemID:    booking.ItemID,
-		Date:      booking.Date,
-		Status:    booking.Status,
-		UserName:  booking.UserName,
-		Phone:     booking.Phone,
-		ItemName:  booking.ItemName,
-		CreatedAt: booking.CreatedAt,
-		UpdatedAt: booking.UpdatedAt,
-	}
-
-	err := b.sheetsService.AppendBooking(googleBooking)
+		ID:             booking.ID,
+		UserID:         booking.UserID,
+		ItemID:         booking.ItemID,
+		Date:           booking.Date,
+		EndDate:        booking.EndDate,
+		Status:         booking.Status,
+		UserName:       booking.UserName,
+		UserNickname:   booking.UserNickname,
+		Phone:          booking.Phone,
+		SecondaryPhone: booking.SecondaryPhone,
+		ItemName:       booking.ItemName,
+		Comment:        booking.Comment,
+		Source:         booking.Source,
+		Price:          booking.Price,
+		DepositPaid:    booking.DepositPaid,
+		CreatedAt:      booking.CreatedAt,
+		UpdatedAt:      booking.UpdatedAt,
+	}
+
+	start := time.Now()
+	err := b.sheetsService.UpsertBooking(googleBooking)
+	metrics.ObserveSheetsSync(metrics.SheetsOperationAppend, start, err)
 	if err != nil {
 		log.Printf("Failed to append booking to Google Sheets: %v", err)
-	} else {
-		log.Printf("Booking %d appended to Google Sheets", booking.ID)
+		return err
 	}
+
+	log.Printf("Booking %d appended to Google Sheets", booking.ID)
+	return nil
+}
+
+// UpdateBookingStatusInSheets ставит в очередь обновление статуса заявки в Google Sheets
+func (b *Bot) UpdateBookingStatusInSheets(bookingID int64) {
+	b.enqueueSheetTask(SheetTaskUpdateStatus, bookingID)
+}
+
+// updateBookingStatusSheet обновляет ячейку статуса заявки. Если строки еще нет, добавляет ее целиком.
+func (b *Bot) updateBookingStatusSheet(booking *models.Booking) error {
+	start := time.Now()
+	err := b.sheetsService.UpdateBookingStatus(booking.ID, booking.Date, booking.Status)
+	if errors.Is(err, google.ErrBookingRowNotFound) {
+		return b.upsertBookingSheet(booking)
+	}
+	metrics.ObserveSheetsSync(metrics.SheetsOperationStatus, start, err)
+	if err != nil {
+		log.Printf("Failed to update booking status in Google Sheets: %v", err)
+		return err
+	}
+
+	return nil
 }
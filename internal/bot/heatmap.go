@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/xuri/excelize/v2"
+)
+
+// defaultHeatmapDays период тепловой карты без аргументов команды, включая сегодня
+const defaultHeatmapDays = 30
+
+// maxHeatmapDays максимальная длина периода тепловой карты
+const maxHeatmapDays = 366
+
+// heatmapSheet название листа тепловой карты
+const heatmapSheet = "Загрузка"
+
+// handleHeatmapCommand обработка /heatmap [ДД.ММ.ГГГГ ДД.ММ.ГГГГ]: отправляет тепловую карту занятости.
+// Без аргументов строится на ближайшие defaultHeatmapDays дней.
+func (b *Bot) handleHeatmapCommand(update tgbotapi.Update, arg string) {
+	chatID := update.Message.Chat.ID
+	usage := fmt.Sprintf("Укажите период, например: /heatmap %s %s",
+		b.today().Format(b.dateLayout()), b.today().AddDate(0, 0, defaultHeatmapDays-1).Format(b.dateLayout()))
+
+	startDate := b.today()
+	endDate := startDate.AddDate(0, 0, defaultHeatmapDays-1)
+	if arg != "" {
+		parts := strings.Fields(arg)
+		if len(parts) != 2 {
+			b.sendMessage(chatID, usage)
+			return
+		}
+		var err error
+		if startDate, err = b.parseDate(parts[0]); err != nil {
+			b.sendMessage(chatID, usage)
+			return
+		}
+		if endDate, err = b.parseDate(parts[1]); err != nil {
+			b.sendMessage(chatID, usage)
+			return
+		}
+	}
+
+	if endDate.Before(startDate) {
+		b.sendMessage(chatID, "Конечная дата не может быть раньше начальной.")
+		return
+	}
+	if days := int(endDate.Sub(startDate).Hours()/24) + 1; days > maxHeatmapDays {
+		b.sendMessage(chatID, fmt.Sprintf("Период тепловой карты не может превышать %d дней.", maxHeatmapDays))
+		return
+	}
+
+	filePath, err := b.exportHeatmap(startDate, endDate)
+	if err != nil {
+		log.Printf("Error exporting heatmap: %v", err)
+		b.sendMessage(chatID, "Ошибка при создании тепловой карты")
+		return
+	}
+
+	caption := fmt.Sprintf("🔥 Загрузка за период %s - %s", startDate.Format(b.dateLayout()), endDate.Format(b.dateLayout()))
+	if err := b.sendDocument(chatID, filePath, caption); err != nil {
+		log.Printf("Error sending document: %v", err)
+		b.sendMessage(chatID, "Ошибка при отправке файла")
+	}
+}
+
+// exportHeatmap создает Excel файл с числом занятых единиц по аппаратам и дням.
+// Полностью занятые ячейки выделяются красным, а дни, когда заняты все аппараты, - в заголовке.
+// Последний столбец и последняя строка содержат итоги по аппаратам и по дням.
+func (b *Bot) exportHeatmap(startDate, endDate time.Time) (string, error) {
+	if err := os.MkdirAll(b.config.Exports.Path, 0755); err != nil {
+		return "", fmt.Errorf("error creating export directory: %v", err)
+	}
+
+	export, err := b.collectBookingsExport(startDate, endDate, false)
+	if err != nil {
+		return "", err
+	}
+
+	items := b.getItems()
+
+	f := excelize.NewFile()
+	index, err := f.NewSheet(heatmapSheet)
+	if err != nil {
+		return "", fmt.Errorf("error creating sheet: %v", err)
+	}
+	f.SetActiveSheet(index)
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#DDEBF7"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	fullDayStyle, _ := f.NewStyle(&excelize.Style{
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#FF0000"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true, Color: "#FFFFFF"},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	itemStyle, _ := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E2EFDA"}, Pattern: 1},
+		Font: &excelize.Font{Bold: true},
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D9D9D9"}, Pattern: 1},
+		Font:      &excelize.Font{Bold: true},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+
+	f.SetCellValue(heatmapSheet, "A1", fmt.Sprintf("Загрузка за период: %s - %s",
+		startDate.Format(b.dateLayout()), endDate.Format(b.dateLayout())))
+
+	totalCol := len(export.Dates) + 2
+	totalRow := len(items) + 3
+
+	itemTotals := make([]int, len(items))
+	for i, date := range export.Dates {
+		col := i + 2
+		cells := export.Cells[date.Format("2006-01-02")]
+
+		dayTotal := 0
+		dayFull := len(items) > 0
+		for j, item := range items {
+			booked := cells[item.ID].BookedCount
+			dayTotal += booked
+			itemTotals[j] += booked
+			if booked < int(item.TotalQuantity) {
+				dayFull = false
+			}
+
+			cell, _ := excelize.CoordinatesToCellName(col, j+3)
+			f.SetCellValue(heatmapSheet, cell, booked)
+			if styleID, err := heatmapCellStyle(f, booked, int(item.TotalQuantity)); err == nil {
+				f.SetCellStyle(heatmapSheet, cell, cell, styleID)
+			}
+		}
+
+		header, _ := excelize.CoordinatesToCellName(col, 2)
+		f.SetCellValue(heatmapSheet, header, date.Format("02.01"))
+		if dayFull {
+			f.SetCellStyle(heatmapSheet, header, header, fullDayStyle)
+		} else {
+			f.SetCellStyle(heatmapSheet, header, header, headerStyle)
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(col, totalRow)
+		f.SetCellValue(heatmapSheet, cell, dayTotal)
+		f.SetCellStyle(heatmapSheet, cell, cell, totalStyle)
+	}
+
+	grandTotal := 0
+	for j, item := range items {
+		cell, _ := excelize.CoordinatesToCellName(1, j+3)
+		f.SetCellValue(heatmapSheet, cell, exportItemTitle(item))
+		f.SetCellStyle(heatmapSheet, cell, cell, itemStyle)
+
+		cell, _ = excelize.CoordinatesToCellName(totalCol, j+3)
+		f.SetCellValue(heatmapSheet, cell, itemTotals[j])
+		f.SetCellStyle(heatmapSheet, cell, cell, totalStyle)
+		grandTotal += itemTotals[j]
+	}
+
+	cell, _ := excelize.CoordinatesToCellName(totalCol, 2)
+	f.SetCellValue(heatmapSheet, cell, "Итого")
+	f.SetCellStyle(heatmapSheet, cell, cell, headerStyle)
+
+	cell, _ = excelize.CoordinatesToCellName(1, totalRow)
+	f.SetCellValue(heatmapSheet, cell, "Итого")
+	f.SetCellStyle(heatmapSheet, cell, cell, totalStyle)
+
+	cell, _ = excelize.CoordinatesToCellName(totalCol, totalRow)
+	f.SetCellValue(heatmapSheet, cell, grandTotal)
+	f.SetCellStyle(heatmapSheet, cell, cell, totalStyle)
+
+	lastCol, _ := excelize.ColumnNumberToName(totalCol)
+	f.SetColWidth(heatmapSheet, "A", "A", 25)
+	f.SetColWidth(heatmapSheet, "B", lastCol, 7)
+	f.MergeCell(heatmapSheet, "A1", lastCol+"1")
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	f.SetCellStyle(heatmapSheet, "A1", "A1", titleStyle)
+	f.SetPanes(heatmapSheet, &excelize.Panes{Freeze: true, XSplit: 1, YSplit: 2, TopLeftCell: "B3", ActivePane: "bottomRight"})
+
+	f.DeleteSheet("Sheet1")
+
+	filePath := filepath.Join(b.config.Exports.Path, fmt.Sprintf("heatmap_%s_to_%s.xlsx",
+		startDate.Format("2006-01-02"), endDate.Format("2006-01-02")))
+	if err := f.SaveAs(filePath); err != nil {
+		return "", fmt.Errorf("error saving file: %v", err)
+	}
+
+	log.Printf("Heatmap file created: %s", filePath)
+	return filePath, nil
+}
+
+// heatmapCellStyle стиль ячейки тепловой карты по доле занятых единиц аппарата
+func heatmapCellStyle(f *excelize.File, booked, totalQuantity int) (int, error) {
+	style := &excelize.Style{Alignment: &excelize.Alignment{Horizontal: "center"}}
+	switch {
+	case booked == 0:
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{"#FFFFFF"}, Pattern: 1}
+	case booked >= totalQuantity:
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{"#FFC7CE"}, Pattern: 1}
+		style.Font = &excelize.Font{Bold: true, Color: "#9C0006"}
+	case booked*2 >= totalQuantity:
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{"#F8CBAD"}, Pattern: 1}
+	default:
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{"#FFEB9C"}, Pattern: 1}
+	}
+	return f.NewStyle(style)
+}
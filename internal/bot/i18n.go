@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// defaultLanguage язык, используемый при отсутствии перевода
+const defaultLanguage = "ru"
+
+// messages каталог сообщений: язык -> ключ -> текст.
+// Ключи с префиксом btn_ - подписи кнопок, они переводятся обратно в русский
+// вариант в canonicalButton, поэтому обработчики сравнивают только русский текст.
+var messages = map[string]map[string]string{
+	"ru": {
+		"menu_welcome":                    "Добро пожаловать! Выберите действие:",
+		"btn_create_booking":              "📋 СОЗДАТЬ ЗАЯВКУ",
+		"btn_view_schedule":               "📅 Посмотреть расписание",
+		"btn_items":                       "💼 Ассортимент",
+		"btn_my_bookings":                 "📊 Мои заявки",
+		"btn_manager_contacts":            "📞 Контакты менеджеров",
+		"btn_main_menu":                   "🏠 Главное меню",
+		"err_session_expired":             "Сессия устарела. Начните заново.",
+		"err_invalid_date":                "Неверный формат даты. Используйте %s или напишите «завтра», «пт»",
+		"date_format_hint":                "%s (например, %s)",
+		"err_past_date":                   "Нельзя бронировать на прошедшие даты. Выберите будущую дату.",
+		"err_availability_check":          "Произошла ошибка при проверке доступности. Попробуйте позже.",
+		"err_item_not_selected":           "Ошибка: не найден выбранный элемент. Начните заново.",
+		"err_booking_create":              "Произошла ошибка при создании заявки. Попробуйте позже.",
+		"err_slot_busy":                   "Эту дату сейчас бронирует кто-то еще. Попробуйте подтвердить заявку еще раз через несколько секунд.",
+		"err_too_many_requests":           "Слишком много запросов, попробуйте через минуту.",
+		"err_duplicate_booking":           "У вас уже есть заявка на эту дату",
+		"err_active_limit":                "У вас уже %d активных заявок - это максимум. Дождитесь завершения одной из них или отмените ненужную в «📊 Мои заявки».",
+		"booking_date_unavailable":        "К сожалению, на выбранную дату позиция недоступна. Выберите другую дату.",
+		"booking_no_longer_free":          "К сожалению, выбранная позиция больше не доступна. Пожалуйста, выберите другую дату.",
+		"booking_summary":                 "📋 Подтверждение заявки:\n\n🏢 Позиция: %s\n📅 Дата: %s\n👤 Имя: %s\n📱 Телефон: %s",
+		"booking_summary_comment":         "\n💬 Комментарий: %s",
+		"booking_summary_secondary_phone": "\n📱 Запасной телефон: %s",
+		"booking_summary_attachment":      "\n📎 Вложение прикреплено",
+		"attachment_saved":                "📎 Вложение прикреплено к заявке. Продолжайте оформление.",
+		"booking_created":                 "⏳ Ваша заявка #%d на позицию %s успешно создана. \nОжидайте подтверждения.",
+		"booking_confirmed":               "✅ Ваша заявка на %s %s подтверждена!",
+		"booking_checkin_code":            "\n\n🎫 Код для получения: %s\nПокажите его менеджеру.",
+		"booking_rejected":                "❌ К сожалению, ваша заявка была отклонена менеджером.",
+		"booking_expired":                 "⌛ Заявка #%d на %s %s не была подтверждена вовремя и отменена. Создайте новую заявку, если бронь ещё нужна.",
+		"booking_reminder":                "🔔 Напоминаем о заявке #%d: %s, %s.",
+		"reminder_ok_button":              "✅ Буду",
+		"reminder_cancel_button":          "❌ Отменить заявку",
+		"reminder_acknowledged":           "👍 Спасибо! Ждём вас.",
+		"reminder_not_active":             "Заявка уже не активна. Актуальный список - в «📊 Мои заявки».",
+	},
+	"en": {
+		"menu_welcome":                    "Welcome! Choose an action:",
+		"btn_create_booking":              "📋 NEW BOOKING",
+		"btn_view_schedule":               "📅 View schedule",
+		"btn_items":                       "💼 Equipment",
+		"btn_my_bookings":                 "📊 My bookings",
+		"btn_manager_contacts":            "📞 Manager contacts",
+		"btn_main_menu":                   "🏠 Main menu",
+		"err_session_expired":             "Your session has expired. Please start again.",
+		"err_invalid_date":                "Invalid date format. Use %s",
+		"date_format_hint":                "%s (for example, %s)",
+		"err_past_date":                   "You can't book a date in the past. Please choose a future date.",
+		"err_availability_check":          "Failed to check availability. Please try again later.",
+		"err_item_not_selected":           "Error: the selected item was not found. Please start again.",
+		"err_booking_create":              "Failed to create the booking. Please try again later.",
+		"err_slot_busy":                   "Someone else is booking this date right now. Please try confirming again in a few seconds.",
+		"err_too_many_requests":           "Too many requests, please try again in a minute.",
+		"err_duplicate_booking":           "You already have a booking for this date",
+		"err_active_limit":                "You already have %d active bookings, which is the maximum. Wait until one of them is completed or cancel one in «📊 My bookings».",
+		"booking_date_unavailable":        "Sorry, this item is not available on the selected date. Please choose another date.",
+		"booking_no_longer_free":          "Sorry, the selected item is no longer available. Please choose another date.",
+		"booking_summary":                 "📋 Booking summary:\n\n🏢 Item: %s\n📅 Date: %s\n👤 Name: %s\n📱 Phone: %s",
+		"booking_summary_comment":         "\n💬 Comment: %s",
+		"booking_summary_secondary_phone": "\n📱 Backup phone: %s",
+		"booking_summary_attachment":      "\n📎 Attachment added",
+		"attachment_saved":                "📎 The file is attached to your booking. Please continue.",
+		"booking_created":                 "⏳ Your booking #%d for %s has been created. \nPlease wait for confirmation.",
+		"booking_confirmed":               "✅ Your booking for %s on %s is confirmed!",
+		"booking_checkin_code":            "\n\n🎫 Check-in code: %s\nShow it to the manager.",
+		"booking_rejected":                "❌ Unfortunately, your booking was rejected by a manager.",
+		"booking_expired":                 "⌛ Booking #%d for %s on %s was not confirmed in time and has been cancelled. Please create a new booking if you still need it.",
+		"booking_reminder":                "🔔 Reminder about booking #%d: %s, %s.",
+		"reminder_ok_button":              "✅ I'll be there",
+		"reminder_cancel_button":          "❌ Cancel booking",
+		"reminder_acknowledged":           "👍 Thank you! See you soon.",
+		"reminder_not_active":             "This booking is no longer active. See «📊 My bookings» for the current list.",
+	},
+}
+
+// t возвращает сообщение по ключу на языке пользователя.
+// Если языка или ключа нет в каталоге, используется русский вариант.
+func (b *Bot) t(userID int64, key string, args ...interface{}) string {
+	text, ok := messages[b.userLanguage(userID)][key]
+	if !ok {
+		text, ok = messages[defaultLanguage][key]
+		if !ok {
+			return key
+		}
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// userLanguage определяет язык пользователя по сохраненному LanguageCode
+func (b *Bot) userLanguage(userID int64) string {
+	user, err := b.db.GetUserByTelegramID(context.Background(), userID)
+	if err != nil || user == nil || user.LanguageCode == "" {
+		return defaultLanguage
+	}
+
+	// Telegram передает коды вида "en-US", в каталоге хранится только язык
+	lang := strings.ToLower(strings.SplitN(user.LanguageCode, "-", 2)[0])
+	if _, ok := messages[lang]; !ok {
+		return defaultLanguage
+	}
+	return lang
+}
+
+// canonicalButton переводит подпись кнопки на любом языке в русский вариант,
+// который ожидают обработчики сообщений
+func canonicalButton(text string) string {
+	for lang, catalog := range messages {
+		if lang == defaultLanguage {
+			continue
+		}
+		for key, value := range catalog {
+			if strings.HasPrefix(key, "btn_") && value == text {
+				return messages[defaultLanguage][key]
+			}
+		}
+	}
+	return text
+}
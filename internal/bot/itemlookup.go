@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bronivik/internal/models"
+)
+
+// maxItemSuggestions сколько похожих названий предлагать, если аппарат не найден
+const maxItemSuggestions = 3
+
+// normalizeItemName приводит название к нижнему регистру и схлопывает пробелы
+func normalizeItemName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// findItemByNameOrID ищет аппарат по ID или названию без учета регистра и лишних пробелов
+func (b *Bot) findItemByNameOrID(query string) (models.Item, bool) {
+	if itemID, err := strconv.ParseInt(strings.TrimSpace(query), 10, 64); err == nil {
+		if item, ok := b.findItemByID(itemID); ok {
+			return item, true
+		}
+	}
+
+	normalized := normalizeItemName(query)
+	for _, item := range b.getItems() {
+		if normalizeItemName(item.Name) == normalized {
+			return item, true
+		}
+	}
+	return models.Item{}, false
+}
+
+// similarItemNames возвращает названия аппаратов, похожие на запрос: начинающиеся с него
+// или отличающиеся не более чем на треть длины (расстояние Левенштейна)
+func (b *Bot) similarItemNames(query string) []string {
+	normalized := normalizeItemName(query)
+	if normalized == "" {
+		return nil
+	}
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+
+	maxDistance := len([]rune(normalized))/3 + 1
+	for _, item := range b.getItems() {
+		name := normalizeItemName(item.Name)
+		distance := levenshtein(normalized, name)
+		if strings.HasPrefix(name, normalized) {
+			distance = 0
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{name: item.Name, distance: distance})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var names []string
+	for i := 0; i < len(candidates) && i < maxItemSuggestions; i++ {
+		names = append(names, candidates[i].name)
+	}
+	return names
+}
+
+// itemNotFoundMessage сообщение о ненайденном аппарате с подсказкой похожих названий
+func (b *Bot) itemNotFoundMessage(query string) string {
+	message := fmt.Sprintf("Аппарат «%s» не найден.", strings.TrimSpace(query))
+	if names := b.similarItemNames(query); len(names) > 0 {
+		message += "\nВозможно, вы имели в виду: " + strings.Join(names, ", ")
+	}
+	return message
+}
+
+// levenshtein считает расстояние редактирования между строками по символам
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"bronivik/internal/config"
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// getItems возвращает текущий список аппаратов.
+// Опубликованный срез не изменяется: перезагрузка и обслуживание заменяют его копией,
+// поэтому фоновые задачи могут читать его без блокировки после получения.
+func (b *Bot) getItems() []models.Item {
+	b.itemsMu.RLock()
+	defer b.itemsMu.RUnlock()
+	return b.items
+}
+
+// setItems публикует новый список аппаратов
+func (b *Bot) setItems(items []models.Item) {
+	b.itemsMu.Lock()
+	b.items = items
+	b.itemsMu.Unlock()
+}
+
+// updateItems меняет копию списка аппаратов и публикует ее
+func (b *Bot) updateItems(update func(items []models.Item)) {
+	b.itemsMu.Lock()
+	defer b.itemsMu.Unlock()
+
+	items := append([]models.Item(nil), b.items...)
+	update(items)
+	b.items = items
+}
+
+// SetItemsPath задает файл аппаратов, который перечитывает /reload_items
+func (b *Bot) SetItemsPath(path string) {
+	b.itemsPath = path
+}
+
+// itemsDiff изменения списка аппаратов при перезагрузке
+type itemsDiff struct {
+	added   []string
+	updated []string
+	removed []string
+}
+
+// diffItems сравнивает аппараты по ID
+func diffItems(oldItems, newItems []models.Item) itemsDiff {
+	var diff itemsDiff
+
+	oldByID := make(map[int64]models.Item, len(oldItems))
+	for _, item := range oldItems {
+		// Период обслуживания хранится в БД, а не в файле - не считаем его изменением
+		item.UnavailableUntil = time.Time{}
+		oldByID[item.ID] = item
+	}
+
+	newIDs := make(map[int64]bool, len(newItems))
+	for _, item := range newItems {
+		newIDs[item.ID] = true
+		old, ok := oldByID[item.ID]
+		switch {
+		case !ok:
+			diff.added = append(diff.added, item.Name)
+		case !reflect.DeepEqual(old, item):
+			diff.updated = append(diff.updated, item.Name)
+		}
+	}
+
+	for _, item := range oldItems {
+		if !newIDs[item.ID] {
+			diff.removed = append(diff.removed, item.Name)
+		}
+	}
+
+	return diff
+}
+
+// handleReloadItemsCommand перечитывает файл аппаратов без перезапуска бота.
+// Файл сначала проверяется целиком; при ошибке текущий список не меняется.
+// Заявки на удаленные аппараты сохраняются, но новые на них оформить нельзя.
+func (b *Bot) handleReloadItemsCommand(update tgbotapi.Update) {
+	path := b.itemsPath
+	if path == "" {
+		path = config.ItemsPath()
+	}
+
+	items, err := config.LoadItems(path)
+	if err != nil {
+		log.Printf("Error reloading items from %s: %v", path, err)
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("❌ Список аппаратов не обновлен, %s содержит ошибки:\n\n%v", path, err))
+		return
+	}
+
+	diff := diffItems(b.getItems(), items)
+
+	// Периоды обслуживания проставляем до публикации, чтобы никто не увидел список без них
+	b.applyItemMaintenance(items)
+	b.setItems(items)
+	b.db.SetItems(items)
+
+	log.Printf("Manager %d reloaded items from %s: added %d, updated %d, removed %d",
+		update.Message.From.ID, path, len(diff.added), len(diff.updated), len(diff.removed))
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("✅ Список аппаратов перезагружен (%d шт.)\n", len(items)))
+	writeItemNames(&message, "➕ Добавлено", diff.added)
+	writeItemNames(&message, "✏️ Изменено", diff.updated)
+	writeItemNames(&message, "➖ Удалено", diff.removed)
+	if len(diff.removed) > 0 {
+		message.WriteString("\nСуществующие заявки на удаленные аппараты сохранены.")
+	}
+	b.sendMessage(update.Message.Chat.ID, message.String())
+}
+
+// writeItemNames добавляет в отчет строку с количеством и названиями аппаратов
+func writeItemNames(message *strings.Builder, title string, names []string) {
+	message.WriteString(fmt.Sprintf("\n%s: %d", title, len(names)))
+	if len(names) > 0 {
+		message.WriteString(" - " + strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// loadItemMaintenance загружает периоды обслуживания аппаратов из БД
+func (b *Bot) loadItemMaintenance() {
+	b.updateItems(b.applyItemMaintenance)
+}
+
+// applyItemMaintenance проставляет аппаратам периоды обслуживания из БД.
+// Меняет переданный срез, поэтому вызывается только для еще не опубликованного списка.
+func (b *Bot) applyItemMaintenance(items []models.Item) {
+	maintenance, err := b.db.GetItemMaintenance(context.Background())
+	if err != nil {
+		log.Printf("Error loading item maintenance: %v", err)
+		return
+	}
+
+	for i := range items {
+		if until, ok := maintenance[items[i].ID]; ok {
+			items[i].UnavailableUntil = until
+		}
+	}
+}
+
+// setItemMaintenance обновляет период обслуживания аппарата в памяти (нулевое значение - снять)
+func (b *Bot) setItemMaintenance(itemID int64, until time.Time) {
+	b.updateItems(func(items []models.Item) {
+		for i := range items {
+			if items[i].ID == itemID {
+				items[i].UnavailableUntil = until
+				return
+			}
+		}
+	})
+}
+
+// inMaintenance проверяет, что аппарат на обслуживании в указанную дату.
+// Аппарат ищется по ID, потому что в состоянии пользователя хранится копия без актуального периода.
+func (b *Bot) inMaintenance(itemID int64, date time.Time) bool {
+	item, ok := b.findItemByID(itemID)
+	return ok && item.InMaintenance(date)
+}
+
+// bookableItems возвращает аппараты, доступные для новых заявок (не на обслуживании сегодня)
+func (b *Bot) bookableItems() []models.Item {
+	now := b.now()
+	var items []models.Item
+	for _, item := range b.getItems() {
+		if !item.InMaintenance(now) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// handleItemMaintenanceCommand переводит аппарат на обслуживание или возвращает его.
+// Формат: /item_maintenance <название или ID> <дата|off>. Существующие заявки не меняются.
+func (b *Bot) handleItemMaintenanceCommand(update tgbotapi.Update, arg string) {
+	usage := fmt.Sprintf("Использование: /item_maintenance <название или ID> <дата в формате %s>\n"+
+		"Досрочно вернуть аппарат: /item_maintenance <название или ID> off",
+		b.dateFormatHint(update.Message.From.ID))
+
+	fields := strings.Fields(arg)
+	if len(fields) < 2 {
+		b.sendMessage(update.Message.Chat.ID, b.itemMaintenanceList()+"\n\n"+usage)
+		return
+	}
+
+	// Название может состоять из нескольких слов, дата - последнее слово
+	untilStr := fields[len(fields)-1]
+	query := strings.Join(fields[:len(fields)-1], " ")
+	item, ok := b.findItemByNameOrID(query)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.itemNotFoundMessage(query)+"\n\n"+usage)
+		return
+	}
+
+	if strings.EqualFold(untilStr, "off") {
+		if err := b.db.ClearItemMaintenance(context.Background(), item.ID); err != nil {
+			log.Printf("Error clearing maintenance for item %d: %v", item.ID, err)
+			b.sendMessage(update.Message.Chat.ID, "Ошибка при снятии аппарата с обслуживания")
+			return
+		}
+		b.setItemMaintenance(item.ID, time.Time{})
+		log.Printf("Manager %d cleared maintenance for item %d", update.Message.From.ID, item.ID)
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("✅ %s снова доступен для бронирования", item.Name))
+		return
+	}
+
+	until, err := b.parseDate(untilStr)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
+		return
+	}
+	if until.Before(b.today()) {
+		b.sendMessage(update.Message.Chat.ID, "Дата окончания обслуживания уже прошла.")
+		return
+	}
+
+	if err := b.db.SetItemMaintenance(context.Background(), item.ID, until); err != nil {
+		log.Printf("Error setting maintenance for item %d: %v", item.ID, err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при переводе аппарата на обслуживание")
+		return
+	}
+	b.setItemMaintenance(item.ID, until)
+	log.Printf("Manager %d set maintenance for item %d until %s", update.Message.From.ID, item.ID, until.Format("2006-01-02"))
+
+	b.sendMessage(update.Message.Chat.ID, fmt.Sprintf(
+		"🔧 %s на обслуживании до %s включительно.\nАппарат скрыт для новых заявок, существующие заявки не изменены.",
+		item.Name, until.Format(b.dateLayout())))
+}
+
+// itemMaintenanceList возвращает список аппаратов, которые сейчас на обслуживании
+func (b *Bot) itemMaintenanceList() string {
+	var message strings.Builder
+	now := b.now()
+	for _, item := range b.getItems() {
+		if item.InMaintenance(now) {
+			message.WriteString(fmt.Sprintf("🔧 %s - до %s\n", item.Name, item.UnavailableUntil.Format(b.dateLayout())))
+		}
+	}
+
+	if message.Len() == 0 {
+		return "Аппаратов на обслуживании нет."
+	}
+	return "Аппараты на обслуживании:\n" + strings.TrimSuffix(message.String(), "\n")
+}
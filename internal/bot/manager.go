@@ -2,12 +2,17 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"bronivik/internal/database"
+	"bronivik/internal/events"
+	"bronivik/internal/google"
+	"bronivik/internal/metrics"
 	"bronivik/internal/models"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -46,6 +51,42 @@ func (b *Bot) handleManagerCommand(update tgbotapi.Update) bool {
 			}
 		}
 
+	case strings.HasPrefix(text, "/export_bookings"):
+		b.startExportBookings(update, strings.TrimSpace(strings.TrimPrefix(text, "/export_bookings")) == "archive")
+
+	case strings.HasPrefix(text, "/heatmap"):
+		b.handleHeatmapCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/heatmap")))
+
+	case strings.HasPrefix(text, "/archive"):
+		b.handleArchiveCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/archive")))
+
+	case strings.HasPrefix(text, "/checkin"):
+		b.handleCheckinCommand(update, strings.TrimPrefix(text, "/checkin"))
+
+	case strings.HasPrefix(text, "/item_maintenance"):
+		b.handleItemMaintenanceCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/item_maintenance")))
+
+	case text == "/reload_items":
+		b.handleReloadItemsCommand(update)
+
+	case strings.HasPrefix(text, "/blacklist"):
+		b.handleBlacklistCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/blacklist")), true)
+
+	case strings.HasPrefix(text, "/unblacklist"):
+		b.handleBlacklistCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/unblacklist")), false)
+
+	case strings.HasPrefix(text, "/reset_user"):
+		b.handleResetUserCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/reset_user")))
+
+	case strings.HasPrefix(text, "/broadcast"):
+		b.handleBroadcastCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/broadcast")))
+
+	case strings.HasPrefix(text, "/cancel_booking"):
+		b.handleCancelBookingCommand(update, strings.TrimSpace(strings.TrimPrefix(text, "/cancel_booking")))
+
+	case strings.HasPrefix(text, "/find_booking"):
+		b.findBookingsByPhone(update, strings.TrimSpace(strings.TrimPrefix(text, "/find_booking")))
+
 	case state != nil && state.CurrentStep == "manager_waiting_client_name":
 		b.handleManagerClientName(update, text, state)
 
@@ -61,6 +102,30 @@ func (b *Bot) handleManagerCommand(update tgbotapi.Update) bool {
 	case state != nil && state.CurrentStep == "manager_waiting_end_date":
 		b.handleManagerEndDate(update, text, state)
 
+	case state != nil && state.CurrentStep == "manager_waiting_weekly_start_date":
+		b.handleManagerWeeklyStartDate(update, text, state)
+
+	case state != nil && state.CurrentStep == "manager_waiting_weekly_count":
+		b.handleManagerWeeklyCount(update, text, state)
+
+	case state != nil && state.CurrentStep == "manager_export_start_date":
+		b.handleExportStartDate(update, text, state)
+
+	case state != nil && state.CurrentStep == "manager_export_end_date":
+		b.handleExportEndDate(update, text, state)
+
+	case state != nil && state.CurrentStep == "manager_reassign_name":
+		b.handleReassignName(update, text, state)
+
+	case state != nil && state.CurrentStep == "manager_reassign_phone":
+		b.handleReassignPhone(update, text, state)
+
+	case state != nil && state.CurrentStep == "manager_waiting_note":
+		b.handleManagerNote(update, text, state)
+
+	case state != nil && state.CurrentStep == "manager_waiting_reschedule_date":
+		b.handleManagerRescheduleDate(update, text, state)
+
 	case state != nil && state.CurrentStep == "manager_waiting_comment":
 		b.handleManagerComment(update, text, state)
 
@@ -74,11 +139,10 @@ func (b *Bot) handleManagerCommand(update tgbotapi.Update) bool {
 
 	case text == "🔄 Синхронизировать бронирования (Google Sheets)":
 		b.SyncBookingsToSheets()
-		b.sendMessage(update.Message.Chat.ID, "✅ Бронирования синхронизированы с Google Таблицей")
+		b.sendMessage(update.Message.Chat.ID, "✅ Синхронизация бронирований с Google Таблицей запущена")
 
 	case text == "📅 Синхронизировать расписание (Google Sheets)":
-		b.SyncScheduleToSheets()
-		b.sendMessage(update.Message.Chat.ID, "✅ Расписание синхронизировано с Google Таблицей")
+		b.handleScheduleSyncRequest(update.Message.Chat.ID)
 	}
 
 	return false
@@ -129,17 +193,27 @@ func (b *Bot) handleManagerAction(update tgbotapi.Update) {
 		b.completeBooking(booking, callback.Message.Chat.ID)
 	}
 
-	// Обновляем сообщение у менеджера
-	editMsg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID,
-		fmt.Sprintf("✅ Заявка #%d обработана\nДействие: %s", bookingID, action))
-	b.bot.Send(editMsg)
+	// Перерисовываем кнопки под новый статус, чтобы с сообщением можно было работать дальше
+	b.refreshManagerBookingKeyboard(callback.Message.Chat.ID, callback.Message.MessageID, bookingID)
+}
 
-	// СИНХРОНИЗИРУЕМ ВСЕ ИЗМЕНЕНИЯ
-	go func() {
-		time.Sleep(1 * time.Second) // Небольшая задержка для завершения операции в БД
-		b.SyncBookingsToSheets()
-		b.SyncScheduleToSheets()
-	}()
+// refreshManagerBookingKeyboard заменяет клавиатуру сообщения о заявке на актуальную для ее текущего статуса.
+// Текст сообщения не меняется.
+func (b *Bot) refreshManagerBookingKeyboard(chatID int64, messageID int, bookingID int64) {
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		log.Printf("Error getting booking %d for keyboard refresh: %v", bookingID, err)
+		return
+	}
+
+	var activeInGroup int
+	if booking.GroupID != "" {
+		if _, activeInGroup, err = b.bookingGroupCounts(booking.GroupID); err != nil {
+			log.Printf("Error getting booking group %s: %v", booking.GroupID, err)
+		}
+	}
+
+	b.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, b.managerBookingKeyboard(booking, activeInGroup)))
 }
 
 // startManagerBooking начало создания заявки менеджером
@@ -159,23 +233,30 @@ func (b *Bot) startManagerBooking(update tgbotapi.Update) {
 
 // handleManagerClientName обработка ввода имени клиента
 func (b *Bot) handleManagerClientName(update tgbotapi.Update, text string, state *models.UserState) {
-	state.TempData["client_name"] = text
+	name, err := userNameField.clean(text)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
+
+	state.TempData["client_name"] = name
 	b.setUserState(update.Message.From.ID, "manager_waiting_client_phone", state.TempData)
 
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "📱 Введите телефон клиента:")
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "📱 Введите телефон клиента (запасной номер можно указать через запятую):")
 	b.bot.Send(msg)
 }
 
 // handleManagerClientPhone обработка ввода телефона клиента
 func (b *Bot) handleManagerClientPhone(update tgbotapi.Update, text string, state *models.UserState) {
-	// Нормализуем телефон
-	normalizedPhone := b.normalizePhone(text)
-	if normalizedPhone == "" {
-		b.sendMessage(update.Message.Chat.ID, "Неверный формат номера телефона. Пожалуйста, введите номер в формате +7XXXXXXXXXX или 8XXXXXXXXXX")
+	// Нормализуем телефон, через запятую можно указать запасной номер клиента
+	normalizedPhone, secondaryPhone, ok := b.parsePhones(text)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, "Неверный формат номера телефона. Пожалуйста, введите номер в формате +7XXXXXXXXXX, 8XXXXXXXXXX или с кодом страны, например +375XXXXXXXXX. Запасной номер можно указать через запятую")
 		return
 	}
 
 	state.TempData["client_phone"] = normalizedPhone
+	state.TempData["client_secondary_phone"] = secondaryPhone
 	b.setUserState(update.Message.From.ID, "manager_waiting_item_selection", state.TempData)
 
 	// Показываем выбор аппарата с пагинацией
@@ -184,18 +265,19 @@ func (b *Bot) handleManagerClientPhone(update tgbotapi.Update, text string, stat
 
 // sendManagerItemsPage отправляет страницу с аппаратами для менеджера
 func (b *Bot) sendManagerItemsPage(chatID, userID int64, page int) {
+	items := b.getItems()
 	itemsPerPage := 8
 	startIdx := page * itemsPerPage
 	endIdx := startIdx + itemsPerPage
-	if endIdx > len(b.items) {
-		endIdx = len(b.items)
+	if endIdx > len(items) {
+		endIdx = len(items)
 	}
 
 	var message strings.Builder
 	message.WriteString("🏢 *Выберите аппарат:*\n\n")
-	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(b.items)+itemsPerPage-1)/itemsPerPage))
+	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(items)+itemsPerPage-1)/itemsPerPage))
 
-	currentItems := b.items[startIdx:endIdx]
+	currentItems := items[startIdx:endIdx]
 	for i, item := range currentItems {
 		message.WriteString(fmt.Sprintf("%d. *%s*\n", startIdx+i+1, item.Name))
 		message.WriteString(fmt.Sprintf("   📝 %s\n", item.Description))
@@ -218,7 +300,7 @@ func (b *Bot) sendManagerItemsPage(chatID, userID int64, page int) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("manager_items_page:%d", page-1)))
 	}
 
-	if endIdx < len(b.items) {
+	if endIdx < len(items) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("manager_items_page:%d", page+1)))
 	}
 
@@ -248,7 +330,7 @@ func (b *Bot) handleManagerItemSelection(update tgbotapi.Update) {
 	}
 
 	var selectedItem models.Item
-	for _, item := range b.items {
+	for _, item := range b.getItems() {
 		if item.ID == itemID {
 			selectedItem = item
 			break
@@ -277,6 +359,9 @@ func (b *Bot) handleManagerItemSelection(update tgbotapi.Update) {
 			tgbotapi.NewInlineKeyboardButtonData("📅 Одна дата", "manager_single_date"),
 			tgbotapi.NewInlineKeyboardButtonData("📆 Интервал дат", "manager_date_range"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Еженедельно", "manager_weekly"),
+		),
 	)
 	msg.ReplyMarkup = &keyboard
 
@@ -292,24 +377,35 @@ func (b *Bot) handleManagerDateType(update tgbotapi.Update, dateType string) {
 		return
 	}
 
-	if dateType == "single" {
+	switch dateType {
+	case "single":
 		state.TempData["date_type"] = "single"
 		b.setUserState(callback.From.ID, "manager_waiting_single_date", state.TempData)
 
 		editMsg := tgbotapi.NewEditMessageText(
 			callback.Message.Chat.ID,
 			callback.Message.MessageID,
-			"📅 Введите дату бронирования в формате ДД.ММ.ГГГГ (например, 25.12.2024):",
+			fmt.Sprintf("📅 Введите дату бронирования в формате %s:", b.dateFormatHint(callback.From.ID)),
 		)
 		b.bot.Send(editMsg)
-	} else {
+	case "weekly":
+		state.TempData["date_type"] = "weekly"
+		b.setUserState(callback.From.ID, "manager_waiting_weekly_start_date", state.TempData)
+
+		editMsg := tgbotapi.NewEditMessageText(
+			callback.Message.Chat.ID,
+			callback.Message.MessageID,
+			fmt.Sprintf("📅 Введите дату первого бронирования в формате %s:", b.dateFormatHint(callback.From.ID)),
+		)
+		b.bot.Send(editMsg)
+	default:
 		state.TempData["date_type"] = "range"
 		b.setUserState(callback.From.ID, "manager_waiting_start_date", state.TempData)
 
 		editMsg := tgbotapi.NewEditMessageText(
 			callback.Message.Chat.ID,
 			callback.Message.MessageID,
-			"📅 Введите начальную дату интервала в формате ДД.ММ.ГГГГ (например, 25.12.2024):",
+			fmt.Sprintf("📅 Введите начальную дату интервала в формате %s:", b.dateFormatHint(callback.From.ID)),
 		)
 		b.bot.Send(editMsg)
 	}
@@ -319,15 +415,20 @@ func (b *Bot) handleManagerDateType(update tgbotapi.Update, dateType string) {
 
 // handleManagerSingleDate обработка ввода одной даты
 func (b *Bot) handleManagerSingleDate(update tgbotapi.Update, dateStr string, state *models.UserState) {
-	date, err := time.Parse("02.01.2006", dateStr)
+	date, err := b.parseDate(dateStr)
 	if err != nil {
-		b.sendMessage(update.Message.Chat.ID, "Неверный формат даты. Используйте ДД.ММ.ГГГГ (например, 25.12.2024)")
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
 		return
 	}
 
-	// Проверяем, что дата не в прошлом
-	if date.Before(time.Now().AddDate(0, 0, -1)) {
-		b.sendMessage(update.Message.Chat.ID, "Нельзя бронировать на прошедшие даты. Выберите будущую дату.")
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
+	if err := b.validateBookingDate(selectedItem, date); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
 		return
 	}
 
@@ -339,33 +440,44 @@ func (b *Bot) handleManagerSingleDate(update tgbotapi.Update, dateStr string, st
 
 // handleManagerStartDate обработка ввода начальной даты интервала
 func (b *Bot) handleManagerStartDate(update tgbotapi.Update, dateStr string, state *models.UserState) {
-	startDate, err := time.Parse("02.01.2006", dateStr)
+	startDate, err := b.parseDate(dateStr)
 	if err != nil {
-		b.sendMessage(update.Message.Chat.ID, "Неверный формат даты. Используйте ДД.ММ.ГГГГ (например, 25.12.2024)")
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
 		return
 	}
 
-	// Проверяем, что дата не в прошлом
-	if startDate.Before(time.Now().AddDate(0, 0, -1)) {
-		b.sendMessage(update.Message.Chat.ID, "Нельзя бронировать на прошедшие даты. Выберите будущую дату.")
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
+	if err := b.validateBookingDate(selectedItem, startDate); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
 		return
 	}
 
 	state.TempData["start_date"] = startDate
 	b.setUserState(update.Message.From.ID, "manager_waiting_end_date", state.TempData)
 
-	b.sendMessage(update.Message.Chat.ID, "📅 Введите конечную дату интервала в формате ДД.ММ.ГГГГ:")
+	b.sendMessage(update.Message.Chat.ID,
+		fmt.Sprintf("📅 Введите конечную дату интервала в формате %s:", b.dateFormatHint(update.Message.From.ID)))
 }
 
 // handleManagerEndDate обработка ввода конечной даты интервала
 func (b *Bot) handleManagerEndDate(update tgbotapi.Update, dateStr string, state *models.UserState) {
-	endDate, err := time.Parse("02.01.2006", dateStr)
+	endDate, err := b.parseDate(dateStr)
 	if err != nil {
-		b.sendMessage(update.Message.Chat.ID, "Неверный формат даты. Используйте ДД.ММ.ГГГГ (например, 25.12.2024)")
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
 		return
 	}
 
-	startDate := state.TempData["start_date"].(time.Time)
+	startDate, ok := state.GetTime("start_date")
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
 
 	// Проверяем, что конечная дата не раньше начальной
 	if endDate.Before(startDate) {
@@ -373,9 +485,28 @@ func (b *Bot) handleManagerEndDate(update tgbotapi.Update, dateStr string, state
 		return
 	}
 
-	// Создаем список всех дат в интервале
+	if err := b.validateRangeLength(startDate, endDate); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error()+" Введите более раннюю конечную дату:")
+		return
+	}
+
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
+	if err := b.validateBookingDate(selectedItem, endDate); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
+
+	// Создаем список всех дат в интервале, пропуская выходные дни
 	var dates []time.Time
 	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		if b.isClosedDay(d) {
+			continue
+		}
 		dates = append(dates, d)
 	}
 
@@ -385,8 +516,217 @@ func (b *Bot) handleManagerEndDate(update tgbotapi.Update, dateStr string, state
 	b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("💬 Введите комментарий к заявке (будет применен ко всем %d дням):", len(dates)))
 }
 
+// startExportBookings начинает выгрузку заявок за период в Excel или CSV
+func (b *Bot) startExportBookings(update tgbotapi.Update, includeArchived bool) {
+	b.setUserState(update.Message.From.ID, "manager_export_start_date", map[string]interface{}{
+		"include_archived": includeArchived,
+	})
+	b.sendMessage(update.Message.Chat.ID,
+		fmt.Sprintf("📅 Введите начальную дату периода выгрузки в формате %s:", b.dateFormatHint(update.Message.From.ID)))
+}
+
+// handleExportStartDate обработка ввода начальной даты выгрузки
+func (b *Bot) handleExportStartDate(update tgbotapi.Update, dateStr string, state *models.UserState) {
+	startDate, err := b.parseDate(dateStr)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
+		return
+	}
+
+	state.TempData["start_date"] = startDate
+	b.setUserState(update.Message.From.ID, "manager_export_end_date", state.TempData)
+
+	b.sendMessage(update.Message.Chat.ID,
+		fmt.Sprintf("📅 Введите конечную дату периода выгрузки в формате %s:", b.dateFormatHint(update.Message.From.ID)))
+}
+
+// handleExportEndDate обработка ввода конечной даты и отправка файла выгрузки
+func (b *Bot) handleExportEndDate(update tgbotapi.Update, dateStr string, state *models.UserState) {
+	endDate, err := b.parseDate(dateStr)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
+		return
+	}
+
+	startDate, ok := state.GetTime("start_date")
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
+
+	if endDate.Before(startDate) {
+		b.sendMessage(update.Message.Chat.ID, "Конечная дата не может быть раньше начальной.")
+		return
+	}
+
+	includeArchived, _ := state.TempData["include_archived"].(bool)
+
+	dailyBookings, err := b.db.GetDailyBookings(context.Background(), startDate, endDate, includeArchived)
+	if err != nil {
+		log.Printf("Error getting bookings for export: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при получении заявок")
+		return
+	}
+
+	if len(dailyBookings) == 0 {
+		b.clearUserState(update.Message.From.ID)
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("За период %s - %s заявок нет, файл не создан.",
+			startDate.Format(b.dateLayout()), endDate.Format(b.dateLayout())))
+		return
+	}
+
+	state.TempData["end_date"] = endDate
+	b.setUserState(update.Message.From.ID, "manager_export_format", state.TempData)
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "📁 Выберите формат файла:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📊 Excel (.xlsx)", "export_format:xlsx"),
+			tgbotapi.NewInlineKeyboardButtonData("📄 CSV", "export_format:csv"),
+		),
+	)
+	b.bot.Send(msg)
+}
+
+// handleExportFormat создает выгрузку заявок в выбранном формате и отправляет файл.
+// Формат callback: export_format:<xlsx|csv>
+func (b *Bot) handleExportFormat(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	state := b.getUserState(callback.From.ID)
+	if state == nil || state.CurrentStep != "manager_export_format" {
+		b.sendMessage(callback.Message.Chat.ID, "Выгрузка устарела. Начните заново: /export_bookings")
+		return
+	}
+
+	startDate, _ := state.TempData["start_date"].(time.Time)
+	endDate, _ := state.TempData["end_date"].(time.Time)
+	includeArchived, _ := state.TempData["include_archived"].(bool)
+	b.clearUserState(callback.From.ID)
+
+	var filePath string
+	var err error
+	if strings.TrimPrefix(callback.Data, "export_format:") == "csv" {
+		filePath, err = b.exportToCSV(startDate, endDate, includeArchived)
+	} else {
+		filePath, err = b.exportToExcel(startDate, endDate, includeArchived)
+	}
+	if err != nil {
+		log.Printf("Error exporting bookings: %v", err)
+		b.sendMessage(callback.Message.Chat.ID, "Ошибка при создании файла экспорта")
+		return
+	}
+
+	caption := fmt.Sprintf("📊 Заявки за период %s - %s", startDate.Format(b.dateLayout()), endDate.Format(b.dateLayout()))
+	if err := b.sendDocument(callback.Message.Chat.ID, filePath, caption); err != nil {
+		log.Printf("Error sending document: %v", err)
+		b.sendMessage(callback.Message.Chat.ID, "Ошибка при отправке файла")
+	}
+}
+
+// handleManagerWeeklyStartDate обработка ввода первой даты еженедельного бронирования
+func (b *Bot) handleManagerWeeklyStartDate(update tgbotapi.Update, dateStr string, state *models.UserState) {
+	startDate, err := b.parseDate(dateStr)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
+		return
+	}
+
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
+	if err := b.validateBookingDate(selectedItem, startDate); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
+
+	state.TempData["start_date"] = startDate
+	b.setUserState(update.Message.From.ID, "manager_waiting_weekly_count", state.TempData)
+
+	b.sendMessage(update.Message.Chat.ID,
+		fmt.Sprintf("🔁 Введите количество повторений (от 2 до %d), бронирования будут созданы каждую неделю начиная с %s:",
+			b.maxWeeklyCount(), startDate.Format(b.dateLayout())))
+}
+
+// handleManagerWeeklyCount обработка ввода количества еженедельных повторений
+func (b *Bot) handleManagerWeeklyCount(update tgbotapi.Update, text string, state *models.UserState) {
+	count, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || count < 2 || count > b.maxWeeklyCount() {
+		b.sendMessage(update.Message.Chat.ID,
+			fmt.Sprintf("Введите число от 2 до %d", b.maxWeeklyCount()))
+		return
+	}
+
+	startDate, ok := state.GetTime("start_date")
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
+
+	// Каждая дата серии проверяется отдельно: выходные, обслуживание и окно бронирования пропускаются
+	dates, skipped := b.splitBookableDates(selectedItem, weeklyDates(startDate, count))
+	if len(dates) < 2 {
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("В серии осталось меньше двух доступных дат:\n%s\nВведите другое количество повторений:",
+			strings.Join(skipped, "\n")))
+		return
+	}
+
+	state.TempData["dates"] = dates
+	b.setUserState(update.Message.From.ID, "manager_waiting_comment", state.TempData)
+
+	if len(skipped) > 0 {
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("⚠️ Пропущено дат: %d\n%s", len(skipped), strings.Join(skipped, "\n")))
+	}
+	b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("💬 Введите комментарий к заявке (будет применен ко всем %d датам):", len(dates)))
+}
+
+// splitBookableDates делит даты на доступные для бронирования аппарата и пропущенные.
+// Для пропущенных возвращается строка с датой и причиной из validateBookingDate.
+func (b *Bot) splitBookableDates(item models.Item, dates []time.Time) ([]time.Time, []string) {
+	var bookable []time.Time
+	var skipped []string
+	for _, date := range dates {
+		if err := b.validateBookingDate(item, date); err != nil {
+			skipped = append(skipped, fmt.Sprintf("• %s: %s", date.Format(b.dateLayout()), err.Error()))
+			continue
+		}
+		bookable = append(bookable, date)
+	}
+	return bookable, skipped
+}
+
+// weeklyDates возвращает count дат с шагом в 7 дней начиная с startDate
+func weeklyDates(startDate time.Time, count int) []time.Time {
+	dates := make([]time.Time, 0, count)
+	for i := 0; i < count; i++ {
+		dates = append(dates, startDate.AddDate(0, 0, 7*i))
+	}
+	return dates
+}
+
 // handleManagerComment обработка ввода комментария
-func (b *Bot) handleManagerComment(update tgbotapi.Update, comment string, state *models.UserState) {
+func (b *Bot) handleManagerComment(update tgbotapi.Update, text string, state *models.UserState) {
+	comment, err := commentField.clean(text)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
+
 	state.TempData["comment"] = comment
 	b.setUserState(update.Message.From.ID, "manager_confirm_booking", state.TempData)
 
@@ -396,25 +736,39 @@ func (b *Bot) handleManagerComment(update tgbotapi.Update, comment string, state
 
 // showManagerBookingConfirmation показывает подтверждение заявки менеджером
 func (b *Bot) showManagerBookingConfirmation(update tgbotapi.Update, state *models.UserState) {
-	clientName := state.TempData["client_name"].(string)
-	clientPhone := state.TempData["client_phone"].(string)
-	selectedItem := state.TempData["selected_item"].(models.Item)
-	dates := state.TempData["dates"].([]time.Time)
-	comment := state.TempData["comment"].(string)
-	dateType := state.TempData["date_type"].(string)
+	clientName, okName := state.GetString("client_name")
+	clientPhone, okPhone := state.GetString("client_phone")
+	selectedItem, okItem := state.TempData["selected_item"].(models.Item)
+	comment, okComment := state.GetString("comment")
+	dateType, okType := state.GetString("date_type")
+	dates, okDates := state.GetDates("dates")
+	if !okName || !okPhone || !okItem || !okComment || !okType || !okDates {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
 
 	var message strings.Builder
 	message.WriteString("📋 *Подтверждение заявки:*\n\n")
 	message.WriteString(fmt.Sprintf("👤 *Клиент:* %s\n", clientName))
 	message.WriteString(fmt.Sprintf("📱 *Телефон:* %s\n", clientPhone))
+	if secondaryPhone, _ := state.TempData["client_secondary_phone"].(string); secondaryPhone != "" {
+		message.WriteString(fmt.Sprintf("📱 *Запасной телефон:* %s\n", secondaryPhone))
+	}
 	message.WriteString(fmt.Sprintf("🏢 *Аппарат:* %s\n", selectedItem.Name))
 
-	if dateType == "single" {
-		message.WriteString(fmt.Sprintf("📅 *Дата:* %s\n", dates[0].Format("02.01.2006")))
-	} else {
+	switch dateType {
+	case "single":
+		message.WriteString(fmt.Sprintf("📅 *Дата:* %s\n", dates[0].Format(b.dateLayout())))
+	case "weekly":
+		message.WriteString(fmt.Sprintf("🔁 *Еженедельно:* %d раз\n", len(dates)))
+		for _, date := range dates {
+			message.WriteString(fmt.Sprintf("   • %s\n", date.Format(b.dateLayout())))
+		}
+	default:
 		message.WriteString(fmt.Sprintf("📅 *Интервал:* %s - %s (%d дней)\n",
-			dates[0].Format("02.01.2006"),
-			dates[len(dates)-1].Format("02.01.2006"),
+			dates[0].Format(b.dateLayout()),
+			dates[len(dates)-1].Format(b.dateLayout()),
 			len(dates)))
 	}
 
@@ -436,51 +790,95 @@ func (b *Bot) showManagerBookingConfirmation(update tgbotapi.Update, state *mode
 
 // createManagerBookings создает заявки менеджера
 func (b *Bot) createManagerBookings(update tgbotapi.Update, state *models.UserState) {
-	clientName := state.TempData["client_name"].(string)
-	clientPhone := state.TempData["client_phone"].(string)
-	selectedItem := state.TempData["selected_item"].(models.Item)
-	dates := state.TempData["dates"].([]time.Time)
-	comment := state.TempData["comment"].(string)
+	clientName, okName := state.GetString("client_name")
+	clientPhone, okPhone := state.GetString("client_phone")
+	clientSecondaryPhone, _ := state.TempData["client_secondary_phone"].(string)
+	selectedItem, okItem := state.TempData["selected_item"].(models.Item)
+	comment, okComment := state.GetString("comment")
+	dates, okDates := state.GetDates("dates")
+	if !okName || !okPhone || !okItem || !okComment || !okDates {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		return
+	}
 
 	var createdBookings []*models.Booking
 	var failedDates []string
 
-	// Создаем заявки на каждую дату
+	// Заявки на несколько дат объединяются в группу, чтобы их можно было отменить одним действием
+	var groupID string
+	if len(dates) > 1 {
+		groupID = fmt.Sprintf("%d-%d", update.Message.From.ID, time.Now().UnixNano())
+	}
+
+	// Менеджер создает сразу подтвержденные заявки. Для аппаратов с двойным подтверждением
+	// оформление считается первым подтверждением, окончательно подтверждает другой менеджер.
+	status, firstApprovedBy := "confirmed", int64(0)
+	if selectedItem.RequiresDualApproval {
+		status, firstApprovedBy = "awaiting_approval", update.Message.From.ID
+	}
+
+	// Создаем заявки на каждую дату. Даты проверяются повторно: пока менеджер вводил данные,
+	// аппарат могли поставить на обслуживание, а дата - выйти из окна бронирования
 	for _, date := range dates {
+		if err := b.validateBookingDate(selectedItem, date); err != nil {
+			failedDates = append(failedDates, date.Format(b.dateLayout()))
+			continue
+		}
+
+		unlock, ok := b.lockSlot(selectedItem.ID, date)
+		if !ok {
+			failedDates = append(failedDates, date.Format(b.dateLayout()))
+			continue
+		}
+
 		// Проверяем доступность
 		available, err := b.db.CheckAvailability(context.Background(), selectedItem.ID, date)
 		if err != nil {
 			log.Printf("Error checking availability: %v", err)
-			failedDates = append(failedDates, date.Format("02.01.2006"))
+			unlock()
+			failedDates = append(failedDates, date.Format(b.dateLayout()))
 			continue
 		}
 
 		if !available {
-			failedDates = append(failedDates, date.Format("02.01.2006"))
+			unlock()
+			failedDates = append(failedDates, date.Format(b.dateLayout()))
 			continue
 		}
 
 		// Создаем бронирование
 		booking := &models.Booking{
-			UserID:       update.Message.From.ID, // ID менеджера
-			UserName:     clientName,
-			UserNickname: clientName,
-			Phone:        clientPhone,
-			ItemID:       selectedItem.ID,
-			ItemName:     selectedItem.Name,
-			Date:         date,
-			Status:       "confirmed", // Менеджер создает сразу подтвержденные заявки
-			Comment:      comment,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+			UserID:             0, // Telegram клиента неизвестен
+			UserName:           clientName,
+			UserNickname:       clientName,
+			Phone:              clientPhone,
+			SecondaryPhone:     clientSecondaryPhone,
+			ItemID:             selectedItem.ID,
+			ItemName:           selectedItem.Name,
+			Date:               date,
+			Status:             status,
+			Comment:            comment,
+			CreatedAt:          time.Now(),
+			UpdatedAt:          time.Now(),
+			CreatedByManagerID: update.Message.From.ID,
+			Source:             models.BookingSourceManager,
+			GroupID:            groupID,
+			Price:              selectedItem.Price,
+			FirstApprovedBy:    firstApprovedBy,
 		}
 
 		err = b.db.CreateBooking(context.Background(), booking)
+		unlock()
 		if err != nil {
 			log.Printf("Error creating manager booking: %v", err)
-			failedDates = append(failedDates, date.Format("02.01.2006"))
+			failedDates = append(failedDates, date.Format(b.dateLayout()))
 		} else {
 			createdBookings = append(createdBookings, booking)
+			b.publishBookingEvent(events.EventBookingCreated, booking)
+			if status == "awaiting_approval" {
+				b.notifySecondApprovers(booking)
+			}
 		}
 	}
 
@@ -491,7 +889,10 @@ func (b *Bot) createManagerBookings(update tgbotapi.Update, state *models.UserSt
 	if len(createdBookings) > 0 {
 		message.WriteString(fmt.Sprintf("✅ *Успешно создано:* %d заявок\n", len(createdBookings)))
 		for _, booking := range createdBookings {
-			message.WriteString(fmt.Sprintf("   • %s (№%d)\n", booking.Date.Format("02.01.2006"), booking.ID))
+			message.WriteString(fmt.Sprintf("   • %s (№%d)\n", b.bookingPeriod(*booking), booking.ID))
+		}
+		if status == "awaiting_approval" {
+			message.WriteString("👥 Аппарат требует второго подтверждения: заявки отправлены другим менеджерам\n")
 		}
 		message.WriteString("\n")
 	}
@@ -528,107 +929,940 @@ func (b *Bot) showManagerBookings(update tgbotapi.Update) {
 		return
 	}
 
-	// Получаем все заявки за период: один месяц назад и два месяца вперед
-	startDate := time.Now().AddDate(0, 0, -7) // 7 дней месяц назад
-	endDate := time.Now().AddDate(0, 2, 0)    // 2 месяца вперед
+	b.sendManagerBookingsPage(update.Message.Chat.ID, 0, "all", 0)
+}
 
-	bookings, err := b.db.GetBookingsByDateRange(context.Background(), startDate, endDate)
+// bookingsPerPage количество заявок на одной странице списка
+const bookingsPerPage = 10
+
+// managerBookingFilters фильтры списка заявок менеджера в порядке кнопок
+var managerBookingFilters = []struct {
+	status string
+	label  string
+}{
+	{"all", "Все"},
+	{"pending", "⏳"},
+	{"awaiting_approval", "👥"},
+	{"confirmed", "✅"},
+	{"completed", "🏁"},
+	{"cancelled", "❌"},
+}
+
+// managerBookingFilterTitles подписи активного фильтра в заголовке списка
+var managerBookingFilterTitles = map[string]string{
+	"all":               "все статусы",
+	"pending":           "ожидают подтверждения",
+	"awaiting_approval": "ждут второго подтверждения",
+	"confirmed":         "подтверждены",
+	"completed":         "завершены",
+	"cancelled":         "отменены",
+}
+
+// sendManagerBookingsPage показывает страницу заявок на квартал вперед с фильтром по статусу.
+// Если messageID не 0, редактирует существующее сообщение.
+func (b *Bot) sendManagerBookingsPage(chatID int64, messageID int, status string, page int) {
+	if _, ok := managerBookingFilterTitles[status]; !ok {
+		status = "all"
+	}
+
+	bookings, err := b.managerBookingsList(status)
 	if err != nil {
 		log.Printf("Error getting bookings: %v", err)
-		b.sendMessage(update.Message.Chat.ID, "Ошибка при получении заявок")
+		b.sendMessage(chatID, "Ошибка при получении заявок")
 		return
 	}
 
-	log.Printf("Получено %d заявок из БД", len(bookings))
-
-	if bookings == nil {
-		log.Printf("Bookings is nil")
-		b.sendMessage(update.Message.Chat.ID, "Ошибка при получении заявок bookings")
-		return
-	}
+	title := fmt.Sprintf("📊 Заявки на квартал вперед (%s)", managerBookingFilterTitles[status])
+	text, page, totalPages := b.renderPaginatedBookings(title, bookings, page, true)
 
-	var message strings.Builder
-	message.WriteString("📊 Все заявки на квартал вперед:\n\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
 
-	for _, booking := range bookings {
-		statusEmoji := "⏳"
-		switch booking.Status {
-		case "confirmed":
-			statusEmoji = "✅"
-		case "cancelled":
-			statusEmoji = "❌"
-		case "changed":
-			statusEmoji = "🔄"
-		case "rescheduled":
-			statusEmoji = "🔄"
-		case "completed":
-			statusEmoji = "🏁"
+	// Кнопки фильтров сохраняют текущую страницу, если она существует в новом списке
+	var filterRow []tgbotapi.InlineKeyboardButton
+	for _, filter := range managerBookingFilters {
+		label := filter.label
+		if filter.status == status {
+			label = "• " + label
 		}
+		filterRow = append(filterRow, tgbotapi.NewInlineKeyboardButtonData(label,
+			fmt.Sprintf("manager_bookings_page:%s:%d", filter.status, page)))
+	}
+	rows = append(rows, filterRow)
 
-		message.WriteString(fmt.Sprintf("%s Заявка #%d\n", statusEmoji, booking.ID))
-		message.WriteString(fmt.Sprintf("   👤 %s\n", booking.UserName))
-		message.WriteString(fmt.Sprintf("   🏢 %s\n", booking.ItemName))
-		message.WriteString(fmt.Sprintf("   📅 %s\n", booking.Date.Format("02.01.2006")))
-		message.WriteString(fmt.Sprintf("   📱 %s\n", booking.Phone))
-		message.WriteString(fmt.Sprintf("   🔗 /manager_booking_%d\n\n", booking.ID))
+	if navRow := paginationRow(fmt.Sprintf("manager_bookings_page:%s:", status), page, totalPages); navRow != nil {
+		rows = append(rows, navRow)
 	}
 
-	if len(bookings) == 0 {
-		message.WriteString("Заявок не найдено")
+	var pendingIDs []int64
+	for _, booking := range bookingsOnPage(bookings, page) {
+		if booking.Status == "pending" {
+			pendingIDs = append(pendingIDs, booking.ID)
+		}
+	}
+	if len(pendingIDs) > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить все на странице",
+				fmt.Sprintf("manager_bulk_confirm:%s:%d", status, page)),
+		))
 	}
 
-	b.sendMessage(update.Message.Chat.ID, message.String())
-}
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
-// showManagerBookingDetail показывает детали заявки менеджеру
-func (b *Bot) showManagerBookingDetail(update tgbotapi.Update, bookingID int64) {
-	// ПРОВЕРКА НА NIL - чтобы избежать паники
-	if update.Message == nil {
-		log.Printf("Error: update.Message is nil in showManagerBookingDetail")
+	if messageID != 0 {
+		b.bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup))
+		b.setBulkConfirmPage(chatID, messageID, pendingIDs)
 		return
 	}
 
-	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = &markup
+	sent, err := b.bot.Send(msg)
 	if err != nil {
-		b.sendMessage(update.Message.Chat.ID, "Заявка не найдена")
+		log.Printf("Error sending bookings page: %v", err)
 		return
 	}
+	b.setBulkConfirmPage(chatID, sent.MessageID, pendingIDs)
+}
 
-	statusText := map[string]string{
-		"pending":   "⏳ Ожидает подтверждения",
-		"confirmed": "✅ Подтверждена",
-		"cancelled": "❌ Отменена",
-		"changed":   "🔄 Изменена",
-		"completed": "🏁 Завершена",
+// bulkConfirmPage сообщение со страницей списка заявок
+type bulkConfirmPage struct {
+	chatID    int64
+	messageID int
+}
+
+// setBulkConfirmPage запоминает ожидающие заявки, показанные в сообщении.
+// Пустой список удаляет запись: кнопки «Подтвердить все» в сообщении нет.
+func (b *Bot) setBulkConfirmPage(chatID int64, messageID int, bookingIDs []int64) {
+	b.bulkConfirmPagesMu.Lock()
+	defer b.bulkConfirmPagesMu.Unlock()
+
+	key := bulkConfirmPage{chatID: chatID, messageID: messageID}
+	if len(bookingIDs) == 0 {
+		delete(b.bulkConfirmPages, key)
+		return
 	}
+	b.bulkConfirmPages[key] = bookingIDs
+}
 
-	message := fmt.Sprintf(`📋 Заявка #%d
+// getBulkConfirmPage возвращает ожидающие заявки, которые менеджер видел в сообщении
+func (b *Bot) getBulkConfirmPage(chatID int64, messageID int) ([]int64, bool) {
+	b.bulkConfirmPagesMu.Lock()
+	defer b.bulkConfirmPagesMu.Unlock()
 
-👤 Клиент: %s
-📱 Телефон: %s
-🏢 Позиция: %s
-📅 Дата: %s
-📊 Статус: %s
-💬 Комментарий: %s
-🕐 Создана: %s
-✏️ Обновлена: %s`,
-		booking.ID,
-		booking.UserName,
-		booking.Phone,
-		booking.ItemName,
-		booking.Date.Format("02.01.2006"),
-		statusText[booking.Status],
-		booking.Comment,
-		booking.CreatedAt.Format("02.01.2006 15:04"),
-		booking.UpdatedAt.Format("02.01.2006 15:04"),
-	)
+	bookingIDs, ok := b.bulkConfirmPages[bulkConfirmPage{chatID: chatID, messageID: messageID}]
+	return bookingIDs, ok
+}
 
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, message)
+// managerBookingsList возвращает заявки на неделю назад и два месяца вперед с фильтром по статусу
+func (b *Bot) managerBookingsList(status string) ([]models.Booking, error) {
+	startDate := b.today().AddDate(0, 0, -7)
+	endDate := b.today().AddDate(0, 2, 0)
 
-	// Создаем инлайн-клавиатуру для управления заявкой
+	bookings, err := b.db.GetBookingsByDateRange(context.Background(), startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == "all" {
+		return bookings, nil
+	}
+
+	var filtered []models.Booking
+	for _, booking := range bookings {
+		if booking.Status == status {
+			filtered = append(filtered, booking)
+		}
+	}
+	return filtered, nil
+}
+
+// handleManagerBulkConfirm подтверждает ожидающие заявки, которые были показаны на странице списка.
+// Заявки, появившиеся или сменившие статус после показа, не затрагиваются.
+// Формат callback: manager_bulk_confirm:<status>:<page>
+func (b *Bot) handleManagerBulkConfirm(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "manager_bulk_confirm:"), ":")
+	if len(parts) != 2 {
+		return
+	}
+
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		log.Printf("Error parsing page: %v", err)
+		return
+	}
+
+	// Список заявок хранится в памяти; после перезапуска бота его нет, и страницу нужно открыть заново
+	bookingIDs, ok := b.getBulkConfirmPage(callback.Message.Chat.ID, callback.Message.MessageID)
+	if !ok {
+		b.sendMessage(callback.Message.Chat.ID, "Список устарел, проверьте заявки и нажмите кнопку еще раз.")
+		b.sendManagerBookingsPage(callback.Message.Chat.ID, callback.Message.MessageID, parts[0], page)
+		return
+	}
+
+	confirmed, awaiting, failed := 0, 0, 0
+	for _, bookingID := range bookingIDs {
+		// Берем актуальную версию: заявку могли изменить после показа списка
+		booking, err := b.db.GetBooking(context.Background(), bookingID)
+		if err != nil {
+			log.Printf("Error getting booking %d: %v", bookingID, err)
+			failed++
+			continue
+		}
+		if booking.Status != "pending" {
+			continue
+		}
+
+		// Аппараты с двойным подтверждением получают только первое подтверждение
+		if b.requiresDualApproval(booking.ItemID) {
+			if err := b.startDualApproval(booking, callback.From.ID); err != nil {
+				log.Printf("Error approving booking %d: %v", booking.ID, err)
+				failed++
+				continue
+			}
+			awaiting++
+			continue
+		}
+
+		err = b.db.UpdateBookingStatusWithVersion(context.Background(), booking.ID, booking.Version, "confirmed", callback.From.ID)
+		if err != nil {
+			log.Printf("Error confirming booking %d: %v", booking.ID, err)
+			failed++
+			continue
+		}
+
+		b.afterBookingConfirmed(booking)
+		confirmed++
+	}
+
+	message := fmt.Sprintf("✅ Подтверждено заявок: %d", confirmed)
+	if awaiting > 0 {
+		message += fmt.Sprintf("\n👥 Ждут второго подтверждения: %d", awaiting)
+	}
+	if failed > 0 {
+		message += fmt.Sprintf("\n❌ Не удалось подтвердить: %d", failed)
+	}
+	b.sendMessage(callback.Message.Chat.ID, message)
+
+	b.sendManagerBookingsPage(callback.Message.Chat.ID, callback.Message.MessageID, parts[0], page)
+}
+
+// handleManagerBookingsPage обработка переключения страницы и фильтра списка заявок
+func (b *Bot) handleManagerBookingsPage(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "manager_bookings_page:"), ":")
+	if len(parts) != 2 {
+		return
+	}
+
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		log.Printf("Error parsing page: %v", err)
+		return
+	}
+
+	b.sendManagerBookingsPage(callback.Message.Chat.ID, callback.Message.MessageID, parts[0], page)
+}
+
+// handleUserHistory показывает менеджеру все заявки пользователя постранично.
+// Формат callback: user_history:<telegram_id>[:<page>]
+func (b *Bot) handleUserHistory(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "user_history:"), ":")
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		log.Printf("Error parsing user ID: %v", err)
+		return
+	}
+
+	page := 0
+	if len(parts) > 1 {
+		page, err = strconv.Atoi(parts[1])
+		if err != nil {
+			log.Printf("Error parsing page: %v", err)
+			return
+		}
+	}
+
+	bookings, err := b.db.GetBookingsByUserID(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting bookings of user %d: %v", userID, err)
+		b.sendMessage(callback.Message.Chat.ID, "Ошибка при получении заявок пользователя")
+		return
+	}
+
+	userName := strconv.FormatInt(userID, 10)
+	if user, err := b.db.GetUserByTelegramID(context.Background(), userID); err == nil {
+		userName = fmt.Sprintf("%s %s (%d)", user.FirstName, user.LastName, userID)
+	}
+
+	if len(bookings) == 0 {
+		b.sendMessage(callback.Message.Chat.ID, fmt.Sprintf("У пользователя %s пока нет заявок", userName))
+		return
+	}
+
+	text, page, totalPages := b.renderPaginatedBookings(fmt.Sprintf("📜 История заявок: %s", userName), bookings, page, true)
+	navRow := paginationRow(fmt.Sprintf("user_history:%d:", userID), page, totalPages)
+
+	// Переключение страниц редактирует уже открытую историю
+	if len(parts) > 1 {
+		edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, text)
+		if navRow != nil {
+			markup := tgbotapi.NewInlineKeyboardMarkup(navRow)
+			edit.ReplyMarkup = &markup
+		}
+		b.bot.Send(edit)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, text)
+	if navRow != nil {
+		markup := tgbotapi.NewInlineKeyboardMarkup(navRow)
+		msg.ReplyMarkup = &markup
+	}
+	b.bot.Send(msg)
+}
+
+// bookingsOnPage возвращает заявки страницы page или пустой список, если такой страницы нет
+func bookingsOnPage(bookings []models.Booking, page int) []models.Booking {
+	startIdx := page * bookingsPerPage
+	if page < 0 || startIdx >= len(bookings) {
+		return nil
+	}
+
+	endIdx := startIdx + bookingsPerPage
+	if endIdx > len(bookings) {
+		endIdx = len(bookings)
+	}
+	return bookings[startIdx:endIdx]
+}
+
+// renderPaginatedBookings формирует текст страницы списка заявок. Для менеджера в заявке
+// показываются клиент и ссылка на карточку, для клиента - только аппарат, дата и статус.
+// Возвращает текст, фактический номер страницы (после ограничения диапазона) и число страниц.
+func (b *Bot) renderPaginatedBookings(title string, bookings []models.Booking, page int, forManager bool) (string, int, int) {
+	totalPages := (len(bookings) + bookingsPerPage - 1) / bookingsPerPage
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	var message strings.Builder
+	message.WriteString(title + "\n")
+	message.WriteString(fmt.Sprintf("Страница %d из %d, всего заявок: %d\n\n", page+1, totalPages, len(bookings)))
+
+	if len(bookings) == 0 {
+		message.WriteString("Заявок не найдено")
+		return message.String(), page, totalPages
+	}
+
+	for _, booking := range bookingsOnPage(bookings, page) {
+		message.WriteString(fmt.Sprintf("%s Заявка #%d\n", bookingStatusEmoji(booking.Status), booking.ID))
+		if !forManager {
+			message.WriteString(fmt.Sprintf("   🏢 %s\n", booking.ItemName))
+			message.WriteString(fmt.Sprintf("   📅 %s\n", b.bookingPeriod(booking)))
+			message.WriteString(fmt.Sprintf("   📊 Статус: %s\n\n", booking.Status))
+			continue
+		}
+		message.WriteString(fmt.Sprintf("   👤 %s\n", booking.UserName))
+		message.WriteString(fmt.Sprintf("   🏢 %s\n", booking.ItemName))
+		message.WriteString(fmt.Sprintf("   📅 %s\n", b.bookingPeriod(booking)))
+		message.WriteString(fmt.Sprintf("   📱 %s\n", booking.Phone))
+		message.WriteString(fmt.Sprintf("   🔗 /manager_booking_%d\n\n", booking.ID))
+	}
+
+	return message.String(), page, totalPages
+}
+
+// paginationRow возвращает кнопки "назад/вперед" для callback вида <prefix><page> или nil, если страница одна
+func paginationRow(prefix string, page, totalPages int) []tgbotapi.InlineKeyboardButton {
+	var navButtons []tgbotapi.InlineKeyboardButton
+
+	if page > 0 {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("%s%d", prefix, page-1)))
+	}
+
+	if page < totalPages-1 {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("%s%d", prefix, page+1)))
+	}
+
+	return navButtons
+}
+
+// bookingStatusEmoji возвращает значок статуса заявки для списков
+func bookingStatusEmoji(status string) string {
+	switch status {
+	case "confirmed":
+		return "✅"
+	case "cancelled":
+		return "❌"
+	case "changed", "rescheduled":
+		return "🔄"
+	case "awaiting_approval":
+		return "👥"
+	case "completed":
+		return "🏁"
+	}
+	return "⏳"
+}
+
+// handleCancelBookingCommand отменяет заявку по ID без inline-кнопок: /cancel_booking <id>
+func (b *Bot) handleCancelBookingCommand(update tgbotapi.Update, arg string) {
+	bookingID, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, "Укажите номер заявки, например: /cancel_booking 123")
+		return
+	}
+
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("Заявка #%d не найдена", bookingID))
+		return
+	}
+
+	switch booking.Status {
+	case "completed":
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("Заявка #%d уже завершена, её нельзя отменить.", bookingID))
+		return
+	case "cancelled":
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("Заявка #%d уже отменена.", bookingID))
+		return
+	}
+
+	managerID := update.Message.From.ID
+	err = b.db.UpdateBookingStatusWithVersion(context.Background(), booking.ID, booking.Version, "cancelled", managerID)
+	if errors.Is(err, database.ErrConcurrentModification) {
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("Заявка #%d была изменена одновременно с отменой. Проверьте её: /manager_booking_%d", bookingID, bookingID))
+		return
+	}
+	if err != nil {
+		log.Printf("Error cancelling booking %d by command: %v", bookingID, err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при отмене заявки")
+		return
+	}
+
+	log.Printf("Manager %d cancelled booking %d by command", managerID, bookingID)
+	b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("❌ Заявка #%d отменена: %s, %s\n👤 %s, 📱 %s",
+		booking.ID, booking.ItemName, b.bookingPeriod(*booking), booking.UserName, b.formatPhoneForDisplay(booking.Phone)))
+
+	b.afterBookingRejected(booking)
+}
+
+// depositTitle подпись состояния залога для карточки заявки
+func depositTitle(paid bool) string {
+	if paid {
+		return "внесен"
+	}
+	return "не внесен"
+}
+
+// handleDepositPaid отмечает внесенный залог из карточки заявки: deposit_paid:<id>
+func (b *Bot) handleDepositPaid(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	bookingID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "deposit_paid:"), 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		b.sendMessage(chatID, "Заявка не найдена")
+		return
+	}
+	if booking.DepositPaid {
+		b.sendMessage(chatID, fmt.Sprintf("Залог по заявке #%d уже отмечен", booking.ID))
+		return
+	}
+
+	if err := b.db.SetBookingDepositPaid(context.Background(), booking.ID, callback.From.ID); err != nil {
+		log.Printf("Error marking deposit paid for booking %d: %v", booking.ID, err)
+		b.sendMessage(chatID, "Ошибка при сохранении залога")
+		return
+	}
+	log.Printf("Manager %d marked deposit paid for booking %d", callback.From.ID, booking.ID)
+
+	booking.DepositPaid = true
+	b.AppendBookingToSheets(booking)
+	b.sendManagerBookingDetail(chatID, booking)
+}
+
+// handleCancelBookingGroup отмена всех заявок группы из карточки заявки: cancel_group:<ask|yes|no>:<id>
+func (b *Bot) handleCancelBookingGroup(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "cancel_group:"), ":")
+	if len(parts) != 2 {
+		return
+	}
+	bookingID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
+
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil || booking.GroupID == "" {
+		b.sendMessage(callback.Message.Chat.ID, "Группа заявок не найдена")
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	if parts[0] != "ask" {
+		// Убираем кнопки подтверждения, чтобы группу нельзя было отменить повторно
+		b.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID,
+			tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+	}
+
+	switch parts[0] {
+	case "ask":
+		group, err := b.db.GetBookingsByGroup(context.Background(), booking.GroupID)
+		if err != nil {
+			log.Printf("Error getting booking group %s: %v", booking.GroupID, err)
+			b.sendMessage(chatID, "Ошибка при получении заявок группы")
+			return
+		}
+
+		var message strings.Builder
+		active := 0
+		for _, groupBooking := range group {
+			if groupBooking.Status == "cancelled" || groupBooking.Status == "completed" {
+				continue
+			}
+			active++
+			message.WriteString(fmt.Sprintf("   • %s (№%d)\n", b.bookingPeriod(groupBooking), groupBooking.ID))
+		}
+		if active == 0 {
+			b.sendMessage(chatID, "В группе нет активных заявок")
+			return
+		}
+
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Отменить %d заявок группы (%s, %s)?\n\n%s",
+			active, booking.ItemName, booking.UserName, message.String()))
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отменить все", fmt.Sprintf("cancel_group:yes:%d", booking.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Оставить", fmt.Sprintf("cancel_group:no:%d", booking.ID)),
+		))
+		msg.ReplyMarkup = keyboard
+		b.bot.Send(msg)
+
+	case "yes":
+		cancelled, err := b.db.CancelBookingGroup(context.Background(), booking.GroupID, callback.From.ID)
+		if err != nil {
+			log.Printf("Error cancelling booking group %s: %v", booking.GroupID, err)
+		}
+
+		message := fmt.Sprintf("❌ Отменено заявок группы: %d", len(cancelled))
+		if err != nil {
+			message += "\nОшибка при отмене остальных заявок, проверьте группу еще раз"
+		}
+		b.sendMessage(chatID, message)
+		log.Printf("Manager %d cancelled %d bookings of group %s", callback.From.ID, len(cancelled), booking.GroupID)
+
+		for i := range cancelled {
+			cancelledBooking := &cancelled[i]
+			b.notifyBookingClient(cancelledBooking, b.t(cancelledBooking.UserID, "booking_rejected"))
+			b.publishBookingEvent(events.EventBookingCancelled, cancelledBooking)
+			b.notifyWaitlist(cancelledBooking.ItemID, cancelledBooking.Date)
+			b.UpdateBookingStatusInSheets(cancelledBooking.ID)
+		}
+		if len(cancelled) > 0 {
+			b.SyncScheduleToSheets()
+		}
+
+	default:
+		b.sendMessage(chatID, "Заявки группы оставлены без изменений")
+	}
+}
+
+// findBookingsByPhone ищет заявки клиента по номеру телефона
+func (b *Bot) findBookingsByPhone(update tgbotapi.Update, phone string) {
+	if phone == "" {
+		b.sendMessage(update.Message.Chat.ID, "Использование: /find_booking <телефон>\nНапример: /find_booking +79001234567")
+		return
+	}
+
+	normalizedPhone := b.normalizePhone(phone)
+	if normalizedPhone == "" {
+		b.sendMessage(update.Message.Chat.ID, "Неверный формат номера телефона. Пожалуйста, введите номер в формате +7XXXXXXXXXX, 8XXXXXXXXXX или с кодом страны, например +375XXXXXXXXX")
+		return
+	}
+
+	bookings, err := b.db.GetBookingsByPhone(context.Background(), normalizedPhone)
+	if err != nil {
+		log.Printf("Error finding bookings by phone: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при поиске заявок")
+		return
+	}
+
+	if len(bookings) == 0 {
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("Заявок с телефоном %s не найдено", b.formatPhoneForDisplay(normalizedPhone)))
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("🔍 Заявки клиента %s (%d):\n\n", b.formatPhoneForDisplay(normalizedPhone), len(bookings)))
+
+	for _, booking := range bookings {
+		message.WriteString(fmt.Sprintf("Заявка #%d\n", booking.ID))
+		message.WriteString(fmt.Sprintf("   👤 %s\n", booking.UserName))
+		message.WriteString(fmt.Sprintf("   🏢 %s\n", booking.ItemName))
+		message.WriteString(fmt.Sprintf("   📅 %s\n", b.bookingPeriod(booking)))
+		message.WriteString(fmt.Sprintf("   📊 Статус: %s\n", booking.Status))
+		message.WriteString(fmt.Sprintf("   🔗 /manager_booking_%d\n\n", booking.ID))
+	}
+
+	b.sendMessage(update.Message.Chat.ID, message.String())
+}
+
+// defaultArchiveDays срок по умолчанию для /archive
+const defaultArchiveDays = 90
+
+// handleArchiveCommand переносит в архив завершенные и отмененные заявки старше указанного числа дней
+func (b *Bot) handleArchiveCommand(update tgbotapi.Update, arg string) {
+	days := defaultArchiveDays
+	if arg != "" {
+		parsed, err := strconv.Atoi(arg)
+		if err != nil || parsed < 1 {
+			b.sendMessage(update.Message.Chat.ID, "Укажите количество дней, например: /archive 90")
+			return
+		}
+		days = parsed
+	}
+
+	cutoff := b.today().AddDate(0, 0, -days)
+
+	archived, err := b.db.ArchiveBookings(context.Background(), cutoff)
+	if err != nil {
+		log.Printf("Error archiving bookings: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при архивации заявок")
+		return
+	}
+
+	log.Printf("Manager %d archived %d bookings older than %s", update.Message.From.ID, archived, cutoff.Format("2006-01-02"))
+	b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("🗄 В архив перенесено заявок: %d (завершенные и отмененные до %s).\n\nДля выгрузки вместе с архивом используйте /export_bookings archive",
+		archived, cutoff.Format(b.dateLayout())))
+}
+
+// handleBlacklistCommand блокирует или разблокирует пользователя по Telegram ID
+func (b *Bot) handleBlacklistCommand(update tgbotapi.Update, arg string, blacklisted bool) {
+	command := "/blacklist"
+	if !blacklisted {
+		command = "/unblacklist"
+	}
+
+	telegramID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("Укажите Telegram ID пользователя, например: %s 123456789", command))
+		return
+	}
+
+	if blacklisted && b.isManager(telegramID) {
+		b.sendMessage(update.Message.Chat.ID, "Нельзя заблокировать менеджера")
+		return
+	}
+
+	if !blacklisted && b.isConfigBlacklisted(telegramID) {
+		b.sendMessage(update.Message.Chat.ID,
+			fmt.Sprintf("Пользователь %d указан в blacklist в config.yaml, /unblacklist его не разблокирует. Уберите его из конфигурации и перезапустите бота", telegramID))
+		return
+	}
+
+	if err := b.db.SetUserBlacklist(context.Background(), telegramID, blacklisted); err != nil {
+		log.Printf("Error updating blacklist for user %d: %v", telegramID, err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при изменении черного списка")
+		return
+	}
+
+	userName := strconv.FormatInt(telegramID, 10)
+	if user, err := b.db.GetUserByTelegramID(context.Background(), telegramID); err == nil && user.FirstName != "" {
+		userName = fmt.Sprintf("%s %s (%d)", user.FirstName, user.LastName, telegramID)
+	}
+
+	if blacklisted {
+		log.Printf("Manager %d blacklisted user %d", update.Message.From.ID, telegramID)
+		b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("🚫 Пользователь %s добавлен в черный список", userName))
+		return
+	}
+
+	log.Printf("Manager %d removed user %d from blacklist", update.Message.From.ID, telegramID)
+	b.sendMessage(update.Message.Chat.ID, fmt.Sprintf("✅ Пользователь %s удален из черного списка", userName))
+}
+
+// handleResetUserCommand сбрасывает зависшее состояние диалога пользователя и присылает ему главное меню
+func (b *Bot) handleResetUserCommand(update tgbotapi.Update, arg string) {
+	chatID := update.Message.Chat.ID
+
+	telegramID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		b.sendMessage(chatID, "Укажите Telegram ID пользователя, например: /reset_user 123456789")
+		return
+	}
+
+	user, err := b.db.GetUserByTelegramID(context.Background(), telegramID)
+	if err != nil || user == nil {
+		b.sendMessage(chatID, fmt.Sprintf("Пользователь %d не найден", telegramID))
+		return
+	}
+
+	// Показываем, на каком шаге застрял пользователь, чтобы поддержке было проще разобраться в причине
+	stateInfo := "сохраненного состояния не было"
+	if state := b.getUserState(telegramID); state != nil {
+		stateInfo = fmt.Sprintf("шаг %s, обновлен %s", state.CurrentStep,
+			state.UpdatedAt.In(b.location).Format("02.01.2006 15:04"))
+	}
+
+	b.clearUserState(telegramID)
+	b.sendMainMenu(telegramID, telegramID)
+	log.Printf("Manager %d reset state of user %d (%s)", update.Message.From.ID, telegramID, stateInfo)
+
+	b.sendMessage(chatID, fmt.Sprintf("🧹 Состояние пользователя %s %s (%d) сброшено: %s. Пользователю отправлено главное меню.",
+		user.FirstName, user.LastName, telegramID, stateInfo))
+}
+
+// showManagerBookingDetail показывает детали заявки менеджеру
+func (b *Bot) showManagerBookingDetail(update tgbotapi.Update, bookingID int64) {
+	// ПРОВЕРКА НА NIL - чтобы избежать паники
+	if update.Message == nil {
+		log.Printf("Error: update.Message is nil in showManagerBookingDetail")
+		return
+	}
+
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, "Заявка не найдена")
+		return
+	}
+
+	b.sendManagerBookingDetail(update.Message.Chat.ID, booking)
+}
+
+// startChangeItem начало изменения аппарата в заявке
+func (b *Bot) startChangeItem(booking *models.Booking, managerChatID int64) {
+	msg := tgbotapi.NewMessage(managerChatID,
+		"Выберите новый аппарат для заявки #"+strconv.FormatInt(booking.ID, 10)+":")
+
+	var keyboardRows [][]tgbotapi.InlineKeyboardButton
+	for _, item := range b.getItems() {
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(item.Name,
+				fmt.Sprintf("change_to_%d_%d", booking.ID, item.ID)),
+		)
+		keyboardRows = append(keyboardRows, row)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
+	msg.ReplyMarkup = &keyboard
+
+	b.bot.Send(msg)
+}
+
+// handleChangeItem обработка выбора нового аппарата С ПРОВЕРКОЙ ДОСТУПНОСТИ
+func (b *Bot) handleChangeItem(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if callback == nil {
+		return
+	}
+
+	data := callback.Data
+	var bookingID, itemID int64
+
+	if _, err := fmt.Sscanf(data, "change_to_%d_%d", &bookingID, &itemID); err != nil {
+		return
+	}
+
+	// Находим выбранный аппарат
+	var selectedItem models.Item
+	for _, item := range b.getItems() {
+		if item.ID == itemID {
+			selectedItem = item
+			break
+		}
+	}
+
+	if selectedItem.ID == 0 {
+		b.sendMessage(callback.Message.Chat.ID, "Аппарат не найден")
+		return
+	}
+
+	// ПРОВЕРЯЕМ ДОСТУПНОСТЬ нового аппарата на дату заявки
+	booking, available, err := b.db.GetBookingWithAvailability(context.Background(), bookingID, selectedItem.ID)
+	if err != nil {
+		log.Printf("Error checking availability: %v", err)
+		b.sendMessage(callback.Message.Chat.ID, "Ошибка при проверке доступности")
+		return
+	}
+
+	if !available {
+		b.sendMessage(callback.Message.Chat.ID,
+			fmt.Sprintf("❌ Аппарат '%s' недоступен на дату %s. Выберите другой аппарат.",
+				selectedItem.Name, b.bookingPeriod(*booking)))
+		return
+	}
+
+	// Обновляем заявку
+	err = b.db.UpdateBookingItem(context.Background(), bookingID, selectedItem.ID, selectedItem.Name, callback.From.ID)
+	if err != nil {
+		log.Printf("Error updating booking item: %v", err)
+		b.sendMessage(callback.Message.Chat.ID, "Ошибка при обновлении заявки")
+		return
+	}
+
+	// Обновляем статус
+	err = b.db.UpdateBookingStatus(context.Background(), bookingID, "changed", callback.From.ID)
+	if err != nil {
+		log.Printf("Error updating booking status: %v", err)
+	}
+
+	// Уведомляем пользователя
+	b.notifyBookingClient(booking,
+		fmt.Sprintf("🔄 В вашей заявке #%d изменен аппарат на: %s", bookingID, selectedItem.Name))
+
+	b.sendMessage(callback.Message.Chat.ID, "✅ Аппарат успешно изменен")
+
+	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ В GOOGLE SHEETS
+	b.SyncBookingsToSheets()
+	b.SyncScheduleToSheets()
+
+	// ВМЕСТО ВЫЗОВА showManagerBookingDetail, который требует Message, используем sendManagerBookingDetail
+	updatedBooking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		log.Printf("Error getting updated booking: %v", err)
+		return
+	}
+
+	// Отправляем обновленные детали заявки
+	b.sendManagerBookingDetail(callback.Message.Chat.ID, updatedBooking)
+}
+
+// sendManagerBookingDetail отправляет детали заявки в указанный чат (без использования update)
+func (b *Bot) sendManagerBookingDetail(chatID int64, booking *models.Booking) {
+	statusText := map[string]string{
+		"pending":           "⏳ Ожидает подтверждения",
+		"awaiting_approval": "👥 Ждет второго подтверждения",
+		"confirmed":         "✅ Подтверждена",
+		"cancelled":         "❌ Отменена",
+		"changed":           "🔄 Изменена",
+		"completed":         "🏁 Завершена",
+	}
+
+	message := fmt.Sprintf(`📋 Заявка #%d
+
+👤 Клиент: %s
+📱 Телефон: %s
+🏢 Позиция: %s
+📅 Дата: %s
+📊 Статус: %s
+💬 Комментарий: %s
+🕐 Создана: %s
+✏️ Обновлена: %s`,
+		booking.ID,
+		booking.UserName,
+		booking.Phone,
+		booking.ItemName,
+		b.bookingPeriod(*booking),
+		statusText[booking.Status],
+		booking.Comment,
+		booking.CreatedAt.Format("02.01.2006 15:04"),
+		booking.UpdatedAt.Format("02.01.2006 15:04"),
+	)
+
+	if booking.SecondaryPhone != "" {
+		message += fmt.Sprintf("\n📱 Запасной телефон: %s", b.formatPhoneForDisplay(booking.SecondaryPhone))
+	}
+	message += fmt.Sprintf("\n📥 Источник: %s", bookingSourceTitle(booking.Source))
+	if booking.CreatedByManagerID != 0 {
+		message += fmt.Sprintf("\n👨‍💼 Оформил менеджер: %s", b.managerDisplayName(booking.CreatedByManagerID))
+	}
+
+	var activeInGroup int
+	if booking.GroupID != "" {
+		var groupSize int
+		var err error
+		if groupSize, activeInGroup, err = b.bookingGroupCounts(booking.GroupID); err != nil {
+			log.Printf("Error getting booking group %s: %v", booking.GroupID, err)
+		} else {
+			message += fmt.Sprintf("\n🔗 Группа: %d заявок, активных %d", groupSize, activeInGroup)
+		}
+	}
+
+	if booking.AttachmentFileID != "" {
+		message += fmt.Sprintf("\n📎 Вложение: %s", attachmentTitle(booking.AttachmentType))
+	}
+
+	if booking.Status == "awaiting_approval" && booking.FirstApprovedBy != 0 {
+		message += fmt.Sprintf("\n☑️ Первое подтверждение: %s", b.managerDisplayName(booking.FirstApprovedBy))
+	}
+
+	if !booking.ReminderAckAt.IsZero() {
+		message += fmt.Sprintf("\n👍 Клиент подтвердил явку: %s", booking.ReminderAckAt.In(b.location).Format("02.01.2006 15:04"))
+	}
+
+	if booking.Price > 0 {
+		message += fmt.Sprintf("\n💰 Стоимость: %d ₽, залог: %s", booking.Price, depositTitle(booking.DepositPaid))
+	}
+
+	if booking.ManagerNote != "" {
+		message += fmt.Sprintf("\n\n🔒 Заметка менеджера: %s", booking.ManagerNote)
+	}
+
+	message += b.formatBookingHistory(booking.ID)
+
+	msg := tgbotapi.NewMessage(chatID, message)
+	keyboard := b.managerBookingKeyboard(booking, activeInGroup)
+	msg.ReplyMarkup = &keyboard
+
+	b.bot.Send(msg)
+}
+
+// bookingGroupCounts возвращает размер группы заявок и число активных заявок в ней
+func (b *Bot) bookingGroupCounts(groupID string) (int, int, error) {
+	group, err := b.db.GetBookingsByGroup(context.Background(), groupID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	active := 0
+	for _, groupBooking := range group {
+		if groupBooking.Status != "cancelled" && groupBooking.Status != "completed" {
+			active++
+		}
+	}
+	return len(group), active, nil
+}
+
+// managerBookingKeyboard инлайн-клавиатура управления заявкой с кнопками для ее текущего статуса
+func (b *Bot) managerBookingKeyboard(booking *models.Booking, activeInGroup int) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
-	if booking.Status == "pending" || booking.Status == "changed" || booking.Status == "rescheduled" {
+	if booking.Status == "pending" || booking.Status == "awaiting_approval" || booking.Status == "changed" || booking.Status == "rescheduled" {
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", fmt.Sprintf("confirm_%d", booking.ID)),
 			tgbotapi.NewInlineKeyboardButtonData("❌ Отклонить", fmt.Sprintf("reject_%d", booking.ID)),
@@ -647,242 +1881,418 @@ func (b *Bot) showManagerBookingDetail(update tgbotapi.Update, bookingID int64)
 		tgbotapi.NewInlineKeyboardButtonData("🔄 Предложить выбрать другую дату", fmt.Sprintf("reschedule_%d", booking.ID)),
 	))
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📅 Перенести дату", fmt.Sprintf("reschedule_to_%d", booking.ID)),
 		tgbotapi.NewInlineKeyboardButtonData("📞 Позвонить", fmt.Sprintf("call_booking:%d", booking.ID)),
 	))
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔒 Заметка", fmt.Sprintf("add_note:%d", booking.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("👥 Другой клиент", fmt.Sprintf("reassign:%d", booking.ID)),
+	))
+	if booking.AttachmentFileID != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📎 Вложение", fmt.Sprintf("attachment:%d", booking.ID)),
+		))
+	}
+	if booking.Price > 0 && !booking.DepositPaid {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💰 Залог внесен", fmt.Sprintf("deposit_paid:%d", booking.ID)),
+		))
+	}
+	if activeInGroup > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("❌ Отменить всю группу (%d)", activeInGroup),
+				fmt.Sprintf("cancel_group:ask:%d", booking.ID)),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// managerDisplayName возвращает имя менеджера из Telegram или его ID, если пользователь не найден
+func (b *Bot) managerDisplayName(managerID int64) string {
+	if user, err := b.db.GetUserByTelegramID(context.Background(), managerID); err == nil && user.FirstName != "" {
+		return strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	return strconv.FormatInt(managerID, 10)
+}
+
+// formatBookingHistory возвращает историю изменений заявки для карточки менеджера
+func (b *Bot) formatBookingHistory(bookingID int64) string {
+	history, err := b.db.GetBookingHistory(context.Background(), bookingID)
+	if err != nil {
+		log.Printf("Error getting booking history: %v", err)
+		return ""
+	}
+	if len(history) == 0 {
+		return ""
+	}
+
+	managerNames := make(map[int64]string)
+	var text strings.Builder
+	text.WriteString("\n\n📜 История:")
+	for _, event := range history {
+		text.WriteString(fmt.Sprintf("\n%s ", event.CreatedAt.Format("02.01.2006 15:04")))
+		if event.FromStatus != event.ToStatus {
+			text.WriteString(fmt.Sprintf("%s %s → %s %s",
+				bookingStatusEmoji(event.FromStatus), event.FromStatus,
+				bookingStatusEmoji(event.ToStatus), event.ToStatus))
+		}
+		if event.Details != "" {
+			if event.FromStatus != event.ToStatus {
+				text.WriteString(", ")
+			}
+			text.WriteString(event.Details)
+		}
+
+		if event.ManagerID == 0 {
+			text.WriteString(" (клиент/система)")
+			continue
+		}
+		name, ok := managerNames[event.ManagerID]
+		if !ok {
+			name = b.managerDisplayName(event.ManagerID)
+			managerNames[event.ManagerID] = name
+		}
+		text.WriteString(fmt.Sprintf(" (%s)", name))
+	}
+
+	return text.String()
+}
+
+// startManagerNote запрашивает у менеджера текст внутренней заметки к заявке
+func (b *Bot) startManagerNote(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
 
-	if len(rows) > 0 {
-		keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
-		msg.ReplyMarkup = &keyboard
+	bookingID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "add_note:"), 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
 	}
 
-	b.bot.Send(msg)
+	b.setUserState(callback.From.ID, "manager_waiting_note", map[string]interface{}{
+		"booking_id": bookingID,
+	})
+
+	b.sendMessage(callback.Message.Chat.ID,
+		fmt.Sprintf("🔒 Введите заметку к заявке #%d. Клиент её не увидит:", bookingID))
 }
 
-// startChangeItem начало изменения аппарата в заявке
-func (b *Bot) startChangeItem(booking *models.Booking, managerChatID int64) {
-	msg := tgbotapi.NewMessage(managerChatID,
-		"Выберите новый аппарат для заявки #"+strconv.FormatInt(booking.ID, 10)+":")
+// handleManagerNote сохраняет заметку менеджера и показывает обновленную заявку
+func (b *Bot) handleManagerNote(update tgbotapi.Update, text string, state *models.UserState) {
+	note, err := noteField.clean(text)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
 
-	var keyboardRows [][]tgbotapi.InlineKeyboardButton
-	for _, item := range b.items {
-		row := tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(item.Name,
-				fmt.Sprintf("change_to_%d_%d", booking.ID, item.ID)),
-		)
-		keyboardRows = append(keyboardRows, row)
+	bookingID, ok := state.GetInt64("booking_id")
+	b.clearUserState(update.Message.From.ID)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		return
 	}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
-	msg.ReplyMarkup = &keyboard
+	if err := b.db.SetBookingManagerNote(context.Background(), bookingID, note); err != nil {
+		log.Printf("Error saving manager note: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при сохранении заметки")
+		return
+	}
 
-	b.bot.Send(msg)
+	b.showManagerBookingDetail(update, bookingID)
 }
 
-// handleChangeItem обработка выбора нового аппарата С ПРОВЕРКОЙ ДОСТУПНОСТИ
-func (b *Bot) handleChangeItem(update tgbotapi.Update) {
+// startReassignBooking начинает передачу заявки другому клиенту
+func (b *Bot) startReassignBooking(update tgbotapi.Update) {
 	callback := update.CallbackQuery
-	if callback == nil {
+	if !b.isManager(callback.From.ID) {
 		return
 	}
 
-	data := callback.Data
-	var bookingID, itemID int64
+	bookingID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "reassign:"), 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
 
-	if _, err := fmt.Sscanf(data, "change_to_%d_%d", &bookingID, &itemID); err != nil {
+	b.setUserState(callback.From.ID, "manager_reassign_name", map[string]interface{}{
+		"booking_id": bookingID,
+	})
+
+	b.sendMessage(callback.Message.Chat.ID,
+		fmt.Sprintf("👥 Введите имя нового клиента для заявки #%d:", bookingID))
+}
+
+// handleReassignName обработка ввода имени нового клиента
+func (b *Bot) handleReassignName(update tgbotapi.Update, text string, state *models.UserState) {
+	name, err := userNameField.clean(text)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
 		return
 	}
 
-	// Находим выбранный аппарат
-	var selectedItem models.Item
-	for _, item := range b.items {
-		if item.ID == itemID {
-			selectedItem = item
-			break
-		}
+	state.TempData["client_name"] = name
+	b.setUserState(update.Message.From.ID, "manager_reassign_phone", state.TempData)
+
+	b.sendMessage(update.Message.Chat.ID, "📱 Введите телефон нового клиента:")
+}
+
+// handleReassignPhone передает заявку новому клиенту.
+// Telegram нового клиента неизвестен, поэтому заявка отвязывается от аккаунта: прежний
+// пользователь больше не видит её в «Мои заявки» и не получает уведомлений по ней.
+func (b *Bot) handleReassignPhone(update tgbotapi.Update, text string, state *models.UserState) {
+	phone := b.normalizePhone(text)
+	if phone == "" {
+		b.sendMessage(update.Message.Chat.ID, "Неверный формат номера телефона. Пожалуйста, введите номер в формате +7XXXXXXXXXX, 8XXXXXXXXXX или с кодом страны, например +375XXXXXXXXX")
+		return
 	}
 
-	if selectedItem.ID == 0 {
-		b.sendMessage(callback.Message.Chat.ID, "Аппарат не найден")
+	managerID := update.Message.From.ID
+	bookingID, ok := state.GetInt64("booking_id")
+	clientName, nameOK := state.GetString("client_name")
+	if !ok || !nameOK {
+		b.clearUserState(managerID)
+		b.sendMessage(update.Message.Chat.ID, b.t(managerID, "err_session_expired"))
 		return
 	}
 
-	// ПРОВЕРЯЕМ ДОСТУПНОСТЬ нового аппарата на дату заявки
-	booking, available, err := b.db.GetBookingWithAvailability(context.Background(), bookingID, selectedItem.ID)
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
 	if err != nil {
-		log.Printf("Error checking availability: %v", err)
-		b.sendMessage(callback.Message.Chat.ID, "Ошибка при проверке доступности")
+		b.clearUserState(managerID)
+		b.sendMessage(update.Message.Chat.ID, "Заявка не найдена")
 		return
 	}
 
-	if !available {
-		b.sendMessage(callback.Message.Chat.ID,
-			fmt.Sprintf("❌ Аппарат '%s' недоступен на дату %s. Выберите другой аппарат.",
-				selectedItem.Name, booking.Date.Format("02.01.2006")))
+	err = b.db.ReassignBookingWithVersion(context.Background(), booking.ID, booking.Version, 0, clientName, "", phone, managerID)
+	if errors.Is(err, database.ErrConcurrentModification) {
+		b.clearUserState(managerID)
+		b.sendMessage(update.Message.Chat.ID, "Заявка была изменена другим пользователем. Откройте её заново.")
 		return
 	}
-
-	// Обновляем заявку
-	err = b.db.UpdateBookingItem(context.Background(), bookingID, selectedItem.ID, selectedItem.Name)
 	if err != nil {
-		log.Printf("Error updating booking item: %v", err)
-		b.sendMessage(callback.Message.Chat.ID, "Ошибка при обновлении заявки")
+		log.Printf("Error reassigning booking: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при передаче заявки")
 		return
 	}
 
-	// Обновляем статус
-	err = b.db.UpdateBookingStatus(context.Background(), bookingID, "changed")
-	if err != nil {
-		log.Printf("Error updating booking status: %v", err)
+	b.clearUserState(managerID)
+
+	log.Printf("Manager %d reassigned booking %d from %s (%s, user %d) to %s (%s)",
+		managerID, booking.ID, booking.UserName, booking.Phone, booking.UserID, clientName, phone)
+
+	b.notifyBookingClient(booking,
+		fmt.Sprintf("ℹ️ Ваша заявка #%d на %s (%s) передана другому клиенту. По вопросам обращайтесь к менеджеру.",
+			booking.ID, booking.ItemName, b.bookingPeriod(*booking)))
+
+	if updatedBooking, err := b.db.GetBooking(context.Background(), booking.ID); err == nil {
+		b.sendManagerBookingDetail(update.Message.Chat.ID, updatedBooking)
 	}
 
-	// Уведомляем пользователя
-	userMsg := tgbotapi.NewMessage(booking.UserID,
-		fmt.Sprintf("🔄 В вашей заявке #%d изменен аппарат на: %s", bookingID, selectedItem.Name))
-	b.bot.Send(userMsg)
+	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ
+	b.AppendBookingToSheets(booking)
+}
 
-	b.sendMessage(callback.Message.Chat.ID, "✅ Аппарат успешно изменен")
+// startRescheduleTo запрашивает у менеджера новую дату для заявки
+func (b *Bot) startRescheduleTo(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
 
-	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ В GOOGLE SHEETS
-	b.SyncBookingsToSheets()
-	b.SyncScheduleToSheets()
+	bookingID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "reschedule_to_"), 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
 
-	// ВМЕСТО ВЫЗОВА showManagerBookingDetail, который требует Message, используем sendManagerBookingDetail
-	updatedBooking, err := b.db.GetBooking(context.Background(), bookingID)
+	// Версию запоминаем сейчас, чтобы перенос не затер изменения, сделанные пока менеджер выбирает дату
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
 	if err != nil {
-		log.Printf("Error getting updated booking: %v", err)
+		b.sendMessage(callback.Message.Chat.ID, "Заявка не найдена")
 		return
 	}
 
-	// Отправляем обновленные детали заявки
-	b.sendManagerBookingDetail(callback.Message.Chat.ID, updatedBooking)
+	b.setUserState(callback.From.ID, "manager_waiting_reschedule_date", map[string]interface{}{
+		"booking_id":      bookingID,
+		"booking_version": booking.Version,
+	})
+
+	b.sendMessage(callback.Message.Chat.ID,
+		fmt.Sprintf("📅 Введите новую дату для заявки #%d в формате %s:", bookingID, b.dateFormatHint(callback.From.ID)))
 }
 
-// sendManagerBookingDetail отправляет детали заявки в указанный чат (без использования update)
-func (b *Bot) sendManagerBookingDetail(chatID int64, booking *models.Booking) {
-	statusText := map[string]string{
-		"pending":   "⏳ Ожидает подтверждения",
-		"confirmed": "✅ Подтверждена",
-		"cancelled": "❌ Отменена",
-		"changed":   "🔄 Изменена",
-		"completed": "🏁 Завершена",
+// handleManagerRescheduleDate переносит заявку на введенную менеджером дату
+func (b *Bot) handleManagerRescheduleDate(update tgbotapi.Update, dateStr string, state *models.UserState) {
+	date, err := b.parseDate(dateStr)
+	if err != nil {
+		b.sendMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
+		return
 	}
 
-	message := fmt.Sprintf(`📋 Заявка #%d
+	bookingID, ok := state.GetInt64("booking_id")
+	version, versionOK := state.GetInt64("booking_version")
+	if !ok || !versionOK {
+		b.clearUserState(update.Message.From.ID)
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		return
+	}
 
-👤 Клиент: %s
-📱 Телефон: %s
-🏢 Позиция: %s
-📅 Дата: %s
-📊 Статус: %s
-🕐 Создана: %s
-✏️ Обновлена: %s`,
-		booking.ID,
-		booking.UserName,
-		booking.Phone,
-		booking.ItemName,
-		booking.Date.Format("02.01.2006"),
-		statusText[booking.Status],
-		booking.CreatedAt.Format("02.01.2006 15:04"),
-		booking.UpdatedAt.Format("02.01.2006 15:04"),
-	)
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		b.clearUserState(update.Message.From.ID)
+		b.sendMessage(update.Message.Chat.ID, "Заявка не найдена")
+		return
+	}
+	if booking.Version != version {
+		b.clearUserState(update.Message.From.ID)
+		b.sendMessage(update.Message.Chat.ID, "Заявка была изменена другим пользователем. Откройте её заново.")
+		return
+	}
 
-	msg := tgbotapi.NewMessage(chatID, message)
+	item, ok := b.findItemByID(booking.ItemID)
+	if !ok {
+		item = models.Item{ID: booking.ItemID, Name: booking.ItemName}
+	}
+	// Многодневная заявка переносится целиком с сохранением длительности
+	var endDate time.Time
+	if !booking.EndDate.IsZero() {
+		endDate = date.AddDate(0, 0, booking.Days()-1)
+	}
+	lastDate := date
+	if !endDate.IsZero() {
+		lastDate = endDate
+	}
 
-	// Создаем инлайн-клавиатуру для управления заявкой
-	var rows [][]tgbotapi.InlineKeyboardButton
+	if err := b.validateBookingDate(item, lastDate); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
+	if err := b.validateBookingDate(item, date); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
 
-	if booking.Status == "pending" || booking.Status == "changed" {
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", fmt.Sprintf("confirm_%d", booking.ID)),
-			tgbotapi.NewInlineKeyboardButtonData("❌ Отклонить", fmt.Sprintf("reject_%d", booking.ID)),
-		))
+	// Проверка и перенос под блокировкой слотов, как и при создании заявки
+	unlock, ok := b.lockSlots(booking.ItemID, date, lastDate)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_slot_busy"))
+		return
 	}
+	defer unlock()
 
-	if booking.Status == "confirmed" {
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🔄 Вернуть в работу", fmt.Sprintf("reopen_%d", booking.ID)),
-			tgbotapi.NewInlineKeyboardButtonData("🏁 Завершить", fmt.Sprintf("complete_%d", booking.ID)),
-		))
+	available, err := b.db.CheckAvailabilityForPeriod(context.Background(), booking.ItemID, date, lastDate, booking.ID)
+	if err != nil {
+		log.Printf("Error checking availability: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при проверке доступности")
+		return
+	}
+	if !available {
+		b.sendMessage(update.Message.Chat.ID,
+			fmt.Sprintf("❌ %s на %s недоступен. Введите другую дату:", booking.ItemName, formatBookingPeriod(date, endDate, b.dateLayout())))
+		return
 	}
 
-	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить аппарат", fmt.Sprintf("change_item_%d", booking.ID)),
-		tgbotapi.NewInlineKeyboardButtonData("🔄 Предложить выбрать другую дату", fmt.Sprintf("reschedule_%d", booking.ID)),
-	))
-	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("📞 Позвонить", fmt.Sprintf("call_booking:%d", booking.ID)),
-	))
+	oldPeriod := formatBookingPeriod(booking.Date, booking.EndDate, b.dateLayout())
+	err = b.db.UpdateBookingDateWithVersion(context.Background(), booking.ID, version, date, endDate, update.Message.From.ID)
+	if errors.Is(err, database.ErrConcurrentModification) {
+		b.clearUserState(update.Message.From.ID)
+		b.sendMessage(update.Message.Chat.ID, "Заявка была изменена другим пользователем. Откройте её заново.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error rescheduling booking: %v", err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при переносе заявки")
+		return
+	}
 
-	if len(rows) > 0 {
-		keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
-		msg.ReplyMarkup = &keyboard
+	b.clearUserState(update.Message.From.ID)
+
+	b.notifyBookingClient(booking,
+		fmt.Sprintf("📅 Ваша заявка на %s перенесена с %s на %s.",
+			booking.ItemName, oldPeriod, formatBookingPeriod(date, endDate, b.dateLayout())))
+
+	if updatedBooking, err := b.db.GetBooking(context.Background(), booking.ID); err == nil {
+		b.publishBookingEvent(events.EventBookingRescheduled, updatedBooking)
+		b.sendManagerBookingDetail(update.Message.Chat.ID, updatedBooking)
 	}
 
-	b.bot.Send(msg)
+	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ
+	go func() {
+		time.Sleep(1 * time.Second) // Небольшая задержка для завершения операции в БД
+		b.SyncBookingsToSheets()
+	}()
 }
 
 // reopenBooking возврат заявки в работу
 func (b *Bot) reopenBooking(booking *models.Booking, managerChatID int64) {
-	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "pending")
+	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "pending", managerChatID)
 	if err != nil {
 		log.Printf("Error reopening booking: %v", err)
 		return
 	}
 
 	// Уведомляем пользователя
-	userMsg := tgbotapi.NewMessage(booking.UserID,
+	b.notifyBookingClient(booking,
 		fmt.Sprintf("🔄 Ваша заявка #%d возвращена в работу. Ожидайте подтверждения.", booking.ID))
-	b.bot.Send(userMsg)
 
 	managerMsg := tgbotapi.NewMessage(managerChatID, "✅ Заявка возвращена в работу")
 	b.bot.Send(managerMsg)
 
 	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ В GOOGLE SHEETS
-	b.SyncBookingsToSheets()
+	b.UpdateBookingStatusInSheets(booking.ID)
 	b.SyncScheduleToSheets()
 }
 
 // completeBooking завершение заявки
 func (b *Bot) completeBooking(booking *models.Booking, managerChatID int64) {
-	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "completed")
+	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "completed", managerChatID)
 	if err != nil {
 		log.Printf("Error completing booking: %v", err)
 		return
 	}
 
 	// Уведомляем пользователя
-	userMsg := tgbotapi.NewMessage(booking.UserID,
+	b.notifyBookingClient(booking,
 		fmt.Sprintf("🏁 Ваша заявка #%d завершена. Спасибо за использование наших услуг!", booking.ID))
-	b.bot.Send(userMsg)
 
 	managerMsg := tgbotapi.NewMessage(managerChatID, "✅ Заявка завершена")
 	b.bot.Send(managerMsg)
 
+	booking.Status = "completed"
+	b.publishBookingEvent(events.EventBookingCompleted, booking)
+
 	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ В GOOGLE SHEETS
-	b.SyncBookingsToSheets()
+	b.UpdateBookingStatusInSheets(booking.ID)
 	b.SyncScheduleToSheets()
 }
 
-// SyncScheduleToSheets синхронизирует расписание в формате таблицы с Google Sheets
+// SyncScheduleToSheets ставит в очередь синхронизацию расписания с Google Sheets
 func (b *Bot) SyncScheduleToSheets() {
-	if b.sheetsService == nil {
-		log.Println("Google Sheets service not initialized")
-		return
-	}
+	b.enqueueSheetTask(SheetTaskSyncSchedule, 0)
+}
+
+// syncScheduleSheet обновляет расписание в формате таблицы в Google Sheets
+func (b *Bot) syncScheduleSheet() error {
+	return b.syncScheduleSheetWithProgress(nil)
+}
 
+// syncScheduleSheetWithProgress перезаписывает листы расписания, сообщая о прогрессе после каждого месяца
+func (b *Bot) syncScheduleSheetWithProgress(progress google.ScheduleProgress) error {
 	// Определяем период: один месяц назад и два месяца вперед
-	startDate := time.Now().AddDate(0, -1, 0).Truncate(24 * time.Hour)
-	endDate := time.Now().AddDate(0, 2, 0).Truncate(24 * time.Hour)
+	startDate := b.today().AddDate(0, -1, 0)
+	endDate := b.today().AddDate(0, 2, 0)
 
 	log.Printf("Syncing schedule to Google Sheets from %s to %s",
-		startDate.Format("02.01.2006"),
-		endDate.Format("02.01.2006"))
+		startDate.Format(b.dateLayout()),
+		endDate.Format(b.dateLayout()))
 
 	// Получаем данные о бронированиях
-	dailyBookings, err := b.db.GetDailyBookings(context.Background(), startDate, endDate)
+	dailyBookings, err := b.db.GetDailyBookings(context.Background(), startDate, endDate, false)
 	if err != nil {
 		log.Printf("Failed to get daily bookings for schedule sync: %v", err)
-		return
+		return err
 	}
 
 	// Логируем количество найденных бронирований
@@ -904,6 +2314,7 @@ func (b *Bot) SyncScheduleToSheets() {
 				Date:         booking.Date,
 				Status:       booking.Status,
 				Comment:      booking.Comment,
+				Source:       booking.Source,
 				UserName:     booking.UserName,
 				UserNickname: booking.UserNickname,
 				Phone:        booking.Phone,
@@ -917,7 +2328,7 @@ func (b *Bot) SyncScheduleToSheets() {
 
 	// Конвертируем items
 	var googleItems []models.Item
-	for _, item := range b.items {
+	for _, item := range b.getItems() {
 		googleItems = append(googleItems, models.Item{
 			ID:            item.ID,
 			Name:          item.Name,
@@ -925,79 +2336,132 @@ func (b *Bot) SyncScheduleToSheets() {
 		})
 	}
 
+	// Выходные дни отмечаются в расписании так же, как в боте
+	closedDays := make(map[string]bool)
+	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		if b.isClosedDay(d) {
+			closedDays[d.Format("2006-01-02")] = true
+		}
+	}
+
 	log.Printf("Updating Google Sheets with %d items", len(googleItems))
 
 	// Обновляем расписание в Google Sheets
-	err = b.sheetsService.UpdateScheduleSheet(startDate, endDate, googleDailyBookings, googleItems)
+	start := time.Now()
+	err = b.sheetsService.UpdateScheduleSheet(startDate, endDate, googleDailyBookings, closedDays, googleItems, progress)
+	metrics.ObserveSheetsSync(metrics.SheetsOperationSchedule, start, err)
 	if err != nil {
 		log.Printf("Failed to sync schedule to Google Sheets: %v", err)
-	} else {
-		log.Printf("Schedule successfully synced to Google Sheets")
+		return err
 	}
+
+	log.Printf("Schedule successfully synced to Google Sheets")
+	return nil
 }
 
 // confirmBooking подтверждение бронирования менеджером
 func (b *Bot) confirmBooking(booking *models.Booking, managerChatID int64) {
-	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "confirmed")
+	if b.requiresDualApproval(booking.ItemID) {
+		switch {
+		case booking.Status == "confirmed":
+			b.sendMessage(managerChatID, "Заявка уже подтверждена")
+			return
+		case booking.Status != "awaiting_approval":
+			b.firstApproval(booking, managerChatID)
+			return
+		case booking.FirstApprovedBy == managerChatID:
+			b.sendMessage(managerChatID, "☑️ Вы уже подтвердили эту заявку. Окончательно ее подтверждает другой менеджер.")
+			return
+		}
+	}
+
+	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "confirmed", managerChatID)
 	if err != nil {
 		log.Printf("Error confirming booking: %v", err)
 		return
 	}
 
-	// Уведомляем пользователя
-	userMsg := tgbotapi.NewMessage(booking.UserID,
-		fmt.Sprintf("✅ Ваша заявка на %s %s подтверждена!",
-			booking.ItemName, booking.Date.Format("02.01.2006")))
-	b.bot.Send(userMsg)
-
 	// Уведомляем менеджера
 	managerMsg := tgbotapi.NewMessage(managerChatID, "✅ Бронирование подтверждено")
 	b.bot.Send(managerMsg)
 
+	b.afterBookingConfirmed(booking)
+}
+
+// afterBookingConfirmed уведомляет клиента, публикует событие и синхронизирует подтвержденную заявку
+func (b *Bot) afterBookingConfirmed(booking *models.Booking) {
+	// Уведомляем пользователя, код регистрации (текстом и QR-кодом) он покажет при получении аппарата
+	text := b.t(booking.UserID, "booking_confirmed", booking.ItemName, b.bookingPeriod(*booking))
+	token := b.issueCheckinToken(booking)
+	if token != "" {
+		text += b.t(booking.UserID, "booking_checkin_code", token)
+	}
+	if token == "" || !b.sendCheckinQR(booking, text, token) {
+		b.notifyBookingClient(booking, text)
+	}
+
+	booking.Status = "confirmed"
+	b.publishBookingEvent(events.EventBookingConfirmed, booking)
+
 	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ В GOOGLE SHEETS
-	b.SyncBookingsToSheets()
+	b.UpdateBookingStatusInSheets(booking.ID)
 	b.SyncScheduleToSheets()
 }
 
 // rejectBooking отклонение бронирования менеджером
 func (b *Bot) rejectBooking(booking *models.Booking, managerChatID int64) {
-	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "cancelled")
+	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "cancelled", managerChatID)
 	if err != nil {
 		log.Printf("Error rejecting booking: %v", err)
 		return
 	}
 
-	// Уведомляем пользователя
-	userMsg := tgbotapi.NewMessage(booking.UserID,
-		"❌ К сожалению, ваша заявка была отклонена менеджером.")
-	b.bot.Send(userMsg)
-
 	managerMsg := tgbotapi.NewMessage(managerChatID, "❌ Бронирование отменено")
 	b.bot.Send(managerMsg)
 
+	b.afterBookingRejected(booking)
+}
+
+// afterBookingRejected уведомляет клиента об отмене заявки менеджером, освобождает дату
+// для очереди ожидания и синхронизирует таблицы
+func (b *Bot) afterBookingRejected(booking *models.Booking) {
+	// Уведомляем пользователя
+	b.notifyBookingClient(booking, b.t(booking.UserID, "booking_rejected"))
+
+	booking.Status = "cancelled"
+	b.publishBookingEvent(events.EventBookingCancelled, booking)
+
+	b.notifyWaitlist(booking.ItemID, booking.Date)
+
 	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ В GOOGLE SHEETS
-	b.SyncBookingsToSheets()
+	b.UpdateBookingStatusInSheets(booking.ID)
 	b.SyncScheduleToSheets()
 }
 
 // rescheduleBooking предложение выбрать другую дату
 func (b *Bot) rescheduleBooking(booking *models.Booking, managerChatID int64) {
 	// Отправляем пользователю сообщение с предложением выбрать другую дату
-	userMsg := tgbotapi.NewMessage(booking.UserID,
-		fmt.Sprintf("🔄 Менеджер предложил выбрать другую дату для %s. Пожалуйста, создайте новую заявку.",
-			booking.ItemName))
-
-	keyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("📋 СОЗДАТЬ ЗАЯВКУ"),
-		),
-	)
-	userMsg.ReplyMarkup = keyboard
+	if hasClientChat(booking) {
+		userMsg := tgbotapi.NewMessage(booking.UserID,
+			fmt.Sprintf("🔄 Менеджер предложил выбрать другую дату для %s. Пожалуйста, создайте новую заявку.",
+				booking.ItemName))
+
+		keyboard := tgbotapi.NewReplyKeyboard(
+			tgbotapi.NewKeyboardButtonRow(
+				tgbotapi.NewKeyboardButton("📋 СОЗДАТЬ ЗАЯВКУ"),
+			),
+		)
+		userMsg.ReplyMarkup = keyboard
 
-	b.bot.Send(userMsg)
+		// Отложенное уведомление хранится без клавиатуры: кнопка создания заявки есть в главном меню
+		deliverAt, quiet := b.notificationDeferredUntil(true)
+		if !quiet || !b.deferNotification(booking.UserID, userMsg.Text, deliverAt) {
+			b.sendNotification(booking.UserID, userMsg)
+		}
+	}
 
 	// Обновляем статус текущей заявки
-	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "rescheduled")
+	err := b.db.UpdateBookingStatus(context.Background(), booking.ID, "rescheduled", managerChatID)
 	if err != nil {
 		log.Printf("Error updating booking status: %v", err)
 	}
@@ -1006,7 +2470,7 @@ func (b *Bot) rescheduleBooking(booking *models.Booking, managerChatID int64) {
 	b.bot.Send(managerMsg)
 
 	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ В GOOGLE SHEETS
-	b.SyncBookingsToSheets()
+	b.UpdateBookingStatusInSheets(booking.ID)
 	b.SyncScheduleToSheets()
 }
 
@@ -1021,13 +2485,16 @@ func (b *Bot) notifyManagers(booking models.Booking) {
 💬 Комментарий: %s
 🆔 ID заявки: %d`,
 		booking.ItemName,
-		booking.Date.Format("02.01.2006"),
+		b.bookingPeriod(booking),
 		booking.UserName,
 		booking.Phone,
 		booking.Comment,
 		booking.ID)
+	if booking.AttachmentFileID != "" {
+		message += fmt.Sprintf("\n📎 Клиент приложил %s: /manager_booking_%d", attachmentTitle(booking.AttachmentType), booking.ID)
+	}
 
-	for _, managerID := range b.config.Managers {
+	for _, managerID := range b.managersForItem(booking.ItemID) {
 		msg := tgbotapi.NewMessage(managerID, message)
 
 		keyboard := tgbotapi.NewInlineKeyboardMarkup(
@@ -1049,21 +2516,57 @@ func (b *Bot) notifyManagers(booking models.Booking) {
 	}
 }
 
+// managersForItem возвращает менеджеров, которым нужно сообщать о заявках на аппарат.
+// Менеджер без записи в manager_assignments получает все уведомления. Если аппарат
+// никому не назначен, уведомляются все менеджеры, чтобы заявка не потерялась.
+func (b *Bot) managersForItem(itemID int64) []int64 {
+	itemKey := strconv.FormatInt(itemID, 10)
+
+	var managers []int64
+	seen := make(map[int64]bool, len(b.config.Managers))
+	for _, managerID := range b.config.Managers {
+		// Один чат не должен получить заявку дважды, даже если ID повторяется в списке
+		if seen[managerID] {
+			continue
+		}
+		seen[managerID] = true
+
+		assignments, ok := b.config.ManagerAssignments[managerID]
+		if !ok {
+			managers = append(managers, managerID)
+			continue
+		}
+
+		for _, assignment := range assignments {
+			if assignment == "all" || assignment == itemKey {
+				managers = append(managers, managerID)
+				break
+			}
+		}
+	}
+
+	if len(managers) == 0 {
+		return b.config.Managers
+	}
+	return managers
+}
+
 // editManagerItemsPage редактирует страницу с аппаратами для менеджера
 func (b *Bot) editManagerItemsPage(update tgbotapi.Update, page int) {
 	callback := update.CallbackQuery
+	items := b.getItems()
 	itemsPerPage := 8
 	startIdx := page * itemsPerPage
 	endIdx := startIdx + itemsPerPage
-	if endIdx > len(b.items) {
-		endIdx = len(b.items)
+	if endIdx > len(items) {
+		endIdx = len(items)
 	}
 
 	var message strings.Builder
 	message.WriteString("🏢 *Выберите аппарат:*\n\n")
-	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(b.items)+itemsPerPage-1)/itemsPerPage))
+	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(items)+itemsPerPage-1)/itemsPerPage))
 
-	currentItems := b.items[startIdx:endIdx]
+	currentItems := items[startIdx:endIdx]
 	for i, item := range currentItems {
 		message.WriteString(fmt.Sprintf("%d. *%s*\n", startIdx+i+1, item.Name))
 		message.WriteString(fmt.Sprintf("   📝 %s\n", item.Description))
@@ -1086,7 +2589,7 @@ func (b *Bot) editManagerItemsPage(update tgbotapi.Update, page int) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("manager_items_page:%d", page-1)))
 	}
 
-	if endIdx < len(b.items) {
+	if endIdx < len(items) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("manager_items_page:%d", page+1)))
 	}
 
@@ -1144,12 +2647,21 @@ func (b *Bot) handleCallButton(update tgbotapi.Update) {
 	// Форматируем номер для отображения
 	formattedPhone := b.formatPhoneForDisplay(booking.Phone)
 
+	// Ссылки на мессенджеры требуют полный международный номер
+	internationalPhone := b.normalizePhone(booking.Phone)
+	if internationalPhone == "" {
+		internationalPhone = digitsOnly(booking.Phone)
+	}
+
 	// Создаем информативное сообщение
 	message := fmt.Sprintf("📞 *Информация для связи*\n\n")
 	message += fmt.Sprintf("👤 *Клиент:* %s\n", booking.UserName)
 	message += fmt.Sprintf("📱 *Телефон:* `%s`\n", formattedPhone)
+	if booking.SecondaryPhone != "" {
+		message += fmt.Sprintf("📱 *Запасной телефон:* `%s`\n", b.formatPhoneForDisplay(booking.SecondaryPhone))
+	}
 	message += fmt.Sprintf("🏢 *Аппарат:* %s\n", booking.ItemName)
-	message += fmt.Sprintf("📅 *Дата:* %s\n", booking.Date.Format("02.01.2006"))
+	message += fmt.Sprintf("📅 *Дата:* %s\n", b.bookingPeriod(*booking))
 
 	if booking.Comment != "" {
 		message += fmt.Sprintf("💬 *Комментарий:* %s\n", booking.Comment)
@@ -1159,42 +2671,25 @@ func (b *Bot) handleCallButton(update tgbotapi.Update) {
 	msg.ParseMode = "Markdown"
 
 	// Создаем клавиатуру с быстрыми действиями
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+	rows := [][]tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("💬 WhatsApp", fmt.Sprintf("https://wa.me/%s", strings.TrimPrefix(booking.Phone, "+"))),
-			tgbotapi.NewInlineKeyboardButtonURL("✉️ Telegram", fmt.Sprintf("https://t.me/%s", strings.TrimPrefix(booking.Phone, "+"))),
+			tgbotapi.NewInlineKeyboardButtonURL("💬 WhatsApp", fmt.Sprintf("https://wa.me/%s", internationalPhone)),
+			tgbotapi.NewInlineKeyboardButtonURL("✉️ Telegram", fmt.Sprintf("https://t.me/+%s", internationalPhone)),
 		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к заявке", fmt.Sprintf("show_booking:%d", booking.ID)),
-		),
-	)
+	}
+	if secondaryPhone := b.normalizePhone(booking.SecondaryPhone); secondaryPhone != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL("💬 WhatsApp (запасной)", fmt.Sprintf("https://wa.me/%s", secondaryPhone)),
+			tgbotapi.NewInlineKeyboardButtonURL("✉️ Telegram (запасной)", fmt.Sprintf("https://t.me/+%s", secondaryPhone)),
+		))
+	}
+	rows = append(rows, b.managerTemplateRows(booking, internationalPhone)...)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к заявке", fmt.Sprintf("show_booking:%d", booking.ID)),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 	msg.ReplyMarkup = &keyboard
 
 	b.bot.Send(tgbotapi.NewCallback(callback.ID, "✅"))
 	b.bot.Send(msg)
 }
-
-// formatPhoneForDisplay форматирует номер телефона для красивого отображения
-func (b *Bot) formatPhoneForDisplay(phone string) string {
-	// Убираем все нецифровые символы
-	cleaned := ""
-	for _, char := range phone {
-		if char >= '0' && char <= '9' {
-			cleaned += string(char)
-		}
-	}
-
-	// Форматируем в зависимости от длины
-	if len(cleaned) == 11 && cleaned[0] == '7' {
-		// Российский номер: +7 (XXX) XXX-XX-XX
-		return fmt.Sprintf("+7 (%s) %s-%s-%s",
-			cleaned[1:4], cleaned[4:7], cleaned[7:9], cleaned[9:])
-	} else if len(cleaned) == 10 {
-		// Номер без кода страны: (XXX) XXX-XX-XX
-		return fmt.Sprintf("(%s) %s-%s-%s",
-			cleaned[0:3], cleaned[3:6], cleaned[6:8], cleaned[8:])
-	}
-
-	// Возвращаем исходный номер, если форматирование не применимо
-	return phone
-}
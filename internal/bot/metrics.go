@@ -1,59 +1,42 @@
 package bot
 
 import (
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"context"
+	"log"
+	"time"
+
+	"bronivik/internal/metrics"
+)
+
+const (
+	// gaugeMetricsInterval как часто обновляются метрики из БД
+	gaugeMetricsInterval = time.Minute
+	// activeUsersPeriod период, за который пользователь считается активным
+	activeUsersPeriod = 30 * 24 * time.Hour
 )
 
-// Metrics структура для метрик Prometheus
-type Metrics struct {
-	MessagesProcessed    prometheus.Counter
-	CommandsProcessed    prometheus.Counter
-	CommandDuration      *prometheus.HistogramVec
-	CalculationsTotal    *prometheus.CounterVec
-	ErrorsTotal          prometheus.Counter
-	UsersTotal           prometheus.Gauge
-	UpdateProcessingTime prometheus.Histogram
+// runGaugeMetricsLoop периодически обновляет метрики заявок и пользователей
+func (b *Bot) runGaugeMetricsLoop() {
+	b.updateGaugeMetrics()
+
+	ticker := time.NewTicker(gaugeMetricsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		b.updateGaugeMetrics()
+	}
 }
 
-// NewMetrics создает новые метрики
-func NewMetrics() *Metrics {
-	return &Metrics{
-		MessagesProcessed: promauto.NewCounter(prometheus.CounterOpts{
-			Name: "telegram_bot_messages_processed_total",
-			Help: "Total number of processed messages",
-		}),
-
-		CommandsProcessed: promauto.NewCounter(prometheus.CounterOpts{
-			Name: "telegram_bot_commands_processed_total",
-			Help: "Total number of processed commands",
-		}),
-
-		CommandDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
-			Name:    "telegram_bot_command_duration_seconds",
-			Help:    "Duration of command processing",
-			Buckets: prometheus.DefBuckets,
-		}, []string{"command"}),
-
-		CalculationsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "telegram_bot_calculations_total",
-			Help: "Total number of calculations by type",
-		}, []string{"type"}),
-
-		ErrorsTotal: promauto.NewCounter(prometheus.CounterOpts{
-			Name: "telegram_bot_errors_total",
-			Help: "Total number of errors",
-		}),
-
-		UsersTotal: promauto.NewGauge(prometheus.GaugeOpts{
-			Name: "telegram_bot_users_total",
-			Help: "Total number of users",
-		}),
-
-		UpdateProcessingTime: promauto.NewHistogram(prometheus.HistogramOpts{
-			Name:    "telegram_bot_update_processing_time_seconds",
-			Help:    "Time spent processing updates",
-			Buckets: prometheus.DefBuckets,
-		}),
+// updateGaugeMetrics заполняет gauge-метрики текущими значениями из БД
+func (b *Bot) updateGaugeMetrics() {
+	counts, err := b.db.GetGaugeCounts(context.Background(), b.today(), time.Now().Add(-activeUsersPeriod))
+	if err != nil {
+		log.Printf("Error updating gauge metrics: %v", err)
+		return
 	}
+
+	metrics.BookingsPending.Set(float64(counts.PendingBookings))
+	metrics.BookingsConfirmedToday.Set(float64(counts.ConfirmedToday))
+	metrics.UsersActive.Set(float64(counts.ActiveUsers))
+	metrics.UsersBlacklisted.Set(float64(counts.BlacklistedUsers))
 }
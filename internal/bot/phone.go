@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// phoneCountry правила номеров для кода страны
+type phoneCountry struct {
+	code        string // код страны без "+"
+	nationalLen int    // длина номера без кода страны
+	trunkPrefix string // префикс для звонков внутри страны (8 в России)
+}
+
+// phoneCountries поддерживаемые страны. Казахстан использует код +7 вместе с Россией.
+var phoneCountries = []phoneCountry{
+	{code: "7", nationalLen: 10, trunkPrefix: "8"},
+	{code: "375", nationalLen: 9, trunkPrefix: "80"},
+}
+
+// defaultPhoneCountryCode код страны, если в конфиге он не задан
+const defaultPhoneCountryCode = "7"
+
+// digitsOnly оставляет в строке только цифры
+func digitsOnly(s string) string {
+	var cleaned strings.Builder
+	for _, char := range s {
+		if char >= '0' && char <= '9' {
+			cleaned.WriteRune(char)
+		}
+	}
+	return cleaned.String()
+}
+
+// findPhoneCountry ищет страну по коду
+func findPhoneCountry(code string) (phoneCountry, bool) {
+	for _, country := range phoneCountries {
+		if country.code == code {
+			return country, true
+		}
+	}
+	return phoneCountry{}, false
+}
+
+// matchInternational проверяет, что номер состоит из кода известной страны и номера нужной длины.
+// Номер внутри страны не может начинаться с 0.
+func matchInternational(digits string) (phoneCountry, bool) {
+	for _, country := range phoneCountries {
+		if strings.HasPrefix(digits, country.code) &&
+			len(digits) == len(country.code)+country.nationalLen &&
+			digits[len(country.code)] != '0' {
+			return country, true
+		}
+	}
+	return phoneCountry{}, false
+}
+
+// normalizePhone приводит номер к виду "код страны + номер" без "+" (например, 79001234567).
+// Возвращает пустую строку, если номер не подходит ни под одну из поддерживаемых стран.
+func (b *Bot) normalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	digits := digitsOnly(phone)
+
+	// Номер с "+" всегда содержит код страны
+	if strings.HasPrefix(phone, "+") {
+		if _, ok := matchInternational(digits); ok {
+			return digits
+		}
+		return ""
+	}
+
+	if _, ok := matchInternational(digits); ok {
+		return digits
+	}
+
+	defaultCountry, ok := findPhoneCountry(b.config.Booking.DefaultCountryCode)
+	if !ok {
+		defaultCountry, _ = findPhoneCountry(defaultPhoneCountryCode)
+	}
+
+	var candidate string
+	switch {
+	case strings.HasPrefix(digits, defaultCountry.trunkPrefix) &&
+		len(digits) == len(defaultCountry.trunkPrefix)+defaultCountry.nationalLen:
+		// Внутренний формат: 8XXXXXXXXXX -> 7XXXXXXXXXX, 80XXXXXXXXX -> 375XXXXXXXXX
+		candidate = defaultCountry.code + digits[len(defaultCountry.trunkPrefix):]
+	case len(digits) == defaultCountry.nationalLen:
+		// Номер без кода страны и без префикса
+		candidate = defaultCountry.code + digits
+	}
+
+	if _, ok := matchInternational(candidate); ok {
+		return candidate
+	}
+
+	return "" // Неверный формат
+}
+
+// phoneSeparator разделяет основной и запасной номер в одном сообщении: запятая, точка с запятой,
+// косая черта, перенос строки или слово "или"
+var phoneSeparator = regexp.MustCompile(`[,;/\n]|\s+или\s+`)
+
+// parsePhones разбирает ввод с одним или двумя номерами: основной и необязательный запасной.
+// Возвращает false, если номеров больше двух или какой-то из них не распознан.
+func (b *Bot) parsePhones(text string) (primary, secondary string, ok bool) {
+	var parts []string
+	for _, part := range phoneSeparator.Split(text, -1) {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 || len(parts) > 2 {
+		return "", "", false
+	}
+
+	primary = b.normalizePhone(parts[0])
+	if primary == "" {
+		return "", "", false
+	}
+	if len(parts) == 2 {
+		secondary = b.normalizePhone(parts[1])
+		if secondary == "" {
+			return "", "", false
+		}
+		if secondary == primary {
+			secondary = ""
+		}
+	}
+	return primary, secondary, true
+}
+
+// formatPhoneForDisplay форматирует номер телефона для красивого отображения
+func (b *Bot) formatPhoneForDisplay(phone string) string {
+	normalized := b.normalizePhone(phone)
+	country, ok := matchInternational(normalized)
+	if !ok {
+		// Возвращаем исходный номер, если форматирование не применимо
+		return phone
+	}
+
+	national := normalized[len(country.code):]
+	switch country.nationalLen {
+	case 10:
+		// +7 (XXX) XXX-XX-XX
+		return fmt.Sprintf("+%s (%s) %s-%s-%s",
+			country.code, national[0:3], national[3:6], national[6:8], national[8:])
+	case 9:
+		// +375 (XX) XXX-XX-XX
+		return fmt.Sprintf("+%s (%s) %s-%s-%s",
+			country.code, national[0:2], national[2:5], national[5:7], national[7:])
+	}
+
+	return "+" + normalized
+}
+
+// maskPhone скрывает цифры номера, кроме первой и двух последних: +7*******67
+func maskPhone(phone string) string {
+	digits := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+
+	var masked strings.Builder
+	seen := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			seen++
+			if seen > 1 && seen <= digits-2 {
+				masked.WriteRune('*')
+				continue
+			}
+		}
+		masked.WriteRune(r)
+	}
+	return masked.String()
+}
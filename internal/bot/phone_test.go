@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"testing"
+
+	"bronivik/internal/config"
+)
+
+// newPhoneTestBot создает бота только с настройкой кода страны по умолчанию
+func newPhoneTestBot(defaultCountryCode string) *Bot {
+	return &Bot{config: &config.Config{Booking: config.BookingConfig{DefaultCountryCode: defaultCountryCode}}}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultCode string
+		input       string
+		want        string
+	}{
+		{"russia international", "7", "+7 900 123-45-67", "79001234567"},
+		{"russia international without plus", "7", "79001234567", "79001234567"},
+		{"russia trunk prefix 8", "7", "8 (900) 123-45-67", "79001234567"},
+		{"russia without code", "7", "9001234567", "79001234567"},
+		{"kazakhstan +77", "7", "+7 701 234 56 78", "77012345678"},
+		{"kazakhstan 87", "7", "87012345678", "77012345678"},
+		{"belarus international", "7", "+375 29 123-45-67", "375291234567"},
+		{"belarus trunk prefix with default 375", "375", "80291234567", "375291234567"},
+		{"belarus without code with default 375", "375", "291234567", "375291234567"},
+		{"unknown default falls back to 7", "999", "89001234567", "79001234567"},
+		{"too short", "7", "+7 900 123", ""},
+		{"too long", "7", "+7 900 123 45 678", ""},
+		{"unknown country with plus", "7", "+44 20 7946 0958", ""},
+		{"national number starting with 0", "7", "+7 000 123 45 67", ""},
+		{"letters", "7", "позвоните мне", ""},
+		{"empty", "7", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newPhoneTestBot(tt.defaultCode)
+			if got := b.normalizePhone(tt.input); got != tt.want {
+				t.Errorf("normalizePhone(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPhoneForDisplay(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"89001234567", "+7 (900) 123-45-67"},
+		{"+77012345678", "+7 (701) 234-56-78"},
+		{"+375291234567", "+375 (29) 123-45-67"},
+		{"garbage", "garbage"},
+	}
+
+	b := newPhoneTestBot("7")
+	for _, tt := range tests {
+		if got := b.formatPhoneForDisplay(tt.input); got != tt.want {
+			t.Errorf("formatPhoneForDisplay(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"bronivik/internal/config"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// deferredNotificationsInterval как часто проверяются отложенные уведомления
+const deferredNotificationsInterval = time.Minute
+
+// quietHoursEnd возвращает момент окончания тихих часов, если now попадает в них
+func quietHoursEnd(now time.Time, quiet config.QuietHoursConfig) (time.Time, bool) {
+	if !quiet.Enabled() {
+		return time.Time{}, false
+	}
+
+	hour := now.Hour()
+	inQuiet := hour >= quiet.Start && hour < quiet.End
+	if quiet.Start > quiet.End {
+		// Период через полночь, например с 22 до 8
+		inQuiet = hour >= quiet.Start || hour < quiet.End
+	}
+	if !inQuiet {
+		return time.Time{}, false
+	}
+
+	end := time.Date(now.Year(), now.Month(), now.Day(), quiet.End, 0, 0, 0, now.Location())
+	if !end.After(now) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+// notificationDeferredUntil возвращает, до какого момента отложить уведомление, если сейчас тихие часы.
+// managerAction - уведомление о действии менеджера, для него учитывается quiet_hours.manager_bypass.
+func (b *Bot) notificationDeferredUntil(managerAction bool) (time.Time, bool) {
+	quiet := b.config.Notifications.QuietHours
+	if managerAction && quiet.ManagerBypass {
+		return time.Time{}, false
+	}
+	return quietHoursEnd(b.now(), quiet)
+}
+
+// deferNotification сохраняет уведомление до deliverAt. false - сохранить не удалось, его нужно отправить сразу
+func (b *Bot) deferNotification(userID int64, text string, deliverAt time.Time) bool {
+	if err := b.db.AddDeferredNotification(context.Background(), userID, text, deliverAt); err != nil {
+		log.Printf("Error deferring notification to %d: %v", userID, err)
+		return false
+	}
+	log.Printf("Notification to %d deferred until %s (quiet hours)", userID, deliverAt.Format("2006-01-02 15:04"))
+	return true
+}
+
+// sendOrDeferNotification отправляет текстовое уведомление сразу или откладывает его до конца тихих часов
+func (b *Bot) sendOrDeferNotification(userID int64, text string, managerAction bool) {
+	if deliverAt, ok := b.notificationDeferredUntil(managerAction); ok && b.deferNotification(userID, text, deliverAt) {
+		return
+	}
+	b.sendNotification(userID, tgbotapi.NewMessage(userID, text))
+}
+
+// runDeferredNotificationsLoop доставляет уведомления, отложенные на тихие часы.
+// Работает и при выключенных тихих часах, чтобы не потерять уже отложенные сообщения.
+func (b *Bot) runDeferredNotificationsLoop() {
+	ticker := time.NewTicker(deferredNotificationsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		b.deliverDeferredNotifications()
+	}
+}
+
+// deliverDeferredNotifications отправляет отложенные уведомления, время которых наступило
+func (b *Bot) deliverDeferredNotifications() {
+	notifications, err := b.db.GetDueDeferredNotifications(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("Error getting deferred notifications: %v", err)
+		return
+	}
+
+	for _, notification := range notifications {
+		b.sendNotification(notification.UserID, tgbotapi.NewMessage(notification.UserID, notification.Text))
+		if err := b.db.DeleteDeferredNotification(context.Background(), notification.ID); err != nil {
+			log.Printf("Error deleting deferred notification %d: %v", notification.ID, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bronivik/internal/config"
+	"bronivik/internal/database"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2030, 3, day, hour, minute, 0, 0, loc)
+	}
+	overnight := config.QuietHoursConfig{Start: 22, End: 8}
+	daytime := config.QuietHoursConfig{Start: 13, End: 15}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		quiet  config.QuietHoursConfig
+		want   time.Time
+		wantOK bool
+	}{
+		{"disabled", at(10, 23, 0), config.QuietHoursConfig{}, time.Time{}, false},
+		{"before overnight window", at(10, 21, 59), overnight, time.Time{}, false},
+		{"overnight before midnight", at(10, 23, 30), overnight, at(11, 8, 0), true},
+		{"overnight after midnight", at(11, 3, 0), overnight, at(11, 8, 0), true},
+		{"overnight window end", at(11, 8, 0), overnight, time.Time{}, false},
+		{"daytime window", at(10, 14, 10), daytime, at(10, 15, 0), true},
+		{"after daytime window", at(10, 15, 0), daytime, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := quietHoursEnd(tt.now, tt.quiet)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("quietHoursEnd(%s) = %s, %v; want %s, %v", tt.now, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSendOrDeferNotificationInQuietHours(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "bookings.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	loc := time.FixedZone("MSK", 3*60*60)
+	setTestNow(t, time.Date(2030, 3, 10, 23, 30, 0, 0, loc))
+
+	cfg := &config.Config{}
+	cfg.Notifications.QuietHours = config.QuietHoursConfig{Start: 22, End: 8, ManagerBypass: true}
+	b := &Bot{config: cfg, location: loc, db: db}
+
+	// Вызов без отложенной отправки обратился бы к Telegram API, а b.bot не задан
+	b.sendOrDeferNotification(100, "🔔 Напоминание", false)
+
+	ctx := context.Background()
+	deliverAt := time.Date(2030, 3, 11, 8, 0, 0, 0, loc)
+
+	due, err := db.GetDueDeferredNotifications(ctx, deliverAt.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetDueDeferredNotifications: %v", err)
+	}
+	if len(due) != 0 {
+		t.Fatalf("notification must not be due before quiet hours end, got %d", len(due))
+	}
+
+	due, err = db.GetDueDeferredNotifications(ctx, deliverAt)
+	if err != nil {
+		t.Fatalf("GetDueDeferredNotifications: %v", err)
+	}
+	if len(due) != 1 || due[0].UserID != 100 || due[0].Text != "🔔 Напоминание" {
+		t.Fatalf("deferred notifications = %+v, want one for user 100", due)
+	}
+
+	// Действия менеджера при manager_bypass не откладываются
+	if _, deferred := b.notificationDeferredUntil(true); deferred {
+		t.Error("manager action must bypass quiet hours when manager_bypass is set")
+	}
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitWindow окно, в котором считаются создания заявок
+const rateLimitWindow = time.Minute
+
+// RateLimiter ограничивает частоту действий пользователя
+type RateLimiter interface {
+	// Allow регистрирует попытку и сообщает, укладывается ли она в лимит
+	Allow(ctx context.Context, userID int64) (bool, error)
+}
+
+// memoryRateLimiter скользящее окно в памяти процесса
+type memoryRateLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	attempts map[int64][]time.Time
+}
+
+func newMemoryRateLimiter(limit int, window time.Duration) *memoryRateLimiter {
+	return &memoryRateLimiter{
+		limit:    limit,
+		window:   window,
+		attempts: make(map[int64][]time.Time),
+	}
+}
+
+func (l *memoryRateLimiter) Allow(ctx context.Context, userID int64) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+
+	// Отбрасываем попытки за пределами окна
+	recent := l.attempts[userID][:0]
+	for _, t := range l.attempts[userID] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.attempts[userID] = recent
+		return false, nil
+	}
+
+	l.attempts[userID] = append(recent, now)
+	return true, nil
+}
+
+// rateLimitScript атомарно очищает окно, проверяет лимит и регистрирует попытку,
+// чтобы параллельные запросы с нескольких экземпляров бота не проходили одну и ту же проверку
+var rateLimitScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "0", ARGV[1])
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return 1
+`)
+
+// redisRateLimiter скользящее окно в Redis на основе sorted set,
+// чтобы лимит соблюдался и между перезапусками бота
+type redisRateLimiter struct {
+	client *redis.Client
+	limit  int
+	window time.Duration
+}
+
+func newRedisRateLimiter(client *redis.Client, limit int, window time.Duration) *redisRateLimiter {
+	return &redisRateLimiter{
+		client: client,
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (l *redisRateLimiter) Allow(ctx context.Context, userID int64) (bool, error) {
+	key := fmt.Sprintf("ratelimit:booking:%d", userID)
+	now := time.Now()
+	cutoff := now.Add(-l.window).UnixNano()
+
+	allowed, err := rateLimitScript.Run(ctx, l.client, []string{key},
+		strconv.FormatInt(cutoff, 10), now.UnixNano(), l.limit, l.window.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return allowed == 1, nil
+}
+
+// SetRedisClient переключает ограничение частоты и блокировку слотов на Redis
+func (b *Bot) SetRedisClient(client *redis.Client) {
+	if client == nil {
+		return
+	}
+	b.slotLocker = newRedisSlotLocker(client)
+
+	if b.config.Booking.MaxPerMinute > 0 {
+		b.rateLimiter = newRedisRateLimiter(client, b.config.Booking.MaxPerMinute, rateLimitWindow)
+	}
+}
+
+// allowBooking проверяет лимит на создание заявок. При ошибке хранилища заявку не блокируем.
+func (b *Bot) allowBooking(userID int64) bool {
+	if b.rateLimiter == nil {
+		return true
+	}
+
+	allowed, err := b.rateLimiter.Allow(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error checking rate limit for user %d: %v", userID, err)
+		return true
+	}
+
+	return allowed
+}
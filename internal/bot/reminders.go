@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendBookingReminder отправляет клиенту напоминание с кнопками подтверждения явки и отмены.
+// Отложенное на тихие часы напоминание хранится без клавиатуры: отменить заявку можно в «📊 Мои заявки».
+func (b *Bot) sendBookingReminder(booking *models.Booking) {
+	if !hasClientChat(booking) {
+		return
+	}
+
+	text := b.t(booking.UserID, "booking_reminder", booking.ID, booking.ItemName, b.bookingPeriod(*booking))
+	if deliverAt, ok := b.notificationDeferredUntil(false); ok && b.deferNotification(booking.UserID, text, deliverAt) {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(booking.UserID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.t(booking.UserID, "reminder_ok_button"), fmt.Sprintf("remind_ok:%d", booking.ID)),
+		tgbotapi.NewInlineKeyboardButtonData(b.t(booking.UserID, "reminder_cancel_button"), fmt.Sprintf("remind_cancel:%d", booking.ID)),
+	))
+	b.sendNotification(booking.UserID, msg)
+}
+
+// handleReminderResponse обрабатывает кнопки напоминания: remind_ok:<id> и remind_cancel:<id>
+func (b *Bot) handleReminderResponse(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	action, rawID, _ := strings.Cut(callback.Data, ":")
+	bookingID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
+
+	// Кнопки напоминания одноразовые, дальше заявкой управляют через «📊 Мои заявки»
+	chatID := callback.Message.Chat.ID
+	b.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+
+	if action == "remind_cancel" {
+		b.cancelBookingByUser(callback, bookingID)
+		return
+	}
+
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil || booking.UserID != callback.From.ID {
+		b.sendMessage(chatID, "❌ Заявка не найдена")
+		return
+	}
+	if booking.Status != "confirmed" {
+		b.sendMessage(chatID, b.t(callback.From.ID, "reminder_not_active"))
+		return
+	}
+
+	if booking.ReminderAckAt.IsZero() {
+		if err := b.db.AcknowledgeBookingReminder(context.Background(), booking.ID); err != nil {
+			log.Printf("Error acknowledging reminder for booking %d: %v", booking.ID, err)
+		}
+	}
+	b.sendMessage(chatID, b.t(callback.From.ID, "reminder_acknowledged"))
+}
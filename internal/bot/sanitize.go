@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// textField ограничения для свободного текста, который вводит пользователь или менеджер
+type textField struct {
+	name      string // название поля в сообщении об ошибке
+	minLength int
+	maxLength int
+	multiline bool // разрешены ли переносы строк
+}
+
+var (
+	userNameField = textField{name: "Имя", minLength: 2, maxLength: 150}
+	commentField  = textField{name: "Комментарий", maxLength: maxUserCommentLength, multiline: true}
+	noteField     = textField{name: "Заметка", maxLength: 1000, multiline: true}
+)
+
+// markdownReplacer убирает символы разметки Telegram Markdown: введенный текст
+// попадает в сообщения с ParseMode Markdown и не должен ломать или подменять форматирование
+var markdownReplacer = strings.NewReplacer("*", "", "_", " ", "`", "'", "[", "(", "]", ")")
+
+// sanitizeInput очищает введенный текст: убирает управляющие символы и разметку Markdown,
+// схлопывает пробелы. Переносы строк сохраняются только для многострочных полей.
+func sanitizeInput(text string, multiline bool) string {
+	text = markdownReplacer.Replace(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) || r == utf8.RuneError {
+				return ' '
+			}
+			return r
+		}, line)
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+
+	if !multiline {
+		return strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// clean очищает текст и проверяет его длину; ошибка содержит готовое сообщение для пользователя
+func (f textField) clean(text string) (string, error) {
+	text = sanitizeInput(text, f.multiline)
+
+	length := utf8.RuneCountInString(text)
+	if f.minLength > 0 && length < f.minLength {
+		return "", fmt.Errorf("%s: слишком короткий текст. Введите от %d символов.", f.name, f.minLength)
+	}
+	if length > f.maxLength {
+		return "", fmt.Errorf("%s: слишком длинный текст. Введите до %d символов.", f.name, f.maxLength)
+	}
+	return text, nil
+}
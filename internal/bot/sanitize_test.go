@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		multiline bool
+		want      string
+	}{
+		{"plain text", "Иван Петров", false, "Иван Петров"},
+		{"bold and code markers", "*Иван* `Петров`", false, "Иван 'Петров'"},
+		{"underscore", "ivan_petrov", false, "ivan petrov"},
+		{"link markup", "[нажми](http://evil)", false, "(нажми)(http://evil)"},
+		{"control characters", "Иван\x00\x07Петров", false, "Иван Петров"},
+		{"extra spaces", "  Иван   Петров  ", false, "Иван Петров"},
+		{"newlines in single-line field", "Иван\nПетров", false, "Иван Петров"},
+		{"newlines in multiline field", "строка 1\n\tстрока 2 ", true, "строка 1\nстрока 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeInput(tt.input, tt.multiline); got != tt.want {
+				t.Errorf("sanitizeInput(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextFieldClean(t *testing.T) {
+	field := textField{name: "Имя", minLength: 2, maxLength: 10}
+
+	if got, err := field.clean("  *Иван*  "); err != nil || got != "Иван" {
+		t.Errorf("clean(valid) = %q, %v; want Иван, nil", got, err)
+	}
+
+	// Длина считается в символах, а не в байтах: 10 кириллических букв проходят
+	if got, err := field.clean(strings.Repeat("я", 10)); err != nil || got != strings.Repeat("я", 10) {
+		t.Errorf("clean(10 runes) = %q, %v; want accepted", got, err)
+	}
+
+	if _, err := field.clean(strings.Repeat("я", 11)); err == nil {
+		t.Error("clean(oversized) must fail")
+	}
+	if _, err := field.clean("*"); err == nil {
+		t.Error("clean(only markup) must fail the minimum length check")
+	}
+}
+
+func TestUserFieldsLimits(t *testing.T) {
+	if _, err := commentField.clean(strings.Repeat("a", maxUserCommentLength+1)); err == nil {
+		t.Errorf("comment longer than %d characters must be rejected", maxUserCommentLength)
+	}
+	if _, err := userNameField.clean(strings.Repeat("a", 151)); err == nil {
+		t.Error("name longer than 150 characters must be rejected")
+	}
+	if _, err := noteField.clean(strings.Repeat("a", 1000)); err != nil {
+		t.Errorf("note of 1000 characters must be accepted: %v", err)
+	}
+}
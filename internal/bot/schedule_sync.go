@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// scheduleProgressInterval минимальный интервал между правками сообщения о прогрессе (лимиты Telegram)
+const scheduleProgressInterval = 2 * time.Second
+
+// handleScheduleSyncRequest запускает синхронизацию расписания по кнопке менеджера
+// и показывает её ход в одном редактируемом сообщении
+func (b *Bot) handleScheduleSyncRequest(chatID int64) {
+	if b.sheetsService == nil {
+		b.sendMessage(chatID, "Google Sheets не настроен")
+		return
+	}
+	if !b.scheduleSyncMu.TryLock() {
+		b.sendMessage(chatID, "⏳ Синхронизация расписания уже выполняется, дождитесь её завершения")
+		return
+	}
+
+	status, err := b.bot.Send(tgbotapi.NewMessage(chatID, "⏳ Синхронизация расписания..."))
+	if err != nil {
+		b.scheduleSyncMu.Unlock()
+		log.Printf("Error sending schedule sync status: %v", err)
+		return
+	}
+
+	go func() {
+		defer b.scheduleSyncMu.Unlock()
+		b.runScheduleSyncWithProgress(chatID, status.MessageID)
+	}()
+}
+
+// runScheduleSyncWithProgress синхронизирует расписание, обновляя сообщение messageID после каждого месяца
+func (b *Bot) runScheduleSyncWithProgress(chatID int64, messageID int) {
+	editStatus := func(text string) {
+		if _, err := b.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, text)); err != nil {
+			log.Printf("Error updating schedule sync status: %v", err)
+		}
+	}
+
+	var lastEdit time.Time
+	var processed, total int
+	err := b.syncScheduleSheetWithProgress(func(done, all int) {
+		processed, total = done, all
+		if done < all && time.Since(lastEdit) < scheduleProgressInterval {
+			return
+		}
+		lastEdit = time.Now()
+		editStatus(fmt.Sprintf("⏳ Синхронизация... обработано %d из %d дней", done, all))
+	})
+
+	if err != nil {
+		if total == 0 {
+			editStatus(fmt.Sprintf("❌ Не удалось синхронизировать расписание: %v", err))
+			return
+		}
+		editStatus(fmt.Sprintf("⚠️ Расписание синхронизировано с ошибками (обработано %d из %d дней):\n%v", processed, total, err))
+		return
+	}
+
+	editStatus(fmt.Sprintf("✅ Расписание синхронизировано: %d дней", total))
+}
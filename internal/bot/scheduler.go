@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"bronivik/internal/database"
+	"bronivik/internal/events"
+)
+
+// startBackgroundJobs запускает периодические фоновые задачи бота
+func (b *Bot) startBackgroundJobs() {
+	if b.sheetsWorker != nil {
+		go b.sheetsWorker.Run()
+	}
+	if b.config.Booking.PendingTTLHours > 0 {
+		go b.runPendingExpiryLoop()
+	}
+	if b.config.Notifications.DailyDigestEnabled {
+		go b.runDailyDigestLoop()
+	}
+	if b.config.Notifications.WeeklySummary.Enabled {
+		go b.runWeeklySummaryLoop()
+	}
+	if len(b.reminderOffsets()) > 0 {
+		go b.runReminderLoop()
+	}
+	go b.runDeferredNotificationsLoop()
+	if b.config.Monitoring.PrometheusEnabled {
+		go b.runGaugeMetricsLoop()
+	}
+}
+
+// timeUntilNextHour возвращает время до начала следующего часа в часовом поясе app.timezone.
+// Truncate считает от UTC, поэтому для поясов со смещением не на целый час он не подходит.
+func (b *Bot) timeUntilNextHour() time.Duration {
+	now := b.now()
+	next := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, b.location).Add(time.Hour)
+	return next.Sub(now)
+}
+
+// runPendingExpiryLoop раз в час отменяет заявки, которые менеджеры не обработали вовремя
+func (b *Bot) runPendingExpiryLoop() {
+	for {
+		time.Sleep(b.timeUntilNextHour())
+		b.expireStalePendingBookings()
+	}
+}
+
+// expireStalePendingBookings отменяет заявки в статусе pending старше booking.pending_ttl_hours
+func (b *Bot) expireStalePendingBookings() {
+	ttl := time.Duration(b.config.Booking.PendingTTLHours) * time.Hour
+	bookings, err := b.db.GetStalePendingBookings(context.Background(), time.Now().Add(-ttl))
+	if err != nil {
+		log.Printf("Error getting stale pending bookings: %v", err)
+		return
+	}
+
+	expired := 0
+	for _, booking := range bookings {
+		// Проверка версии не даст отменить заявку, которую менеджер как раз подтверждает
+		err := b.db.UpdateBookingStatusWithVersion(context.Background(), booking.ID, booking.Version, "cancelled", 0)
+		if errors.Is(err, database.ErrConcurrentModification) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error expiring booking %d: %v", booking.ID, err)
+			continue
+		}
+
+		expired++
+		log.Printf("Booking %d expired after %d hours in pending", booking.ID, b.config.Booking.PendingTTLHours)
+
+		b.notifyBookingClientScheduled(&booking,
+			b.t(booking.UserID, "booking_expired", booking.ID, booking.ItemName, b.bookingPeriod(booking)))
+
+		booking.Status = "cancelled"
+		b.publishBookingEvent(events.EventBookingCancelled, &booking)
+
+		b.notifyWaitlist(booking.ItemID, booking.Date)
+	}
+
+	if expired > 0 {
+		b.SyncBookingsToSheets()
+	}
+}
+
+// runDailyDigestLoop каждый день в notifications.daily_digest_hour отправляет менеджерам сводку на сегодня
+func (b *Bot) runDailyDigestLoop() {
+	for {
+		time.Sleep(b.timeUntilNextHour())
+		if b.now().Hour() == b.config.Notifications.DailyDigestHour {
+			b.sendManagerDailyDigest()
+		}
+	}
+}
+
+// sendManagerDailyDigest отправляет менеджерам подтвержденные заявки на сегодня, сгруппированные по аппаратам
+func (b *Bot) sendManagerDailyDigest() {
+	today := b.today()
+	bookings, err := b.db.GetBookingsByDateRange(context.Background(), today, today)
+	if err != nil {
+		log.Printf("Error getting bookings for daily digest: %v", err)
+		return
+	}
+
+	byItem := make(map[int64][]string)
+	total := 0
+	for _, booking := range bookings {
+		if booking.Status != "confirmed" {
+			continue
+		}
+		byItem[booking.ItemID] = append(byItem[booking.ItemID],
+			fmt.Sprintf("   👤 %s, 📱 %s (#%d)", booking.UserName, b.formatPhoneForDisplay(booking.Phone), booking.ID))
+		total++
+	}
+
+	if total == 0 {
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("☀️ Расписание на сегодня, %s\nПодтвержденных заявок: %d\n", today.Format(b.dateLayout()), total))
+
+	// Порядок аппаратов как в меню
+	for _, item := range b.getItems() {
+		lines, ok := byItem[item.ID]
+		if !ok {
+			continue
+		}
+		message.WriteString(fmt.Sprintf("\n🏢 %s (%d):\n", item.Name, len(lines)))
+		for _, line := range lines {
+			message.WriteString(line + "\n")
+		}
+	}
+
+	for _, managerID := range b.config.Managers {
+		b.sendMessage(managerID, message.String())
+	}
+}
+
+// reminderOffsets возвращает смещения напоминаний из notifications.reminder_offsets
+func (b *Bot) reminderOffsets() []time.Duration {
+	var offsets []time.Duration
+	for _, offset := range b.config.Notifications.ReminderOffsets {
+		duration, err := time.ParseDuration(offset)
+		if err != nil || duration <= 0 {
+			log.Printf("Invalid reminder offset %q: %v", offset, err)
+			continue
+		}
+		offsets = append(offsets, duration)
+	}
+	return offsets
+}
+
+// runReminderLoop раз в час отправляет клиентам напоминания о подтвержденных заявках
+func (b *Bot) runReminderLoop() {
+	for {
+		time.Sleep(b.timeUntilNextHour())
+		b.sendBookingReminders()
+	}
+}
+
+// sendBookingReminders отправляет напоминания, время которых наступило. Если к моменту проверки
+// наступило сразу несколько (например, бот был выключен), клиент получает одно сообщение.
+func (b *Bot) sendBookingReminders() {
+	offsets := b.reminderOffsets()
+	var maxOffset time.Duration
+	for _, offset := range offsets {
+		if offset > maxOffset {
+			maxOffset = offset
+		}
+	}
+
+	now := b.now()
+	today := b.today()
+	bookings, err := b.db.GetBookingsForReminders(context.Background(), today, now.Add(maxOffset).AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Error getting bookings for reminders: %v", err)
+		return
+	}
+
+	for _, booking := range bookings {
+		start := time.Date(booking.Date.Year(), booking.Date.Month(), booking.Date.Day(),
+			b.config.Notifications.BookingStartHour, 0, 0, 0, b.location)
+		if !now.Before(start) {
+			continue
+		}
+
+		reminded, err := b.db.GetRemindedOffsets(context.Background(), booking.ID)
+		if err != nil {
+			log.Printf("Error getting reminded offsets for booking %d: %v", booking.ID, err)
+			continue
+		}
+
+		var due []string
+		for _, offset := range offsets {
+			if now.Before(start.Add(-offset)) || slices.Contains(reminded, offset.String()) {
+				continue
+			}
+			due = append(due, offset.String())
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		for _, offset := range due {
+			if err := b.db.AddRemindedOffset(context.Background(), booking.ID, offset); err != nil {
+				log.Printf("Error marking reminder %s for booking %d: %v", offset, booking.ID, err)
+			}
+		}
+
+		b.sendBookingReminder(&booking)
+		log.Printf("Reminder sent for booking %d (offsets %s)", booking.ID, strings.Join(due, ", "))
+	}
+}
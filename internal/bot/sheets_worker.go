@@ -0,0 +1,242 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bronivik/internal/database"
+	"bronivik/internal/metrics"
+	"bronivik/internal/models"
+)
+
+// Типы задач синхронизации с Google Sheets
+const (
+	SheetTaskUpsertBooking = "upsert_booking"
+	SheetTaskUpdateStatus  = "update_status"
+	SheetTaskSyncBookings  = "sync_bookings"
+	SheetTaskSyncSchedule  = "sync_schedule"
+)
+
+const (
+	sheetsWorkerPollInterval = 2 * time.Second
+	sheetsWorkerBatchSize    = 10
+	// maxSheetTasksPending ограничивает очередь при длительной недоступности Google Sheets
+	maxSheetTasksPending    = 1000
+	maxSheetTaskAttempts    = 8
+	sheetTaskBaseBackoff    = 5 * time.Second
+	sheetTaskMaxBackoff     = 10 * time.Minute
+	sheetTasksDoneRetention = 7 * 24 * time.Hour
+)
+
+// SheetsWorker выполняет задачи синхронизации из таблицы sheet_tasks.
+// Задачи переживают перезапуск бота, неудачные повторяются с экспоненциальной задержкой.
+type SheetsWorker struct {
+	db      *database.DB
+	process func(task *models.SheetTask) error
+
+	stop     chan struct{} // закрывается в Shutdown
+	stopOnce sync.Once
+	done     chan struct{} // закрывается, когда Run завершился
+}
+
+// NewSheetsWorker создает воркер, process выполняет одну задачу
+func NewSheetsWorker(db *database.DB, process func(task *models.SheetTask) error) *SheetsWorker {
+	return &SheetsWorker{
+		db:      db,
+		process: process,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// EnqueueTask ставит задачу в очередь. Одинаковые ожидающие задачи объединяются.
+func (w *SheetsWorker) EnqueueTask(taskType string, bookingID int64, payload string) error {
+	ctx := context.Background()
+
+	depth, err := w.db.CountPendingSheetTasks(ctx)
+	if err != nil {
+		return err
+	}
+	if depth >= maxSheetTasksPending {
+		return fmt.Errorf("sheets queue is full (%d tasks)", depth)
+	}
+
+	created, err := w.db.EnqueueSheetTask(ctx, taskType, bookingID, payload)
+	if err != nil {
+		return err
+	}
+	if created {
+		metrics.SheetsQueueDepth.Set(float64(depth + 1))
+	}
+	return nil
+}
+
+// Run обрабатывает очередь, пока не вызван Shutdown
+func (w *SheetsWorker) Run() {
+	defer close(w.done)
+
+	if err := w.db.ReleaseClaimedSheetTasks(context.Background()); err != nil {
+		log.Printf("Error releasing claimed sheet tasks: %v", err)
+	}
+
+	ticker := time.NewTicker(sheetsWorkerPollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		w.processDueTasks()
+
+		if time.Since(lastCleanup) > time.Hour {
+			if err := w.db.DeleteDoneSheetTasks(context.Background(), time.Now().Add(-sheetTasksDoneRetention)); err != nil {
+				log.Printf("Error cleaning up sheet tasks: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+	}
+}
+
+// Shutdown останавливает воркер: новые задачи больше не берутся из очереди, а выполняемые
+// дожидаются завершения, но не дольше ctx. Невыполненные задачи остаются в sheet_tasks
+// и будут обработаны после следующего запуска.
+func (w *SheetsWorker) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	var err error
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if depth, countErr := w.db.CountPendingSheetTasks(context.Background()); countErr == nil && depth > 0 {
+		if err != nil {
+			log.Printf("Sheets worker shutdown timed out, %d tasks remain in the queue", depth)
+		} else {
+			log.Printf("Sheets worker stopped, %d tasks remain in the queue for the next start", depth)
+		}
+	}
+	return err
+}
+
+// processDueTasks выполняет задачи, время которых наступило
+func (w *SheetsWorker) processDueTasks() {
+	ctx := context.Background()
+
+	tasks, err := w.db.GetDueSheetTasks(ctx, sheetsWorkerBatchSize)
+	if err != nil {
+		log.Printf("Error getting sheet tasks: %v", err)
+		return
+	}
+
+	for i := range tasks {
+		// После Shutdown новые задачи не начинаются, они останутся в очереди
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		task := &tasks[i]
+
+		// Задача отмечается взятой до чтения заявки: изменения, сделанные во время обработки,
+		// попадут в новую задачу, а не сольются с этой
+		claimed, err := w.db.ClaimSheetTask(ctx, task.ID)
+		if err != nil {
+			log.Printf("Error claiming sheet task %d: %v", task.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := w.process(task); err != nil {
+			attempts := task.Attempts + 1
+			final := attempts >= maxSheetTaskAttempts
+			nextRetry := time.Now().Add(sheetTaskBackoff(attempts))
+
+			if final {
+				log.Printf("Sheet task %d (%s) failed permanently after %d attempts: %v", task.ID, task.Type, attempts, err)
+			} else {
+				log.Printf("Sheet task %d (%s) failed, attempt %d, retry at %s: %v",
+					task.ID, task.Type, attempts, nextRetry.Format("15:04:05"), err)
+			}
+
+			if err := w.db.MarkSheetTaskFailed(ctx, task.ID, nextRetry, err.Error(), final); err != nil {
+				log.Printf("Error updating sheet task %d: %v", task.ID, err)
+			}
+			continue
+		}
+
+		if err := w.db.MarkSheetTaskDone(ctx, task.ID); err != nil {
+			log.Printf("Error marking sheet task %d done: %v", task.ID, err)
+		}
+	}
+
+	if depth, err := w.db.CountPendingSheetTasks(ctx); err == nil {
+		metrics.SheetsQueueDepth.Set(float64(depth))
+	}
+}
+
+// sheetTaskBackoff задержка перед повтором: 5s, 10s, 20s ... но не больше 10 минут
+func sheetTaskBackoff(attempts int) time.Duration {
+	backoff := sheetTaskBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= sheetTaskMaxBackoff {
+			return sheetTaskMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// processSheetTask выполняет задачу синхронизации из очереди
+func (b *Bot) processSheetTask(task *models.SheetTask) error {
+	switch task.Type {
+	case SheetTaskUpsertBooking:
+		booking, err := b.db.GetBooking(context.Background(), task.BookingID)
+		if err != nil {
+			return err
+		}
+		return b.upsertBookingSheet(booking)
+	case SheetTaskUpdateStatus:
+		booking, err := b.db.GetBooking(context.Background(), task.BookingID)
+		if err != nil {
+			return err
+		}
+		return b.updateBookingStatusSheet(booking)
+	case SheetTaskSyncBookings:
+		return b.syncBookingsSheet()
+	case SheetTaskSyncSchedule:
+		return b.syncScheduleSheet()
+	default:
+		return fmt.Errorf("unknown sheet task type: %s", task.Type)
+	}
+}
+
+// enqueueSheetTask ставит задачу в очередь воркера. Без воркера задача выполняется сразу.
+func (b *Bot) enqueueSheetTask(taskType string, bookingID int64) {
+	if b.sheetsService == nil {
+		log.Println("Google Sheets service not initialized")
+		return
+	}
+
+	if b.sheetsWorker != nil {
+		if err := b.sheetsWorker.EnqueueTask(taskType, bookingID, ""); err != nil {
+			log.Printf("Failed to enqueue sheet task %s: %v", taskType, err)
+		}
+		return
+	}
+
+	if err := b.processSheetTask(&models.SheetTask{Type: taskType, BookingID: bookingID}); err != nil {
+		log.Printf("Sheet task %s failed: %v", taskType, err)
+	}
+}
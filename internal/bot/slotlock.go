@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// slotLockTTL снимает блокировку, если процесс упал, не освободив ее
+	slotLockTTL = 10 * time.Second
+	// slotLockWait сколько ждать освобождения занятой блокировки
+	slotLockWait       = 2 * time.Second
+	slotLockRetryDelay = 50 * time.Millisecond
+)
+
+var errSlotLockTimeout = errors.New("slot lock timeout")
+
+// slotUnlockScript удаляет ключ, только если блокировка все еще принадлежит нам
+var slotUnlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redisSlotLocker распределенная блокировка на пару (аппарат или общий ресурс, дата), чтобы проверка
+// доступности и создание заявки не выполнялись одновременно на нескольких экземплярах бота
+type redisSlotLocker struct {
+	client *redis.Client
+}
+
+func newRedisSlotLocker(client *redis.Client) *redisSlotLocker {
+	return &redisSlotLocker{client: client}
+}
+
+// Lock захватывает блокировку слота и возвращает функцию для ее освобождения
+func (l *redisSlotLocker) Lock(ctx context.Context, slot string, date time.Time) (func(), error) {
+	key := fmt.Sprintf("lock:booking:%s:%s", slot, date.Format("2006-01-02"))
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	deadline := time.Now().Add(slotLockWait)
+	for {
+		acquired, err := l.client.SetNX(ctx, key, token, slotLockTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, errSlotLockTimeout
+		}
+		time.Sleep(slotLockRetryDelay)
+	}
+
+	return func() {
+		if err := slotUnlockScript.Run(context.Background(), l.client, []string{key}, token).Err(); err != nil {
+			log.Printf("Error releasing slot lock %s: %v", key, err)
+		}
+	}, nil
+}
+
+// lockSlot блокирует слот перед проверкой доступности и созданием заявки.
+// Аппараты с общим ресурсом блокируются одним ключом, чтобы их нельзя было занять одновременно.
+// Без Redis или при его ошибке полагаемся на проверки в БД. false - слот занят другим запросом.
+func (b *Bot) lockSlot(itemID int64, date time.Time) (func(), bool) {
+	noop := func() {}
+	if b.slotLocker == nil {
+		return noop, true
+	}
+
+	slot := strconv.FormatInt(itemID, 10)
+	if item, ok := b.findItemByID(itemID); ok && item.SharedResource != "" {
+		slot = "shared:" + item.SharedResource
+	}
+
+	unlock, err := b.slotLocker.Lock(context.Background(), slot, date)
+	if errors.Is(err, errSlotLockTimeout) {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error acquiring slot lock for item %d on %s: %v", itemID, date.Format("2006-01-02"), err)
+		return noop, true
+	}
+
+	return unlock, true
+}
+
+// lockSlots блокирует все дни многодневной заявки. Если какой-то день занят,
+// уже захваченные блокировки освобождаются.
+func (b *Bot) lockSlots(itemID int64, startDate, endDate time.Time) (func(), bool) {
+	var unlocks []func()
+	unlockAll := func() {
+		for _, unlock := range unlocks {
+			unlock()
+		}
+	}
+
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		unlock, ok := b.lockSlot(itemID, date)
+		if !ok {
+			unlockAll()
+			return nil, false
+		}
+		unlocks = append(unlocks, unlock)
+	}
+
+	return unlockAll, true
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bronivik/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// renderManagerTemplate подставляет в шаблон manager_templates данные заявки
+func (b *Bot) renderManagerTemplate(text string, booking *models.Booking) string {
+	return strings.NewReplacer(
+		"{name}", booking.UserName,
+		"{item}", booking.ItemName,
+		"{date}", b.bookingPeriod(*booking),
+	).Replace(text)
+}
+
+// managerTemplateRows кнопки шаблонов для экрана "Позвонить": отправка клиенту через бота,
+// если у заявки есть Telegram клиента, и ссылка на WhatsApp с подставленным текстом
+func (b *Bot) managerTemplateRows(booking *models.Booking, internationalPhone string) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, template := range b.config.ManagerTemplates {
+		text := b.renderManagerTemplate(template.Text, booking)
+
+		var row []tgbotapi.InlineKeyboardButton
+		if hasClientChat(booking) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("✉️ "+template.Name,
+				fmt.Sprintf("call_template:%d:%d", booking.ID, i)))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonURL("💬 "+template.Name,
+			fmt.Sprintf("https://wa.me/%s?text=%s", internationalPhone, url.QueryEscape(text))))
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// handleManagerTemplate отправляет клиенту заявки сообщение по шаблону: call_template:<id заявки>:<номер шаблона>
+func (b *Bot) handleManagerTemplate(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if !b.isManager(callback.From.ID) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "call_template:"), ":")
+	if len(parts) != 2 {
+		return
+	}
+	bookingID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 || index >= len(b.config.ManagerTemplates) {
+		b.bot.Request(tgbotapi.NewCallback(callback.ID, "Шаблон не найден"))
+		return
+	}
+
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		b.bot.Request(tgbotapi.NewCallback(callback.ID, "❌ Заявка не найдена"))
+		return
+	}
+	if !hasClientChat(booking) {
+		b.bot.Request(tgbotapi.NewCallback(callback.ID, "У клиента нет Telegram в боте"))
+		return
+	}
+
+	text := b.renderManagerTemplate(b.config.ManagerTemplates[index].Text, booking)
+	b.notifyBookingClient(booking, text)
+	log.Printf("Manager %d sent template %q to client of booking %d", callback.From.ID, b.config.ManagerTemplates[index].Name, booking.ID)
+
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, "✅ Отправлено"))
+	b.sendMessage(callback.Message.Chat.ID, fmt.Sprintf("✉️ Клиенту заявки #%d отправлено:\n%s", booking.ID, text))
+}
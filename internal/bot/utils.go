@@ -2,38 +2,104 @@ package bot
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"bronivik/internal/database"
+	"bronivik/internal/events"
 	"bronivik/internal/models"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// Вспомогательные методы для работы с состояниями пользователей
+// Вспомогательные методы для работы с состояниями пользователей.
+// Состояния хранятся в памяти и дублируются в БД, чтобы пережить перезапуск бота.
+
+// userStateTTL время, после которого незавершенное состояние считается устаревшим
+const userStateTTL = time.Hour
+
+// maxUserCommentLength ограничивает комментарий клиента к заявке
+const maxUserCommentLength = 500
 
 func (b *Bot) setUserState(userID int64, step string, tempData map[string]interface{}) {
 	if tempData == nil {
 		tempData = make(map[string]interface{})
 	}
 
-	b.userStates[userID] = &models.UserState{
+	state := &models.UserState{
 		UserID:      userID,
 		CurrentStep: step,
 		TempData:    tempData,
+		UpdatedAt:   time.Now(),
+	}
+	b.userStatesMu.Lock()
+	b.userStates[userID] = state
+	b.userStatesMu.Unlock()
+
+	if err := b.db.SaveUserState(context.Background(), state); err != nil {
+		log.Printf("Error saving state for user %d: %v", userID, err)
 	}
 }
 
 func (b *Bot) getUserState(userID int64) *models.UserState {
-	return b.userStates[userID]
+	b.userStatesMu.RLock()
+	state, ok := b.userStates[userID]
+	b.userStatesMu.RUnlock()
+	if !ok {
+		// После перезапуска состояния в памяти нет - загружаем из БД
+		var err error
+		state, err = b.db.GetUserState(context.Background(), userID)
+		if err != nil {
+			log.Printf("Error loading state for user %d: %v", userID, err)
+			return nil
+		}
+		if state == nil {
+			return nil
+		}
+		b.userStatesMu.Lock()
+		b.userStates[userID] = state
+		b.userStatesMu.Unlock()
+	}
+
+	if time.Since(state.UpdatedAt) > userStateTTL {
+		b.clearUserState(userID)
+		return nil
+	}
+
+	return state
 }
 
 func (b *Bot) clearUserState(userID int64) {
+	b.userStatesMu.Lock()
 	delete(b.userStates, userID)
+	b.userStatesMu.Unlock()
+
+	if err := b.db.DeleteUserState(context.Background(), userID); err != nil {
+		log.Printf("Error deleting state for user %d: %v", userID, err)
+	}
 }
 
 func (b *Bot) isBlacklisted(userID int64) bool {
+	if b.isConfigBlacklisted(userID) {
+		return true
+	}
+
+	// Блокировки, выставленные менеджерами через /blacklist, хранятся в БД
+	blacklisted, err := b.db.IsUserBlacklisted(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error checking blacklist for user %d: %v", userID, err)
+		return false
+	}
+	return blacklisted
+}
+
+// isConfigBlacklisted проверяет blacklist из config.yaml; такую блокировку /unblacklist не снимает
+func (b *Bot) isConfigBlacklisted(userID int64) bool {
 	for _, blacklistedID := range b.config.Blacklist {
 		if userID == blacklistedID {
 			return true
@@ -56,6 +122,68 @@ func (b *Bot) sendMessage(chatID int64, text string) {
 	b.bot.Send(msg)
 }
 
+// notifyBookingClient отправляет клиенту заявки уведомление о действии менеджера. Заявки, оформленные
+// менеджером без Telegram клиента, пропускаются, чтобы уведомления не уходили самому менеджеру.
+// В тихие часы уведомление откладывается, если не включен quiet_hours.manager_bypass.
+func (b *Bot) notifyBookingClient(booking *models.Booking, text string) {
+	if !hasClientChat(booking) {
+		return
+	}
+	b.sendOrDeferNotification(booking.UserID, text, true)
+}
+
+// notifyBookingClientScheduled отправляет клиенту уведомление от фоновой задачи (напоминание,
+// истечение срока). В тихие часы оно всегда откладывается.
+func (b *Bot) notifyBookingClientScheduled(booking *models.Booking, text string) {
+	if !hasClientChat(booking) {
+		return
+	}
+	b.sendOrDeferNotification(booking.UserID, text, false)
+}
+
+// sendNotification отправляет уведомление, которое пользователь не запрашивал.
+// Пользователям, заблокировавшим бота, уведомления не отправляются.
+func (b *Bot) sendNotification(userID int64, msg tgbotapi.Chattable) {
+	blocked, err := b.db.IsUserBlockedBot(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error checking if user %d blocked the bot: %v", userID, err)
+	}
+	if blocked {
+		return
+	}
+
+	if _, err := b.bot.Send(msg); err != nil {
+		b.handleSendError(userID, err)
+	}
+}
+
+// handleSendError отмечает пользователя, заблокировавшего бота, остальные ошибки только логирует
+func (b *Bot) handleSendError(userID int64, err error) {
+	if !isBotBlockedError(err) {
+		log.Printf("Error sending message to %d: %v", userID, err)
+		return
+	}
+
+	log.Printf("User %d blocked the bot, notifications disabled", userID)
+	if err := b.db.MarkUserBlockedBot(context.Background(), userID); err != nil {
+		log.Printf("Error marking user %d as blocked: %v", userID, err)
+	}
+}
+
+// isBotBlockedError проверяет ответ Telegram 403 "bot was blocked by the user"
+func isBotBlockedError(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == 403 && strings.Contains(apiErr.Message, "bot was blocked by the user")
+}
+
+// hasClientChat проверяет, что у заявки есть Telegram клиента, отличный от менеджера
+func hasClientChat(booking *models.Booking) bool {
+	return booking.UserID != 0 && booking.UserID != booking.CreatedByManagerID
+}
+
 // handleMainMenu - главное меню с контактами
 func (b *Bot) handleMainMenu(update tgbotapi.Update) {
 	var userID int64
@@ -74,25 +202,32 @@ func (b *Bot) handleMainMenu(update tgbotapi.Update) {
 	}
 
 	b.updateUserActivity(userID)
+	b.sendMainMenu(userID, chatID)
+}
 
-	msg := tgbotapi.NewMessage(chatID,
-		"Добро пожаловать! Выберите действие:")
+// sendMainMenu отправляет главное меню и переводит пользователя в состояние главного меню
+func (b *Bot) sendMainMenu(userID, chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, b.t(userID, "menu_welcome"))
+	if welcome := b.config.Messages.Welcome; welcome != "" {
+		msg.Text = welcome
+		msg.ParseMode = "Markdown"
+	}
 
 	var rows [][]tgbotapi.KeyboardButton
 
 	// Основные кнопки для всех пользователей
 	if !b.isManager(userID) {
 		rows = append(rows, tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("📋 СОЗДАТЬ ЗАЯВКУ"),
+			tgbotapi.NewKeyboardButton(b.t(userID, "btn_create_booking")),
 		))
 		rows = append(rows, tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("📅 Посмотреть расписание"),
-			tgbotapi.NewKeyboardButton("💼 Ассортимент"),
+			tgbotapi.NewKeyboardButton(b.t(userID, "btn_view_schedule")),
+			tgbotapi.NewKeyboardButton(b.t(userID, "btn_items")),
 		))
 
 		rows = append(rows, tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("📊 Мои заявки"),
-			tgbotapi.NewKeyboardButton("📞 Контакты менеджеров"),
+			tgbotapi.NewKeyboardButton(b.t(userID, "btn_my_bookings")),
+			tgbotapi.NewKeyboardButton(b.t(userID, "btn_manager_contacts")),
 		))
 	}
 
@@ -116,56 +251,312 @@ func (b *Bot) handleMainMenu(update tgbotapi.Update) {
 	b.bot.Send(msg)
 }
 
+// handleWhoAmI показывает пользователю его профиль в том виде, в каком он сохранен в БД.
+// Нужен поддержке, чтобы узнать Telegram ID и флаги пользователя.
+func (b *Bot) handleWhoAmI(update tgbotapi.Update) {
+	userID := update.Message.From.ID
+
+	user, err := b.db.GetUserByTelegramID(context.Background(), userID)
+	if err == sql.ErrNoRows {
+		b.sendMessage(update.Message.Chat.ID,
+			fmt.Sprintf("🆔 Ваш Telegram ID: %d\n\nПрофиль еще не сохранен. Отправьте /start.", userID))
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user %d: %v", userID, err)
+		b.sendMessage(update.Message.Chat.ID, "Ошибка при загрузке профиля")
+		return
+	}
+
+	phone := "не указан"
+	if user.Phone != "" {
+		phone = maskPhone(b.formatPhoneForDisplay(user.Phone))
+	}
+
+	yesNo := func(flag bool) string {
+		if flag {
+			return "да"
+		}
+		return "нет"
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("🆔 Telegram ID: %d\n", user.TelegramID))
+	message.WriteString(fmt.Sprintf("👤 Имя: %s\n", strings.TrimSpace(user.FirstName+" "+user.LastName)))
+	if user.Username != "" {
+		message.WriteString(fmt.Sprintf("🔗 Username: @%s\n", user.Username))
+	}
+	message.WriteString(fmt.Sprintf("📱 Телефон: %s\n", phone))
+	message.WriteString(fmt.Sprintf("👨‍💼 Менеджер: %s\n", yesNo(user.IsManager)))
+	message.WriteString(fmt.Sprintf("🚫 В черном списке: %s\n", yesNo(user.IsBlacklisted)))
+	message.WriteString(fmt.Sprintf("🕐 Последняя активность: %s", user.LastActivity.Format("02.01.2006 15:04")))
+
+	b.sendMessage(update.Message.Chat.ID, message.String())
+}
+
 // showManagerContacts показывает контакты менеджеров
 func (b *Bot) showManagerContacts(update tgbotapi.Update) {
 	contacts := b.config.ManagersContacts
+	header := b.config.Messages.ContactsHeader
+	if header == "" {
+		header = "📞 Контакты менеджера:"
+	}
+
 	var message strings.Builder
-	message.WriteString("📞 Контакты менеджера:\n\n")
+	message.WriteString(header + "\n\n")
 	for _, contact := range contacts {
-		message.WriteString(fmt.Sprintf("🔹 %s\n", contact))
+		// Контакты экранируются: в никнеймах часто встречается "_"
+		message.WriteString(fmt.Sprintf("🔹 %s\n", tgbotapi.EscapeText(tgbotapi.ModeMarkdown, contact)))
 	}
 	message.WriteString("\nПо любым интересующим Вас вопросам, дадим ответ.")
 
 	msg := tgbotapi.NewMessage(update.Message.Chat.ID, message.String())
+	msg.ParseMode = "Markdown"
 	b.bot.Send(msg)
 }
 
-// showUserBookings показывает заявки пользователя
+// upcomingFirst упорядочивает заявки пользователя: текущие и предстоящие по возрастанию даты,
+// затем прошедшие от новых к старым. Без includePast прошедшие отбрасываются.
+func upcomingFirst(bookings []models.Booking, today time.Time, includePast bool) []models.Booking {
+	var upcoming, past []models.Booking
+	for _, booking := range bookings {
+		if booking.LastDate().Before(today) {
+			past = append(past, booking)
+		} else {
+			upcoming = append(upcoming, booking)
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Date.Before(upcoming[j].Date)
+	})
+	if !includePast {
+		return upcoming
+	}
+
+	sort.SliceStable(past, func(i, j int) bool {
+		return past[i].Date.After(past[j].Date)
+	})
+	return append(upcoming, past...)
+}
+
+// showUserBookings показывает заявки пользователя, по умолчанию только текущие и предстоящие
 func (b *Bot) showUserBookings(update tgbotapi.Update) {
-	bookings, err := b.db.GetUserBookings(context.Background(), update.Message.From.ID)
+	b.sendUserBookingsPage(update.Message.Chat.ID, 0, update.Message.From.ID, 0, false)
+}
+
+// handleUserBookingsPage переключает страницу или видимость прошедших заявок в списке пользователя.
+// Формат callback: user_bookings:<1 - показывать прошедшие>:<страница>
+func (b *Bot) handleUserBookingsPage(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "user_bookings:"), ":")
+	if len(parts) != 2 {
+		return
+	}
+
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		log.Printf("Error parsing page: %v", err)
+		return
+	}
+
+	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	b.sendUserBookingsPage(callback.Message.Chat.ID, callback.Message.MessageID, callback.From.ID, page, parts[0] == "1")
+}
+
+// sendUserBookingsPage отправляет страницу заявок пользователя, ближайшие сверху (см. upcomingFirst).
+// Если messageID не 0, редактирует уже открытый список.
+func (b *Bot) sendUserBookingsPage(chatID int64, messageID int, userID int64, page int, showPast bool) {
+	allBookings, err := b.db.GetBookingsByUserID(context.Background(), userID)
 	if err != nil {
 		log.Printf("Error getting user bookings: %v", err)
-		b.sendMessage(update.Message.Chat.ID, "Ошибка при получении заявок")
+		b.sendMessage(chatID, "Ошибка при получении заявок")
 		return
 	}
 
-	var message strings.Builder
-	message.WriteString("📊 Ваши заявки (за последние 2 недели и предстоящие):\n\n")
+	title := "📊 Ваши заявки (предстоящие):"
+	if showPast {
+		title = "📊 Ваши заявки (все):"
+	}
+	bookings := upcomingFirst(allBookings, b.today(), showPast)
 
-	for _, booking := range bookings {
-		statusEmoji := "⏳"
-		switch booking.Status {
-		case "confirmed":
-			statusEmoji = "✅"
-		case "cancelled":
-			statusEmoji = "❌"
-		case "changed":
-			statusEmoji = "🔄"
-		case "completed":
-			statusEmoji = "🏁"
+	text, page, totalPages := b.renderPaginatedBookings(title, bookings, page, false)
+
+	pastFlag := "0"
+	if showPast {
+		pastFlag = "1"
+	}
+
+	// Кнопки отмены для активных заявок на странице
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, booking := range bookingsOnPage(bookings, page) {
+		if booking.Status == "pending" || booking.Status == "awaiting_approval" || booking.Status == "confirmed" {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(
+					fmt.Sprintf("❌ Отменить #%d (%s)", booking.ID, booking.Date.Format(b.dateLayout())),
+					fmt.Sprintf("user_cancel:%d", booking.ID)),
+			))
 		}
+	}
+	if navRow := paginationRow("user_bookings:"+pastFlag+":", page, totalPages); navRow != nil {
+		rows = append(rows, navRow)
+	}
 
-		message.WriteString(fmt.Sprintf("%s Заявка #%d\n", statusEmoji, booking.ID))
-		message.WriteString(fmt.Sprintf("   🏢 %s\n", booking.ItemName))
-		message.WriteString(fmt.Sprintf("   📅 %s\n", booking.Date.Format("02.01.2006")))
-		message.WriteString(fmt.Sprintf("   📊 Статус: %s\n\n", booking.Status))
+	toggleText, toggleFlag := "🗂 Все", "1"
+	if showPast {
+		toggleText, toggleFlag = "📅 Предстоящие", "0"
 	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(toggleText, "user_bookings:"+toggleFlag+":0"),
+	))
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
-	if len(bookings) == 0 {
-		message.WriteString("У вас пока нет заявок")
+	if messageID != 0 {
+		b.bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup))
+		return
 	}
 
-	b.sendMessage(update.Message.Chat.ID, message.String())
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = &markup
+	b.bot.Send(msg)
+}
+
+// handleUserCancelBooking отмена заявки самим пользователем
+func (b *Bot) handleUserCancelBooking(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	if callback == nil {
+		return
+	}
+
+	bookingID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "user_cancel:"), 10, 64)
+	if err != nil {
+		log.Printf("Error parsing booking ID: %v", err)
+		return
+	}
+
+	b.cancelBookingByUser(callback, bookingID)
+}
+
+// cancelBookingByUser отменяет заявку по кнопке клиента («📊 Мои заявки» или напоминание).
+// Клиент может отменить только свою заявку.
+func (b *Bot) cancelBookingByUser(callback *tgbotapi.CallbackQuery, bookingID int64) {
+	booking, err := b.db.GetBooking(context.Background(), bookingID)
+	if err != nil {
+		b.sendMessage(callback.Message.Chat.ID, "❌ Заявка не найдена")
+		return
+	}
+
+	// Пользователь может отменить только свою заявку
+	if booking.UserID != callback.From.ID {
+		log.Printf("User %d tried to cancel booking %d of user %d", callback.From.ID, booking.ID, booking.UserID)
+		b.sendMessage(callback.Message.Chat.ID, "❌ Заявка не найдена")
+		return
+	}
+
+	switch booking.Status {
+	case "completed":
+		b.sendMessage(callback.Message.Chat.ID, "Заявка уже завершена, её нельзя отменить.")
+		return
+	case "cancelled":
+		b.sendMessage(callback.Message.Chat.ID, "Заявка уже отменена.")
+		return
+	}
+
+	err = b.db.UpdateBookingStatusWithVersion(context.Background(), booking.ID, booking.Version, "cancelled", 0)
+	if errors.Is(err, database.ErrConcurrentModification) {
+		b.sendMessage(callback.Message.Chat.ID, "Заявка была изменена менеджером. Откройте «📊 Мои заявки» ещё раз.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error cancelling booking by user: %v", err)
+		b.sendMessage(callback.Message.Chat.ID, "Ошибка при отмене заявки. Попробуйте позже.")
+		return
+	}
+
+	b.sendMessage(callback.Message.Chat.ID,
+		fmt.Sprintf("❌ Заявка #%d на %s %s отменена.", booking.ID, booking.ItemName, b.bookingPeriod(*booking)))
+
+	booking.Status = "cancelled"
+	b.publishBookingEvent(events.EventBookingCancelled, booking)
+
+	// Уведомляем менеджеров
+	message := fmt.Sprintf("❌ Клиент отменил заявку #%d\n\n🏢 Позиция: %s\n📅 Дата: %s\n👤 Клиент: %s\n📱 Телефон: %s",
+		booking.ID, booking.ItemName, b.bookingPeriod(*booking), booking.UserName, booking.Phone)
+	for _, managerID := range b.config.Managers {
+		b.sendMessage(managerID, message)
+	}
+
+	b.notifyWaitlist(booking.ItemID, booking.Date)
+
+	// СИНХРОНИЗИРУЕМ ИЗМЕНЕНИЯ
+	go func() {
+		time.Sleep(1 * time.Second) // Небольшая задержка для завершения операции в БД
+		b.SyncBookingsToSheets()
+	}()
+}
+
+// handleWaitlistJoin ставит пользователя в очередь на занятую дату
+func (b *Bot) handleWaitlistJoin(update tgbotapi.Update) {
+	callback := update.CallbackQuery
+
+	if !b.config.Booking.AllowWaitlist {
+		return
+	}
+
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 3 {
+		return
+	}
+
+	itemID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		log.Printf("Error parsing waitlist item id: %v", err)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", parts[2])
+	if err != nil {
+		log.Printf("Error parsing waitlist date: %v", err)
+		return
+	}
+
+	if err := b.db.AddToWaitlist(context.Background(), callback.From.ID, itemID, date); err != nil {
+		log.Printf("Error adding user %d to waitlist: %v", callback.From.ID, err)
+		b.sendMessage(callback.Message.Chat.ID, "Ошибка при постановке в очередь. Попробуйте позже.")
+		return
+	}
+
+	b.sendMessage(callback.Message.Chat.ID,
+		fmt.Sprintf("⏳ Вы в очереди на %s. Мы сообщим, если место освободится.", date.Format(b.dateLayout())))
+}
+
+// notifyWaitlist сообщает первому в очереди, что на дату освободилось место
+func (b *Bot) notifyWaitlist(itemID int64, date time.Time) {
+	if !b.config.Booking.AllowWaitlist {
+		return
+	}
+
+	entry, err := b.db.PopWaitlist(context.Background(), itemID, date)
+	if err != nil {
+		log.Printf("Error reading waitlist: %v", err)
+		return
+	}
+	if entry == nil {
+		return
+	}
+
+	itemName := ""
+	for _, item := range b.getItems() {
+		if item.ID == itemID {
+			itemName = item.Name
+			break
+		}
+	}
+
+	b.sendOrDeferNotification(entry.UserID,
+		fmt.Sprintf("🔔 Освободилось место: %s на %s.\nСоздайте заявку через «📋 СОЗДАТЬ ЗАЯВКУ», пока дату не заняли.",
+			itemName, date.Format(b.dateLayout())), false)
 }
 
 // Обновляем handlePersonalData - добавляем запрос имени
@@ -197,6 +588,7 @@ func (b *Bot) handlePersonalData(update tgbotapi.Update, itemID int64, date time
 		),
 		tgbotapi.NewKeyboardButtonRow(
 			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(b.t(update.Message.From.ID, "btn_main_menu")),
 		),
 	)
 	msg.ReplyMarkup = keyboard
@@ -218,6 +610,7 @@ func (b *Bot) handleNameRequest(update tgbotapi.Update) {
 		),
 		tgbotapi.NewKeyboardButtonRow(
 			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(b.t(update.Message.From.ID, "btn_main_menu")),
 		),
 	)
 	msg.ReplyMarkup = keyboard
@@ -235,7 +628,7 @@ func (b *Bot) handlePhoneRequest(update tgbotapi.Update) {
 	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
 		"Пожалуйста, предоставьте ваш номер телефона для связи:\n"+
 			"Вы можете предоставить разрешение на использование номера из контакта телеграмм\n"+
-			"Либо введите номер телефона для связи")
+			"Либо введите номер телефона для связи. Запасной номер можно указать через запятую")
 
 	keyboard := tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
@@ -247,6 +640,7 @@ func (b *Bot) handlePhoneRequest(update tgbotapi.Update) {
 		),
 		tgbotapi.NewKeyboardButtonRow(
 			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(b.t(update.Message.From.ID, "btn_main_menu")),
 		),
 	)
 	msg.ReplyMarkup = keyboard
@@ -259,17 +653,47 @@ func (b *Bot) handlePhoneRequest(update tgbotapi.Update) {
 
 // Обновляем finalizeBooking для использования имени
 func (b *Bot) finalizeBooking(update tgbotapi.Update) {
-	state := b.getUserState(update.Message.From.ID)
+	userID := update.Message.From.ID
+	state := b.getUserState(userID)
 	if state == nil {
-		b.sendMessage(update.Message.Chat.ID, "Сессия устарела. Начните заново.")
+		b.sendMessage(update.Message.Chat.ID, b.t(userID, "err_session_expired"))
 		b.handleMainMenu(update)
 		return
 	}
 
+	if !b.allowBooking(userID) {
+		b.sendMessage(update.Message.Chat.ID, b.t(userID, "err_too_many_requests"))
+		return
+	}
+
+	// Лимит активных заявок на клиента, менеджеры его не учитывают
+	if limit := b.config.Booking.MaxActivePerUser; limit > 0 && !b.isManager(userID) {
+		activeCount, err := b.db.CountActiveUserBookings(context.Background(), userID)
+		if err != nil {
+			log.Printf("Error counting active bookings for user %d: %v", userID, err)
+		} else if activeCount >= limit {
+			b.sendMessage(update.Message.Chat.ID, b.t(userID, "err_active_limit", activeCount))
+			b.clearUserState(userID)
+			b.handleMainMenu(update)
+			return
+		}
+	}
+
 	// Получаем данные из состояния
-	itemID := state.TempData["item_id"].(int64)
-	date := state.TempData["date"].(time.Time)
-	phone := state.TempData["phone"].(string)
+	itemID, okItem := state.GetInt64("item_id")
+	date, okDate := state.GetTime("date")
+	phone, okPhone := state.GetString("phone")
+	if !okItem || !okDate || !okPhone {
+		b.sendMessage(update.Message.Chat.ID, b.t(userID, "err_session_expired"))
+		b.clearUserState(userID)
+		b.handleMainMenu(update)
+		return
+	}
+	endDate, _ := state.TempData["end_date"].(time.Time)
+	secondaryPhone, _ := state.TempData["secondary_phone"].(string)
+	comment, _ := state.TempData["comment"].(string)
+	attachmentFileID, _ := state.TempData["attachment_file_id"].(string)
+	attachmentType, _ := state.TempData["attachment_type"].(string)
 	userName, ok := state.TempData["user_name"].(string)
 	if !ok {
 		// Если имя не было введено, используем имя из Telegram
@@ -278,7 +702,7 @@ func (b *Bot) finalizeBooking(update tgbotapi.Update) {
 
 	// Находим элемент по ID
 	var selectedItem models.Item
-	for _, item := range b.items {
+	for _, item := range b.getItems() {
 		if item.ID == itemID {
 			selectedItem = item
 			break
@@ -291,52 +715,73 @@ func (b *Bot) finalizeBooking(update tgbotapi.Update) {
 		return
 	}
 
+	// Создаем бронирование
+	booking := models.Booking{
+		UserID:           update.Message.From.ID,
+		UserName:         userName,
+		UserNickname:     update.Message.From.FirstName + " " + update.Message.From.LastName,
+		Phone:            phone,
+		SecondaryPhone:   secondaryPhone,
+		ItemID:           selectedItem.ID,
+		ItemName:         selectedItem.Name,
+		Date:             date,
+		EndDate:          endDate,
+		Status:           "pending",
+		Comment:          comment,
+		Source:           models.BookingSourceUser,
+		AttachmentFileID: attachmentFileID,
+		AttachmentType:   attachmentType,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	// Цена аппарата указана за день, многодневная заявка стоит пропорционально дольше
+	booking.Price = selectedItem.Price * int64(booking.Days())
+
+	// Проверка доступности и создание заявки выполняются под блокировкой всех дней периода
+	unlock, ok := b.lockSlots(selectedItem.ID, booking.Date, booking.LastDate())
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(userID, "err_slot_busy"))
+		return
+	}
+
+	// Повторное подтверждение той же заявки (двойное нажатие) не должно создавать дубликат
+	existing, err := b.db.FindActiveBooking(context.Background(), userID, selectedItem.ID, booking.Date)
+	if err != nil {
+		log.Printf("Error checking duplicate booking for user %d: %v", userID, err)
+	} else if existing != nil {
+		unlock()
+		b.sendMessage(update.Message.Chat.ID, b.t(userID, "err_duplicate_booking"))
+		b.clearUserState(userID)
+		b.handleMainMenu(update)
+		return
+	}
+
 	// Финальная проверка доступности
-	available, err := b.db.CheckAvailability(context.Background(), selectedItem.ID, date)
+	available, err := b.db.CheckAvailabilityForPeriod(context.Background(), selectedItem.ID, booking.Date, booking.LastDate(), 0)
 	if err != nil || !available {
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-			"К сожалению, выбранная позиция больше не доступна. Пожалуйста, выберите другую дату.")
+		unlock()
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.t(userID, "booking_no_longer_free"))
 		b.bot.Send(msg)
 		b.handleMainMenu(update)
 		return
 	}
 
-	// Создаем бронирование
-	booking := models.Booking{
-		UserID:       update.Message.From.ID,
-		UserName:     userName,
-		UserNickname: update.Message.From.FirstName + " " + update.Message.From.LastName,
-		Phone:        phone,
-		ItemID:       selectedItem.ID,
-		ItemName:     selectedItem.Name,
-		Date:         date,
-		Status:       "pending",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
 	err = b.db.CreateBooking(context.Background(), &booking)
+	unlock()
 	if err != nil {
 		log.Printf("Error creating booking: %v", err)
-		b.sendMessage(update.Message.Chat.ID, "Произошла ошибка при создании заявки. Попробуйте позже.")
+		b.sendMessage(update.Message.Chat.ID, b.t(userID, "err_booking_create"))
 		return
 	}
 
 	// Уведомляем менеджеров
 	b.notifyManagers(booking)
+	b.publishBookingEvent(events.EventBookingCreated, &booking)
 
-	if b.sheetsService != nil {
-		err := b.sheetsService.AppendBooking(&booking)
-		if err != nil {
-			log.Printf("Failed to sync booking to Google Sheets: %v", err)
-			// Не прерываем выполнение, просто логируем ошибку
-		} else {
-			log.Printf("Booking synced to Google Sheets: %d", booking.ID)
-		}
-	}
+	b.AppendBookingToSheets(&booking)
 
 	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-		fmt.Sprintf("⏳ Ваша заявка #%d на позицию %s успешно создана. \nОжидайте подтверждения.", booking.ID, booking.ItemName))
+		b.t(userID, "booking_created", booking.ID, booking.ItemName))
 
 	go func() {
 		time.Sleep(1 * time.Second) // Небольшая задержка для завершения операции в БД
@@ -378,18 +823,19 @@ func (b *Bot) handleViewSchedule(update tgbotapi.Update) {
 
 // sendScheduleItemsPage отправляет страницу с аппаратами для просмотра расписания
 func (b *Bot) sendScheduleItemsPage(chatID, userID int64, page int) {
+	items := b.getItems()
 	itemsPerPage := 8
 	startIdx := page * itemsPerPage
 	endIdx := startIdx + itemsPerPage
-	if endIdx > len(b.items) {
-		endIdx = len(b.items)
+	if endIdx > len(items) {
+		endIdx = len(items)
 	}
 
 	var message strings.Builder
 	message.WriteString("🏢 *Выберите аппарат для просмотра расписания:*\n\n")
-	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(b.items)+itemsPerPage-1)/itemsPerPage))
+	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(items)+itemsPerPage-1)/itemsPerPage))
 
-	currentItems := b.items[startIdx:endIdx]
+	currentItems := items[startIdx:endIdx]
 	for i, item := range currentItems {
 		message.WriteString(fmt.Sprintf("%d. *%s*\n", i+1, item.Name))
 		if item.Description != "" {
@@ -415,7 +861,7 @@ func (b *Bot) sendScheduleItemsPage(chatID, userID int64, page int) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("schedule_items_page:%d", page-1)))
 	}
 
-	if endIdx < len(b.items) {
+	if endIdx < len(items) {
 		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("schedule_items_page:%d", page+1)))
 	}
 
@@ -471,23 +917,46 @@ func (b *Bot) handleSelectItem(update tgbotapi.Update) {
 	b.sendItemsPage(chatID, userID, 0)
 }
 
-// sendItemsPage отправляет страницу с аппаратами
+// sendItemsPage отправляет страницу с аппаратами. Если у аппаратов заданы категории,
+// сначала показывается список категорий.
 func (b *Bot) sendItemsPage(chatID, userID int64, page int) {
+	var text string
+	var markup tgbotapi.InlineKeyboardMarkup
+	if b.hasItemCategories() {
+		text, markup = b.categoriesPage()
+	} else {
+		text, markup = itemsPage(b.bookableItems(), page, "items_page:", false)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = &markup
+	msg.ParseMode = "Markdown"
+
+	b.bot.Send(msg)
+}
+
+// itemsPage формирует текст и клавиатуру страницы аппаратов.
+// pagePrefix - префикс callback для кнопок навигации, к нему добавляется номер страницы.
+func itemsPage(items []models.Item, page int, pagePrefix string, backToCategories bool) (string, tgbotapi.InlineKeyboardMarkup) {
 	itemsPerPage := 8 // Количество аппаратов на странице
 	startIdx := page * itemsPerPage
+	if startIdx > len(items) {
+		startIdx = 0
+		page = 0
+	}
 	endIdx := startIdx + itemsPerPage
-	if endIdx > len(b.items) {
-		endIdx = len(b.items)
+	if endIdx > len(items) {
+		endIdx = len(items)
 	}
 
 	var message strings.Builder
 	message.WriteString("🏢 *Доступные аппараты*\n\n")
-	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(b.items)+itemsPerPage-1)/itemsPerPage))
+	message.WriteString(fmt.Sprintf("Страница %d из %d\n\n", page+1, (len(items)+itemsPerPage-1)/itemsPerPage))
 
 	// Текущие аппараты на странице
-	currentItems := b.items[startIdx:endIdx]
+	currentItems := items[startIdx:endIdx]
 	for i, item := range currentItems {
-		message.WriteString(fmt.Sprintf("%d. *%s*\n", startIdx+i+1, item.Name))
+		message.WriteString(fmt.Sprintf("%d. *%s*%s\n", startIdx+i+1, item.Name, photoMark(item)))
 		message.WriteString(fmt.Sprintf("   📝 %s\n", item.Description))
 	}
 
@@ -507,29 +976,69 @@ func (b *Bot) sendItemsPage(chatID, userID int64, page int) {
 	var navButtons []tgbotapi.InlineKeyboardButton
 
 	if page > 0 {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("items_page:%d", page-1)))
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("%s%d", pagePrefix, page-1)))
 	}
 
-	if endIdx < len(b.items) {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("items_page:%d", page+1)))
+	if endIdx < len(items) {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("%s%d", pagePrefix, page+1)))
 	}
 
 	if len(navButtons) > 0 {
 		keyboard = append(keyboard, navButtons)
 	}
 
+	if backToCategories {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К категориям", "item_categories"),
+		})
+	}
+
 	// Кнопка возврата
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад в меню", "back_to_main"),
 	})
 
-	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return message.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+}
 
-	msg := tgbotapi.NewMessage(chatID, message.String())
-	msg.ReplyMarkup = &markup
-	msg.ParseMode = "Markdown"
+// SetEventPublisher задает получателя событий по заявкам
+func (b *Bot) SetEventPublisher(publisher events.EventPublisher) {
+	b.events = publisher
+}
 
-	b.bot.Send(msg)
+// publishBookingEvent отправляет событие по заявке, если настроен получатель
+func (b *Bot) publishBookingEvent(eventType string, booking *models.Booking) {
+	if b.events == nil {
+		return
+	}
+	if err := b.events.PublishJSON(eventType, booking); err != nil {
+		log.Printf("Error publishing event %s for booking %d: %v", eventType, booking.ID, err)
+	}
+}
+
+// photoMark отмечает в списке аппараты, у которых есть фото
+func photoMark(item models.Item) string {
+	if item.PhotoURL == "" {
+		return ""
+	}
+	return " 📷"
+}
+
+// sendItemPhoto отправляет фото аппарата с описанием, если фото задано в items.yaml
+func (b *Bot) sendItemPhoto(chatID int64, item models.Item) {
+	if item.PhotoURL == "" {
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(item.PhotoURL))
+	photo.Caption = item.Name
+	if item.Description != "" {
+		photo.Caption += "\n\n" + item.Description
+	}
+
+	if _, err := b.bot.Send(photo); err != nil {
+		log.Printf("Error sending photo for item %d: %v", item.ID, err)
+	}
 }
 
 // showAvailableItems показывает доступные позиции
@@ -537,7 +1046,7 @@ func (b *Bot) showAvailableItems(update tgbotapi.Update) {
 	var message strings.Builder
 	message.WriteString("🏢 Доступные позиции:\n\n")
 
-	for _, item := range b.items {
+	for _, item := range b.getItems() {
 		message.WriteString(fmt.Sprintf("🔹 %s\n", item.Name))
 		if item.Description != "" {
 			message.WriteString(fmt.Sprintf("   📝 %s\n", item.Description))
@@ -567,8 +1076,14 @@ func (b *Bot) showMonthScheduleForItem(update tgbotapi.Update) {
 		return
 	}
 
-	selectedItem := state.TempData["selected_item"].(models.Item)
-	startDate := time.Now()
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		b.handleMainMenu(update)
+		return
+	}
+	startDate := b.today()
 
 	availability, err := b.db.GetAvailabilityForPeriod(context.Background(), selectedItem.ID, startDate, 30)
 	if err != nil {
@@ -583,12 +1098,16 @@ func (b *Bot) showMonthScheduleForItem(update tgbotapi.Update) {
 
 	message.WriteString("```\n")
 	message.WriteString("Дата     Статус\n")
-	message.WriteString("───────  ──────────\n")
+	message.WriteString("───────  ──────────────\n")
 
 	for _, avail := range availability {
-		status := "✅ Свободно"
-		if avail.Available == 0 {
-			status = "❌ Занято  "
+		status := fmt.Sprintf("✅ %d/%d свободно", avail.Available, selectedItem.TotalQuantity)
+		if b.isClosedDay(avail.Date) {
+			status = "🚫 Выходной"
+		} else if b.inMaintenance(selectedItem.ID, avail.Date) {
+			status = "🔧 На обслуживании"
+		} else if avail.Available == 0 {
+			status = "❌ Занято"
 		}
 
 		message.WriteString(fmt.Sprintf("%s   %s\n",
@@ -621,12 +1140,17 @@ func (b *Bot) handleSpecificDateInput(update tgbotapi.Update, dateStr string) {
 		return
 	}
 
-	selectedItem := state.TempData["selected_item"].(models.Item)
+	selectedItem, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		b.handleMainMenu(update)
+		return
+	}
 
-	date, err := time.Parse("02.01.2006", dateStr)
+	date, err := b.parseDate(dateStr)
 	if err != nil {
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-			"Неверный формат даты. Используйте ДД.ММ.ГГГГ (например, 25.12.2024)")
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
 		b.bot.Send(msg)
 		return
 	}
@@ -644,11 +1168,22 @@ func (b *Bot) handleSpecificDateInput(update tgbotapi.Update, dateStr string) {
 	}
 
 	booked, _ := b.db.GetBookedCount(context.Background(), selectedItem.ID, date)
-	message := fmt.Sprintf("📅 Доступность *%s* на %s:\n\n%s\n\nЗабронировано: %d/%d",
+	free := selectedItem.TotalQuantity - int64(booked)
+	if free < 0 {
+		free = 0
+	}
+	if b.isClosedDay(date) {
+		status = "🚫 Выходной"
+		free = 0
+	} else if b.inMaintenance(selectedItem.ID, date) {
+		status = "🔧 На обслуживании"
+		free = 0
+	}
+	message := fmt.Sprintf("📅 Доступность *%s* на %s:\n\n%s\n\nСвободно: %d/%d",
 		selectedItem.Name,
-		date.Format("02.01.2006"),
+		date.Format(b.dateLayout()),
 		status,
-		booked,
+		free,
 		selectedItem.TotalQuantity)
 
 	var keyboard [][]tgbotapi.InlineKeyboardButton
@@ -669,7 +1204,7 @@ func (b *Bot) handleSpecificDateInput(update tgbotapi.Update, dateStr string) {
 // requestSpecificDate запрашивает у пользователя конкретную дату
 func (b *Bot) requestSpecificDate(update tgbotapi.Update) {
 	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-		"Введите дату в формате ДД.ММ.ГГГГ (например, 25.12.2025):")
+		fmt.Sprintf("Введите дату в формате %s:", b.dateFormatHint(update.Message.From.ID)))
 	b.debugState(update.Message.Chat.ID, "DEBUG: requestSpecificDate START")
 
 	state := b.getUserState(update.Message.From.ID)
@@ -691,58 +1226,258 @@ func (b *Bot) handleCustomInput(update tgbotapi.Update, state *models.UserState)
 func (b *Bot) handleDateInput(update tgbotapi.Update, dateStr string, state *models.UserState) {
 	b.debugState(update.Message.From.ID, "handleDateInput START")
 
-	date, err := time.Parse("02.01.2006", dateStr)
+	date, err := b.parseDate(dateStr)
 	if err != nil {
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-			"Неверный формат даты. Используйте ДД.ММ.ГГГГ (например, 25.12.2024)")
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.invalidDateMessage(update.Message.From.ID))
 		b.bot.Send(msg)
 		return
 	}
 
 	// Проверяем, что дата не в прошлом
-	if date.Before(time.Now().AddDate(0, 0, -1)) {
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-			"Нельзя бронировать на прошедшие даты. Выберите будущую дату.")
+	if date.Before(b.today()) {
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_past_date"))
 		b.bot.Send(msg)
 		return
 	}
 
 	item, ok := state.TempData["selected_item"].(models.Item)
 	if !ok {
-		b.sendMessage(update.Message.Chat.ID, "Ошибка: не найден выбранный элемент. Начните заново.")
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_item_not_selected"))
 		b.handleMainMenu(update)
 		return
 	}
 
+	if err := b.validateBookingDate(item, date); err != nil {
+		b.sendMessage(update.Message.Chat.ID, err.Error())
+		return
+	}
+
 	// Проверяем доступность
 	available, err := b.db.CheckAvailability(context.Background(), item.ID, date)
 	if err != nil {
 		log.Printf("Error checking availability: %v", err)
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-			"Произошла ошибка при проверке доступности. Попробуйте позже.")
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_availability_check"))
 		b.bot.Send(msg)
 		return
 	}
 
 	if !available {
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-			"К сожалению, на выбранную дату позиция недоступна. Выберите другую дату.")
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "booking_date_unavailable"))
+		if b.config.Booking.AllowWaitlist {
+			msg.Text += "\n\nМожно встать в очередь — мы сообщим, если место освободится."
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("⏳ Встать в очередь",
+						fmt.Sprintf("waitlist:%d:%s", item.ID, date.Format("2006-01-02"))),
+				),
+			)
+		}
 		b.bot.Send(msg)
 		return
 	}
 
-	// Сохраняем данные в состоянии перед переходом
+	// Сохраняем данные в состоянии перед переходом.
+	// Новая дата сбрасывает ранее выбранную длительность.
 	state.TempData["item_id"] = item.ID
 	state.TempData["date"] = date
+	delete(state.TempData, "end_date")
 	b.setUserState(update.Message.From.ID, "waiting_date", state.TempData)
 
 	b.debugState(update.Message.From.ID, "handleDateInput END")
 
+	if b.config.Booking.MaxBookingDays > 1 {
+		b.handleDurationRequest(update, state)
+		return
+	}
+
+	if isEditingBooking(state) {
+		b.showBookingConfirmation(update, state)
+		return
+	}
+
 	// Переходим к запросу персональных данных
 	// b.handlePersonalData(update, item.ID, date)
 	b.handleNameRequest(update)
 }
 
+// handleDateRequest возвращает пользователя к вводу даты для выбранного аппарата
+func (b *Bot) handleDateRequest(update tgbotapi.Update, state *models.UserState) {
+	item, ok := state.TempData["selected_item"].(models.Item)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_item_not_selected"))
+		b.handleMainMenu(update)
+		return
+	}
+	b.setUserState(update.Message.From.ID, StateWaitingDate, state.TempData)
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+		fmt.Sprintf("Вы выбрали: %s\n\nВведите дату бронирования в формате %s:",
+			item.Name, b.dateFormatHint(update.Message.From.ID)))
+	msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(b.t(update.Message.From.ID, "btn_main_menu")),
+		),
+	)
+	b.bot.Send(msg)
+	b.sendDateCalendar(update.Message.Chat.ID, item)
+}
+
+// handleDurationRequest спрашивает, на сколько дней бронируется аппарат (до booking.max_booking_days)
+func (b *Bot) handleDurationRequest(update tgbotapi.Update, state *models.UserState) {
+	b.setUserState(update.Message.From.ID, StateEnterDuration, state.TempData)
+
+	maxDays := b.config.Booking.MaxBookingDays
+	var buttons []tgbotapi.KeyboardButton
+	for days := 1; days <= maxDays && days <= 7; days++ {
+		buttons = append(buttons, tgbotapi.NewKeyboardButton(strconv.Itoa(days)))
+	}
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+		fmt.Sprintf("На сколько дней? Выберите или введите число от 1 до %d:", maxDays))
+	msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(buttons...),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(b.t(update.Message.From.ID, "btn_main_menu")),
+		),
+	)
+	b.bot.Send(msg)
+}
+
+// handleDurationInput проверяет, что аппарат свободен на весь период, и сохраняет последний день заявки
+func (b *Bot) handleDurationInput(update tgbotapi.Update, text string, state *models.UserState) {
+	maxDays := b.config.Booking.MaxBookingDays
+	days, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || days < 1 || days > maxDays {
+		b.sendMessage(update.Message.Chat.ID,
+			fmt.Sprintf("Введите количество дней от 1 до %d.", maxDays))
+		return
+	}
+
+	item, ok := state.TempData["selected_item"].(models.Item)
+	date, dateOk := state.TempData["date"].(time.Time)
+	if !ok || !dateOk {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.handleMainMenu(update)
+		return
+	}
+
+	if days == 1 {
+		delete(state.TempData, "end_date")
+	} else {
+		endDate := date.AddDate(0, 0, days-1)
+		for day := date.AddDate(0, 0, 1); !day.After(endDate); day = day.AddDate(0, 0, 1) {
+			if err := b.validateBookingDate(item, day); err != nil {
+				b.sendMessage(update.Message.Chat.ID, err.Error())
+				return
+			}
+		}
+
+		available, err := b.db.CheckAvailabilityForPeriod(context.Background(), item.ID, date, endDate, 0)
+		if err != nil {
+			log.Printf("Error checking availability: %v", err)
+			b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_availability_check"))
+			return
+		}
+		if !available {
+			b.sendMessage(update.Message.Chat.ID,
+				fmt.Sprintf("❌ %s занят в один из дней периода %s. Выберите меньший срок или другую дату.",
+					item.Name, formatBookingPeriod(date, endDate, b.dateLayout())))
+			return
+		}
+
+		state.TempData["end_date"] = endDate
+	}
+
+	if isEditingBooking(state) {
+		b.showBookingConfirmation(update, state)
+		return
+	}
+
+	b.handleNameRequest(update)
+}
+
+// isClosedDay проверяет, попадает ли дата на выходной день недели или в список blackout_dates
+func (b *Bot) isClosedDay(date time.Time) bool {
+	weekday := isoWeekday(date)
+	for _, closed := range b.config.Availability.ClosedWeekdays {
+		if closed == weekday {
+			return true
+		}
+	}
+
+	day := date.Format("2006-01-02")
+	for _, blackout := range b.config.Availability.BlackoutDates {
+		if blackout == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateBookingDate проверяет, что дата попадает в окно бронирования аппарата.
+// Окно задается в items.yaml, а если там не указано - в booking конфига.
+func (b *Bot) validateBookingDate(item models.Item, date time.Time) error {
+	today := b.today()
+	days := int(date.Sub(today).Hours() / 24)
+
+	if days < 0 {
+		return errors.New("Нельзя бронировать на прошедшие даты. Выберите будущую дату.")
+	}
+
+	if b.isClosedDay(date) {
+		return fmt.Errorf("%s - выходной день, бронирование недоступно. Выберите другую дату.",
+			date.Format(b.dateLayout()))
+	}
+
+	if current, ok := b.findItemByID(item.ID); ok && current.InMaintenance(date) {
+		return fmt.Errorf("🔧 %s на обслуживании до %s включительно. Выберите дату позже.",
+			current.Name, current.UnavailableUntil.Format(b.dateLayout()))
+	}
+
+	minDays := b.config.Booking.MinAdvanceDays
+	if item.MinAdvanceDays != nil {
+		minDays = *item.MinAdvanceDays
+	}
+	maxDays := b.config.Booking.MaxAdvanceDays
+	if item.MaxAdvanceDays != nil {
+		maxDays = *item.MaxAdvanceDays
+	}
+
+	if days < minDays {
+		return fmt.Errorf("%s нужно бронировать минимум за %d дн. Ближайшая доступная дата: %s.",
+			item.Name, minDays, today.AddDate(0, 0, minDays).Format(b.dateLayout()))
+	}
+
+	if minHours := b.config.Booking.MinAdvanceHours; minHours > 0 {
+		start := time.Date(date.Year(), date.Month(), date.Day(),
+			b.config.Notifications.BookingStartHour, 0, 0, 0, b.location)
+		if b.now().Add(time.Duration(minHours) * time.Hour).After(start) {
+			return fmt.Errorf("Заявку нужно оформить минимум за %d ч. до начала (%s %02d:00). Выберите дату позже.",
+				minHours, date.Format(b.dateLayout()), b.config.Notifications.BookingStartHour)
+		}
+	}
+
+	if maxDays > 0 && days > maxDays {
+		return fmt.Errorf("%s можно бронировать не более чем на %d дн. вперед (до %s включительно).",
+			item.Name, maxDays, today.AddDate(0, 0, maxDays).Format(b.dateLayout()))
+	}
+
+	return nil
+}
+
+// findItemByID возвращает аппарат по ID
+func (b *Bot) findItemByID(itemID int64) (models.Item, bool) {
+	for _, item := range b.getItems() {
+		if item.ID == itemID {
+			return item, true
+		}
+	}
+	return models.Item{}, false
+}
+
 // restoreStateOrRestart восстанавливает состояние или начинает заново
 func (b *Bot) restoreStateOrRestart(update tgbotapi.Update, requiredFields ...string) bool {
 	state := b.getUserState(update.Message.From.ID)
@@ -786,20 +1521,26 @@ func (b *Bot) handlePhoneReceived(update tgbotapi.Update, phone string) {
 
 	state := b.getUserState(update.Message.From.ID)
 
-	// Проверяем и нормализуем номер телефона
-	normalizedPhone := b.normalizePhone(phone)
-	if normalizedPhone == "" {
-		b.sendMessage(update.Message.Chat.ID, "Неверный формат номера телефона. Пожалуйста, введите номер в формате +7XXXXXXXXXX или 8XXXXXXXXXX")
+	// Проверяем и нормализуем номер телефона. Через запятую можно указать запасной номер.
+	normalizedPhone, secondaryPhone, ok := b.parsePhones(phone)
+	if !ok {
+		b.sendMessage(update.Message.Chat.ID, "Неверный формат номера телефона. Пожалуйста, введите номер в формате +7XXXXXXXXXX, 8XXXXXXXXXX или с кодом страны, например +375XXXXXXXXX. Запасной номер можно указать через запятую")
 		return
 	}
 
 	// Получаем данные из состояния
-	itemID := state.TempData["item_id"].(int64)
-	date := state.TempData["date"].(time.Time)
+	itemID, okItem := state.GetInt64("item_id")
+	date, okDate := state.GetTime("date")
+	if !okItem || !okDate {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.clearUserState(update.Message.From.ID)
+		b.handleMainMenu(update)
+		return
+	}
 
 	// Находим выбранный элемент по ID
 	var selectedItem models.Item
-	for _, item := range b.items {
+	for _, item := range b.getItems() {
 		if item.ID == itemID {
 			selectedItem = item
 			break
@@ -813,6 +1554,7 @@ func (b *Bot) handlePhoneReceived(update tgbotapi.Update, phone string) {
 	}
 
 	state.TempData["phone"] = normalizedPhone
+	state.TempData["secondary_phone"] = secondaryPhone
 	state.TempData["selected_item"] = selectedItem // Сохраняем элемент для подтверждения
 	b.setUserState(update.Message.From.ID, StateConfirmation, state.TempData)
 
@@ -821,8 +1563,12 @@ func (b *Bot) handlePhoneReceived(update tgbotapi.Update, phone string) {
 	// Сохраняем телефон пользователя
 	b.updateUserPhone(update.Message.From.ID, normalizedPhone)
 
-	// Проверяем доступность еще раз
-	available, err := b.db.CheckAvailability(context.Background(), selectedItem.ID, date)
+	// Проверяем доступность еще раз на весь период заявки
+	lastDate := date
+	if endDate, ok := state.TempData["end_date"].(time.Time); ok {
+		lastDate = endDate
+	}
+	available, err := b.db.CheckAvailabilityForPeriod(context.Background(), selectedItem.ID, date, lastDate, 0)
 	if err != nil || !available {
 		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
 			"К сожалению, выбранная позиция больше не доступна на эту дату. Пожалуйста, начните заново.")
@@ -831,57 +1577,145 @@ func (b *Bot) handlePhoneReceived(update tgbotapi.Update, phone string) {
 		return
 	}
 
-	name, ok := state.TempData["user_name"].(string)
-	if !ok {
-		name = ""
+	b.continueAfterPhone(update, state)
+}
+
+// showBookingConfirmation показывает пользователю сводку заявки перед созданием.
+// Из сводки можно вернуться к любому полю, после правки пользователь снова попадает сюда.
+func (b *Bot) showBookingConfirmation(update tgbotapi.Update, state *models.UserState) {
+	item, _ := state.TempData["selected_item"].(models.Item)
+	date, _ := state.TempData["date"].(time.Time)
+	endDate, _ := state.TempData["end_date"].(time.Time)
+	name, _ := state.TempData["user_name"].(string)
+	phone, _ := state.TempData["phone"].(string)
+	secondaryPhone, _ := state.TempData["secondary_phone"].(string)
+	comment, _ := state.TempData["comment"].(string)
+
+	delete(state.TempData, "editing")
+	b.setUserState(update.Message.From.ID, StateConfirmation, state.TempData)
+
+	summary := b.t(update.Message.From.ID, "booking_summary",
+		item.Name,
+		formatBookingPeriod(date, endDate, b.dateLayout()),
+		name,
+		b.formatPhoneForDisplay(phone))
+	if secondaryPhone != "" {
+		summary += b.t(update.Message.From.ID, "booking_summary_secondary_phone", b.formatPhoneForDisplay(secondaryPhone))
+	}
+	if comment != "" {
+		summary += b.t(update.Message.From.ID, "booking_summary_comment", comment)
+	}
+	if fileID, _ := state.TempData["attachment_file_id"].(string); fileID != "" {
+		summary += b.t(update.Message.From.ID, "booking_summary_attachment")
 	}
 
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
-		fmt.Sprintf(`📋 Подтверждение заявки:
-
-🏢 Позиция: %s
-📅 Дата: %s
-👤 Имя: %s
-📱 Телефон: %s`,
-			selectedItem.Name,
-			date.Format("02.01.2006"),
-			name,
-			normalizedPhone))
-
-	// keyboard := tgbotapi.NewReplyKeyboard(
-	// 	tgbotapi.NewKeyboardButtonRow(
-	// 		tgbotapi.NewKeyboardButton("✅ Подтвердить заявку"),
-	// 	),
-	// 	tgbotapi.NewKeyboardButtonRow(
-	// 		tgbotapi.NewKeyboardButton("❌ Отмена"),
-	// 	),
-	// )
-	// msg.ReplyMarkup = keyboard
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, summary)
+
+	editRow := tgbotapi.NewKeyboardButtonRow(
+		tgbotapi.NewKeyboardButton("✏️ Изменить дату"),
+		tgbotapi.NewKeyboardButton("✏️ Изменить имя"),
+		tgbotapi.NewKeyboardButton("✏️ Изменить телефон"),
+	)
+	rows := [][]tgbotapi.KeyboardButton{
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("✅ Подтвердить заявку"),
+			tgbotapi.NewKeyboardButton("❌ Отмена"),
+		),
+		editRow,
+	}
+	if b.config.Booking.AskComment {
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("✏️ Изменить комментарий"),
+		))
+	}
+	msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(rows...)
 
 	b.bot.Send(msg)
-	b.finalizeBooking(update)
 }
 
-// normalizePhone нормализует номер телефона
-func (b *Bot) normalizePhone(phone string) string {
-	// Удаляем все нецифровые символы
-	cleaned := ""
-	for _, char := range phone {
-		if char >= '0' && char <= '9' {
-			cleaned += string(char)
+// editBookingField возвращает пользователя из сводки к вводу даты, имени или телефона
+func (b *Bot) editBookingField(update tgbotapi.Update, state *models.UserState, field string) {
+	state.TempData["editing"] = true
+
+	switch field {
+	case "date":
+		item, ok := state.TempData["selected_item"].(models.Item)
+		if !ok {
+			b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_item_not_selected"))
+			b.handleMainMenu(update)
+			return
 		}
+		b.setUserState(update.Message.From.ID, StateWaitingDate, state.TempData)
+
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+			fmt.Sprintf("Введите новую дату в формате %s:", b.dateFormatHint(update.Message.From.ID)))
+		msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(
+			tgbotapi.NewKeyboardButtonRow(
+				tgbotapi.NewKeyboardButton("⬅️ Назад"),
+				tgbotapi.NewKeyboardButton(b.t(update.Message.From.ID, "btn_main_menu")),
+			),
+		)
+		b.bot.Send(msg)
+		b.sendDateCalendar(update.Message.Chat.ID, item)
+	case "name":
+		b.setUserState(update.Message.From.ID, StateEnterName, state.TempData)
+		b.handleNameRequest(update)
+	case "phone":
+		b.setUserState(update.Message.From.ID, StatePhoneNumber, state.TempData)
+		b.handlePhoneRequest(update)
+	case "comment":
+		b.handleCommentRequest(update)
 	}
+}
 
-	// Обрабатываем разные форматы номеров
-	if len(cleaned) == 11 {
-		if cleaned[0] == '8' {
-			return "7" + cleaned[1:] // 8XXXXXXXXXX -> 7XXXXXXXXXX
-		} else if cleaned[0] == '7' {
-			return cleaned // 7XXXXXXXXXX
-		}
-	} else if len(cleaned) == 10 {
-		return "7" + cleaned // XXXXXXXXXX -> 7XXXXXXXXXX
+// isEditingBooking проверяет, что пользователь правит поле из сводки заявки
+func isEditingBooking(state *models.UserState) bool {
+	editing, _ := state.TempData["editing"].(bool)
+	return editing
+}
+
+// continueAfterName переходит к телефону или, при правке из сводки, обратно к сводке
+func (b *Bot) continueAfterName(update tgbotapi.Update, state *models.UserState) {
+	if isEditingBooking(state) {
+		b.showBookingConfirmation(update, state)
+		return
+	}
+
+	b.setUserState(update.Message.From.ID, StatePhoneNumber, state.TempData)
+	b.handlePhoneRequest(update)
+}
+
+// continueAfterPhone переходит к комментарию, если он включен в booking.ask_comment, иначе к сводке
+func (b *Bot) continueAfterPhone(update tgbotapi.Update, state *models.UserState) {
+	if isEditingBooking(state) || !b.config.Booking.AskComment {
+		b.showBookingConfirmation(update, state)
+		return
+	}
+
+	b.handleCommentRequest(update)
+}
+
+// handleCommentRequest запрашивает необязательный комментарий к заявке
+func (b *Bot) handleCommentRequest(update tgbotapi.Update) {
+	state := b.getUserState(update.Message.From.ID)
+	if state == nil {
+		b.sendMessage(update.Message.Chat.ID, b.t(update.Message.From.ID, "err_session_expired"))
+		b.handleMainMenu(update)
+		return
 	}
 
-	return "" // Неверный формат
+	b.setUserState(update.Message.From.ID, StateEnterComment, state.TempData)
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+		"💬 Добавьте комментарий к заявке (например, адрес или пожелания) или нажмите «Пропустить»:")
+	msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("⏭ Пропустить"),
+		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("⬅️ Назад"),
+			tgbotapi.NewKeyboardButton(b.t(update.Message.From.ID, "btn_main_menu")),
+		),
+	)
+	b.bot.Send(msg)
 }
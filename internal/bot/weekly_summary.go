@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// weeklySummaryReport имя недельного отчета в таблице sent_reports
+const weeklySummaryReport = "weekly_summary"
+
+// weeklySummaryTopItems сколько самых популярных аппаратов показывать в отчете
+const weeklySummaryTopItems = 3
+
+// weeklySummaryStatuses порядок и подписи статусов в отчете
+var weeklySummaryStatuses = []struct {
+	status string
+	label  string
+}{
+	{"confirmed", "✅ Подтверждены"},
+	{"completed", "🏁 Завершены"},
+	{"pending", "⏳ Ожидают подтверждения"},
+	{"awaiting_approval", "👥 Ждут второго подтверждения"},
+	{"changed", "🔄 Изменены"},
+	{"rescheduled", "🔄 Предложена другая дата"},
+	{"cancelled", "❌ Отменены"},
+}
+
+// weeklySummary статистика заявок, начинающихся в периоде с start по end
+type weeklySummary struct {
+	start, end time.Time
+	total      int
+	byStatus   map[string]int
+	byItem     map[string]int
+	newUsers   int
+}
+
+// itemCount количество заявок на аппарат для списка популярных
+type itemCount struct {
+	name  string
+	count int
+}
+
+// summarizeWeek считает заявки по статусам и аппаратам. Многодневная заявка относится
+// к неделе, в которую она начинается, чтобы не попасть в два отчета подряд.
+func summarizeWeek(bookings []models.Booking, users []models.User, start, end time.Time) weeklySummary {
+	summary := weeklySummary{
+		start:    start,
+		end:      end,
+		byStatus: make(map[string]int),
+		byItem:   make(map[string]int),
+	}
+
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	for _, booking := range bookings {
+		day := booking.Date.Format("2006-01-02")
+		if day < from || day > to {
+			continue
+		}
+		summary.total++
+		summary.byStatus[booking.Status]++
+		if booking.Status != "cancelled" {
+			summary.byItem[booking.ItemName]++
+		}
+	}
+
+	for _, user := range users {
+		day := user.CreatedAt.Format("2006-01-02")
+		if day >= from && day <= to {
+			summary.newUsers++
+		}
+	}
+	return summary
+}
+
+// topItems возвращает самые востребованные аппараты недели
+func (s weeklySummary) topItems(limit int) []itemCount {
+	items := make([]itemCount, 0, len(s.byItem))
+	for name, count := range s.byItem {
+		items = append(items, itemCount{name: name, count: count})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].count != items[j].count {
+			return items[i].count > items[j].count
+		}
+		return items[i].name < items[j].name
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// percent возвращает долю part от total в процентах
+func percent(part, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return part * 100 / total
+}
+
+// render формирует текст отчета для менеджеров
+func (s weeklySummary) render(layout string) string {
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("📈 Итоги недели: %s - %s\n\n", s.start.Format(layout), s.end.Format(layout)))
+	message.WriteString(fmt.Sprintf("📋 Заявок: %d\n", s.total))
+	for _, status := range weeklySummaryStatuses {
+		if count := s.byStatus[status.status]; count > 0 {
+			message.WriteString(fmt.Sprintf("%s: %d\n", status.label, count))
+		}
+	}
+
+	if top := s.topItems(weeklySummaryTopItems); len(top) > 0 {
+		message.WriteString("\n🏆 Популярные аппараты:\n")
+		for i, item := range top {
+			message.WriteString(fmt.Sprintf("%d. %s - %d\n", i+1, item.name, item.count))
+		}
+	}
+
+	message.WriteString(fmt.Sprintf("\n👥 Новых пользователей: %d\n", s.newUsers))
+
+	cancelled := s.byStatus["cancelled"]
+	message.WriteString(fmt.Sprintf("❌ Отмены: %d из %d (%d%%)\n", cancelled, s.total, percent(cancelled, s.total)))
+
+	// Неделя уже прошла: подтвержденная, но не завершенная заявка означает, что клиент не пришел
+	// или менеджер не отметил приход
+	noShow := s.byStatus["confirmed"]
+	held := noShow + s.byStatus["completed"]
+	message.WriteString(fmt.Sprintf("🚷 Без отметки о приходе: %d из %d (%d%%)\n", noShow, held, percent(noShow, held)))
+
+	return message.String()
+}
+
+// runWeeklySummaryLoop раз в неделю в notifications.weekly_summary отправляет менеджерам отчет за прошедшие 7 дней
+func (b *Bot) runWeeklySummaryLoop() {
+	for {
+		time.Sleep(b.timeUntilNextHour())
+		now := b.now()
+		weekly := b.config.Notifications.WeeklySummary
+		if isoWeekday(now) == weekly.Weekday && now.Hour() == weekly.Hour {
+			b.sendWeeklySummary()
+		}
+	}
+}
+
+// sendWeeklySummary отправляет менеджерам отчет за прошедшие 7 дней. Отчет за неделю
+// отмечается в sent_reports, поэтому после перезапуска бота он не отправляется повторно.
+func (b *Bot) sendWeeklySummary() {
+	ctx := context.Background()
+	end := b.today().AddDate(0, 0, -1)
+	start := end.AddDate(0, 0, -6)
+
+	bookings, err := b.db.GetBookingsByDateRange(ctx, start, end)
+	if err != nil {
+		log.Printf("Error getting bookings for weekly summary: %v", err)
+		return
+	}
+	users, err := b.db.GetAllUsers(ctx)
+	if err != nil {
+		log.Printf("Error getting users for weekly summary: %v", err)
+		return
+	}
+
+	year, week := b.today().ISOWeek()
+	claimed, err := b.db.ClaimReport(ctx, weeklySummaryReport, fmt.Sprintf("%d-W%02d", year, week))
+	if err != nil {
+		log.Printf("Error marking weekly summary as sent: %v", err)
+		return
+	}
+	if !claimed {
+		log.Printf("Weekly summary for %d-W%02d already sent", year, week)
+		return
+	}
+
+	message := summarizeWeek(bookings, users, start, end).render(b.dateLayout())
+	for _, managerID := range b.config.Managers {
+		b.sendMessage(managerID, message)
+	}
+	log.Printf("Weekly summary for %s - %s sent to %d managers",
+		start.Format("2006-01-02"), end.Format("2006-01-02"), len(b.config.Managers))
+}
@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+func TestSplitBookableDatesChecksEveryWeeklyDate(t *testing.T) {
+	// 4 марта 2030 - понедельник
+	setTestNow(t, time.Date(2030, 3, 1, 9, 0, 0, 0, time.UTC))
+	b := newDatesTestBot(t, "Europe/Moscow")
+	b.config.Booking.MaxAdvanceDays = 20
+	b.config.Availability.BlackoutDates = []string{"2030-03-11"}
+
+	start := time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC)
+	dates, skipped := b.splitBookableDates(models.Item{ID: 1, Name: "Аппарат A"}, weeklyDates(start, 4))
+
+	// 11.03 - в blackout_dates, 25.03 - дальше max_advance_days
+	want := []time.Time{start, start.AddDate(0, 0, 14)}
+	if len(dates) != len(want) {
+		t.Fatalf("bookable dates = %v, want %v", dates, want)
+	}
+	for i := range want {
+		if !dates[i].Equal(want[i]) {
+			t.Errorf("bookable dates[%d] = %s, want %s", i, dates[i], want[i])
+		}
+	}
+	if len(skipped) != 2 {
+		t.Errorf("skipped = %v, want 2 dates", skipped)
+	}
+}
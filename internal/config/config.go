@@ -1,7 +1,9 @@
 package config
 
 import (
+	"log"
 	"os"
+	"time"
 
 	"bronivik/internal/models"
 	"github.com/joho/godotenv"
@@ -9,19 +11,34 @@ import (
 )
 
 type Config struct {
-	App              AppConfig        `yaml:"app"`
-	Telegram         TelegramConfig   `yaml:"telegram"`
-	Database         DatabaseConfig   `yaml:"database"`
-	Redis            RedisConfig      `yaml:"redis"`
-	Backup           BackupConfig     `yaml:"backup"`
-	Monitoring       MonitoringConfig `yaml:"monitoring"`
-	Logging          LoggingConfig    `yaml:"logging"`
-	Managers         []int64          `yaml:"managers"`
-	ManagersContacts []string         `yaml:"managers_contacts"`
-	Blacklist        []int64          `yaml:"blacklist"`
-	Items            []models.Item    `yaml:"items"`
-	Exports          ExportConfig     `yaml:"exports"`
-	Google           GoogleConfig     `yaml:"google"`
+	App              AppConfig           `yaml:"app"`
+	Telegram         TelegramConfig      `yaml:"telegram"`
+	Database         DatabaseConfig      `yaml:"database"`
+	Redis            RedisConfig         `yaml:"redis"`
+	Backup           BackupConfig        `yaml:"backup"`
+	Monitoring       MonitoringConfig    `yaml:"monitoring"`
+	Logging          LoggingConfig       `yaml:"logging"`
+	Managers         []int64             `yaml:"managers"`
+	ManagersContacts []string            `yaml:"managers_contacts"`
+	Blacklist        []int64             `yaml:"blacklist"`
+	Items            []models.Item       `yaml:"items"`
+	Exports          ExportConfig        `yaml:"exports"`
+	Google           GoogleConfig        `yaml:"google"`
+	Booking          BookingConfig       `yaml:"booking"`
+	Notifications    NotificationsConfig `yaml:"notifications"`
+	Webhooks         WebhooksConfig      `yaml:"webhooks"`
+	Availability     AvailabilityConfig  `yaml:"availability"`
+	Messages         MessagesConfig      `yaml:"messages"`
+	// ManagerAssignments ID менеджера -> ID аппаратов или "all", о которых он получает уведомления
+	ManagerAssignments map[int64][]string `yaml:"manager_assignments"`
+	// ManagerTemplates готовые сообщения клиенту на экране "Позвонить"
+	ManagerTemplates []MessageTemplate `yaml:"manager_templates"`
+}
+
+// MessageTemplate шаблон сообщения клиенту. В тексте подставляются {name}, {item} и {date}.
+type MessageTemplate struct {
+	Name string `yaml:"name"` // подпись кнопки
+	Text string `yaml:"text"`
 }
 
 type ExportConfig struct {
@@ -32,6 +49,19 @@ type AppConfig struct {
 	Name        string `yaml:"name"`
 	Environment string `yaml:"environment"`
 	Version     string `yaml:"version"`
+	Timezone    string `yaml:"timezone"` // IANA, например Europe/Moscow
+}
+
+// Location возвращает часовой пояс app.timezone или часовой пояс сервера, если он не задан
+func (c AppConfig) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
 }
 
 type TelegramConfig struct {
@@ -84,10 +114,80 @@ type LoggingConfig struct {
 	FilePath string `yaml:"file_path"`
 }
 
+type BookingConfig struct {
+	AllowWaitlist      bool   `yaml:"allow_waitlist"`
+	MaxPerMinute       int    `yaml:"max_per_minute"`
+	PendingTTLHours    int    `yaml:"pending_ttl_hours"`
+	DefaultCountryCode string `yaml:"default_country_code"`
+	MinAdvanceDays     int    `yaml:"min_advance_days"`
+	MinAdvanceHours    int    `yaml:"min_advance_hours"` // Минимум часов от текущего момента до начала дня заявки (notifications.booking_start_hour)
+	MaxAdvanceDays     int    `yaml:"max_advance_days"`
+	AskComment         bool   `yaml:"ask_comment"`
+	DateFormat         string `yaml:"date_format"`
+	MaxBookingDays     int    `yaml:"max_booking_days"`
+	MaxActivePerUser   int    `yaml:"max_active_per_user"`
+	MaxRangeDays       int    `yaml:"max_range_days"`   // Максимальная длина периода, который менеджер бронирует за один раз
+	MaxWeeklyCount     int    `yaml:"max_weekly_count"` // Максимальное количество дат в еженедельной серии менеджера
+}
+
+type NotificationsConfig struct {
+	DailyDigestEnabled bool `yaml:"daily_digest_enabled"`
+	DailyDigestHour    int  `yaml:"daily_digest_hour"`
+	// WeeklySummary еженедельный отчет менеджерам за прошедшие 7 дней
+	WeeklySummary WeeklySummaryConfig `yaml:"weekly_summary"`
+	// ReminderOffsets за сколько до начала заявки напоминать клиенту, например ["24h", "2h"]
+	ReminderOffsets []string `yaml:"reminder_offsets"`
+	// BookingStartHour час начала дня заявки, от которого отсчитываются напоминания
+	BookingStartHour int `yaml:"booking_start_hour"`
+	// QuietHours период, в который уведомления клиентам откладываются
+	QuietHours QuietHoursConfig `yaml:"quiet_hours"`
+}
+
+// WeeklySummaryConfig день недели (1 - пн ... 7 - вс) и час (app.timezone) отправки недельного отчета
+type WeeklySummaryConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Weekday int  `yaml:"weekday"`
+	Hour    int  `yaml:"hour"`
+}
+
+// QuietHoursConfig тихие часы в часовом поясе app.timezone: с Start до End (End не включается).
+// Период может переходить через полночь, например с 22 до 8. Start == End - тихие часы выключены.
+type QuietHoursConfig struct {
+	Start int `yaml:"start"`
+	End   int `yaml:"end"`
+	// ManagerBypass отправляет уведомления о действиях менеджера (подтверждение, отклонение) сразу
+	ManagerBypass bool `yaml:"manager_bypass"`
+}
+
+// Enabled проверяет, что тихие часы заданы
+func (q QuietHoursConfig) Enabled() bool {
+	return q.Start != q.End
+}
+
+// AvailabilityConfig задает дни, в которые бронирование недоступно.
+// Дни недели нумеруются с 1 (понедельник) до 7 (воскресенье), даты - в формате 2006-01-02.
+type AvailabilityConfig struct {
+	ClosedWeekdays []int    `yaml:"closed_weekdays"`
+	BlackoutDates  []string `yaml:"blackout_dates"`
+}
+
+// MessagesConfig тексты бота, которые можно изменить без пересборки. Поддерживается разметка Markdown.
+// Пустое значение - стандартный текст на языке пользователя.
+type MessagesConfig struct {
+	Welcome        string `yaml:"welcome"`         // приветствие над главным меню
+	ContactsHeader string `yaml:"contacts_header"` // заголовок списка контактов менеджеров
+}
+
+type WebhooksConfig struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"`
+}
+
 type GoogleConfig struct {
 	GoogleCredentialsFile string `yaml:"credentials_file"`
 	UsersSpreadSheetId    string `yaml:"users_spreadsheet_id"`
 	BookingSpreadSheetId  string `yaml:"bookings_spreadsheet_id"`
+	DryRun                bool   `yaml:"dry_run"`
 }
 
 func Load(configPath string) (*Config, error) {
@@ -110,5 +210,27 @@ func Load(configPath string) (*Config, error) {
 		return nil, err
 	}
 
+	config.Managers = normalizeManagers(config.Managers)
+
 	return &config, nil
 }
+
+// normalizeManagers убирает из списка менеджеров повторы и неположительные ID:
+// иначе менеджер получает уведомления дважды, а 0 (заявки без Telegram клиента) считается менеджером
+func normalizeManagers(managers []int64) []int64 {
+	seen := make(map[int64]bool, len(managers))
+	normalized := make([]int64, 0, len(managers))
+	for _, id := range managers {
+		if id <= 0 {
+			log.Printf("Warning: managers: skipping invalid Telegram ID %d", id)
+			continue
+		}
+		if seen[id] {
+			log.Printf("Warning: managers: duplicate Telegram ID %d ignored", id)
+			continue
+		}
+		seen[id] = true
+		normalized = append(normalized, id)
+	}
+	return normalized
+}
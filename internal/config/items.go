@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"bronivik/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultItemsPath путь к списку аппаратов, если не задана переменная ITEMS_PATH
+const DefaultItemsPath = "configs/items.yaml"
+
+// ItemsPath возвращает путь к файлу аппаратов из ITEMS_PATH или путь по умолчанию
+func ItemsPath() string {
+	if path := os.Getenv("ITEMS_PATH"); path != "" {
+		return path
+	}
+	return DefaultItemsPath
+}
+
+// LoadItems читает и проверяет список аппаратов. Аппараты сортируются по Order, затем по ID.
+func LoadItems(path string) ([]models.Item, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemsConfig struct {
+		Items []models.Item `yaml:"items"`
+	}
+	if err := yaml.Unmarshal(data, &itemsConfig); err != nil {
+		return nil, err
+	}
+
+	if err := validateItems(itemsConfig.Items); err != nil {
+		return nil, err
+	}
+
+	items := itemsConfig.Items
+	sort.Slice(items, func(i, j int) bool {
+		// Если Order не задан, считаем его 0 (будет в начале)
+		if items[i].Order != items[j].Order {
+			return items[i].Order < items[j].Order
+		}
+		// Если Order одинаковый, сортируем по ID для стабильности
+		return items[i].ID < items[j].ID
+	})
+
+	return items, nil
+}
+
+// validateItems проверяет ID, названия и количество аппаратов и возвращает все найденные проблемы
+func validateItems(items []models.Item) error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(items) == 0 {
+		addProblem("список items пуст")
+	}
+
+	ids := make(map[int64]bool, len(items))
+	names := make(map[string]bool, len(items))
+	for _, item := range items {
+		if item.ID <= 0 {
+			addProblem("%q: id должен быть положительным (%d)", item.Name, item.ID)
+		} else if ids[item.ID] {
+			addProblem("id %d повторяется", item.ID)
+		}
+		ids[item.ID] = true
+
+		name := strings.ToLower(strings.TrimSpace(item.Name))
+		if name == "" {
+			addProblem("id %d: не задано название", item.ID)
+		} else if names[name] {
+			addProblem("название %q повторяется", item.Name)
+		}
+		names[name] = true
+
+		if item.TotalQuantity <= 0 {
+			addProblem("%q: total_quantity должен быть больше нуля (%d)", item.Name, item.TotalQuantity)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("некорректный список аппаратов:\n  - %s", strings.Join(problems, "\n  - "))
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Validate проверяет смысловую корректность конфигурации и возвращает
+// одну ошибку со списком всех найденных проблем
+func (c *Config) Validate() error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.App.Timezone != "" {
+		if _, err := time.LoadLocation(c.App.Timezone); err != nil {
+			addProblem("app.timezone %q не найден в базе часовых поясов (например, Europe/Moscow)", c.App.Timezone)
+		}
+	}
+
+	if c.Telegram.BotToken == "" {
+		addProblem("telegram.bot_token не задан")
+	}
+
+	if len(c.Managers) == 0 {
+		addProblem("managers: список менеджеров пуст")
+	}
+
+	managers := make(map[int64]bool, len(c.Managers))
+	for _, id := range c.Managers {
+		managers[id] = true
+	}
+	for id := range c.ManagerAssignments {
+		if !managers[id] {
+			addProblem("manager_assignments: %d не указан в managers", id)
+		}
+	}
+
+	for i, template := range c.ManagerTemplates {
+		if strings.TrimSpace(template.Name) == "" || strings.TrimSpace(template.Text) == "" {
+			addProblem("manager_templates[%d]: нужно задать name и text", i)
+		}
+	}
+
+	booking := c.Booking
+	if booking.MaxPerMinute < 0 {
+		addProblem("booking.max_per_minute не может быть отрицательным (%d)", booking.MaxPerMinute)
+	}
+	if booking.PendingTTLHours < 0 {
+		addProblem("booking.pending_ttl_hours не может быть отрицательным (%d)", booking.PendingTTLHours)
+	}
+	if booking.MinAdvanceDays < 0 {
+		addProblem("booking.min_advance_days не может быть отрицательным (%d)", booking.MinAdvanceDays)
+	}
+	if booking.MinAdvanceHours < 0 {
+		addProblem("booking.min_advance_hours не может быть отрицательным (%d)", booking.MinAdvanceHours)
+	}
+	if booking.MaxAdvanceDays < 0 {
+		addProblem("booking.max_advance_days не может быть отрицательным (%d)", booking.MaxAdvanceDays)
+	}
+	if booking.MaxAdvanceDays > 0 && booking.MaxAdvanceDays < booking.MinAdvanceDays {
+		addProblem("booking.max_advance_days (%d) меньше booking.min_advance_days (%d)",
+			booking.MaxAdvanceDays, booking.MinAdvanceDays)
+	}
+	if booking.MaxBookingDays < 0 {
+		addProblem("booking.max_booking_days не может быть отрицательным (%d)", booking.MaxBookingDays)
+	}
+	if booking.MaxRangeDays != 0 && booking.MaxRangeDays < 2 {
+		addProblem("booking.max_range_days должен быть не меньше 2 или 0 для значения по умолчанию (%d)", booking.MaxRangeDays)
+	}
+	if booking.MaxWeeklyCount != 0 && booking.MaxWeeklyCount < 2 {
+		addProblem("booking.max_weekly_count должен быть не меньше 2 или 0 для значения по умолчанию (%d)", booking.MaxWeeklyCount)
+	}
+	if booking.MaxActivePerUser < 0 {
+		addProblem("booking.max_active_per_user не может быть отрицательным (%d)", booking.MaxActivePerUser)
+	}
+	if booking.DateFormat != "" && !isValidDateLayout(booking.DateFormat) {
+		addProblem("booking.date_format %q не содержит день, месяц и год в формате Go (например, 02.01.2006)",
+			booking.DateFormat)
+	}
+
+	if c.Notifications.DailyDigestHour < 0 || c.Notifications.DailyDigestHour > 23 {
+		addProblem("notifications.daily_digest_hour должен быть от 0 до 23 (%d)", c.Notifications.DailyDigestHour)
+	}
+
+	if weekly := c.Notifications.WeeklySummary; weekly.Enabled {
+		if weekly.Weekday < 1 || weekly.Weekday > 7 {
+			addProblem("notifications.weekly_summary.weekday должен быть от 1 (пн) до 7 (вс) (%d)", weekly.Weekday)
+		}
+		if weekly.Hour < 0 || weekly.Hour > 23 {
+			addProblem("notifications.weekly_summary.hour должен быть от 0 до 23 (%d)", weekly.Hour)
+		}
+	}
+
+	for _, offset := range c.Notifications.ReminderOffsets {
+		if duration, err := time.ParseDuration(offset); err != nil || duration <= 0 {
+			addProblem("notifications.reminder_offsets: %q не положительная длительность (например, 24h или 2h)", offset)
+		}
+	}
+	if c.Notifications.BookingStartHour < 0 || c.Notifications.BookingStartHour > 23 {
+		addProblem("notifications.booking_start_hour должен быть от 0 до 23 (%d)", c.Notifications.BookingStartHour)
+	}
+
+	quiet := c.Notifications.QuietHours
+	if quiet.Start < 0 || quiet.Start > 23 || quiet.End < 0 || quiet.End > 23 {
+		addProblem("notifications.quiet_hours: start и end должны быть от 0 до 23 (%d, %d)", quiet.Start, quiet.End)
+	}
+
+	for _, weekday := range c.Availability.ClosedWeekdays {
+		if weekday < 1 || weekday > 7 {
+			addProblem("availability.closed_weekdays: %d вне диапазона 1-7", weekday)
+		}
+	}
+	for _, date := range c.Availability.BlackoutDates {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			addProblem("availability.blackout_dates: %q не в формате 2006-01-02", date)
+		}
+	}
+
+	// Google Sheets включается, если задан хотя бы один параметр - тогда нужны все три
+	google := c.Google
+	if google.GoogleCredentialsFile != "" || google.UsersSpreadSheetId != "" || google.BookingSpreadSheetId != "" {
+		if google.GoogleCredentialsFile == "" {
+			addProblem("google.credentials_file не задан")
+		}
+		if google.UsersSpreadSheetId == "" {
+			addProblem("google.users_spreadsheet_id не задан")
+		}
+		if google.BookingSpreadSheetId == "" {
+			addProblem("google.bookings_spreadsheet_id не задан")
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("некорректная конфигурация:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// isValidDateLayout проверяет, что по формату дату можно записать и прочитать без потерь
+func isValidDateLayout(layout string) bool {
+	sample := time.Date(2024, time.December, 25, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, sample.Format(layout))
+	return err == nil && parsed.Equal(sample)
+}
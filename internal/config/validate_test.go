@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateManagerRangeLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		booking BookingConfig
+		problem string
+	}{
+		{"defaults", BookingConfig{}, ""},
+		{"minimum", BookingConfig{MaxRangeDays: 2, MaxWeeklyCount: 2}, ""},
+		{"range too short", BookingConfig{MaxRangeDays: 1}, "booking.max_range_days"},
+		{"negative range", BookingConfig{MaxRangeDays: -1}, "booking.max_range_days"},
+		{"weekly too short", BookingConfig{MaxWeeklyCount: 1}, "booking.max_weekly_count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Managers: []int64{1}, Booking: tt.booking}
+			cfg.Telegram.BotToken = "token"
+
+			err := cfg.Validate()
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Errorf("Validate() = %v, want no error", err)
+			case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+				t.Errorf("Validate() = %v, want problem with %s", err, tt.problem)
+			}
+		})
+	}
+}
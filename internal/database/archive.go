@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// ArchiveBookings переносит завершенные и отмененные бронирования с датой раньше olderThan
+// в таблицу bookings_archive и удаляет их из bookings. Возвращает количество перенесенных заявок.
+func (db *DB) ArchiveBookings(ctx context.Context, olderThan time.Time) (int64, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	cutoff := olderThan.Format("2006-01-02")
+
+	insertQuery := `
+        INSERT OR REPLACE INTO bookings_archive (` + bookingColumns + `, archived_at)
+        SELECT ` + bookingColumns + `, ?
+        FROM bookings
+        WHERE status IN ('completed', 'cancelled') AND COALESCE(end_date, date) < ?
+    `
+	if _, err := tx.ExecContext(ctx, insertQuery, time.Now(), cutoff); err != nil {
+		return 0, err
+	}
+
+	deleteQuery := `DELETE FROM bookings WHERE status IN ('completed', 'cancelled') AND COALESCE(end_date, date) < ?`
+	result, err := tx.ExecContext(ctx, deleteQuery, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	archived, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return archived, nil
+}
+
+// GetArchivedBookingsByDateRange возвращает архивные бронирования за период
+func (db *DB) GetArchivedBookingsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Booking, error) {
+	query := `
+        SELECT ` + bookingColumns + `
+        FROM bookings_archive
+        WHERE strftime('%Y-%m-%d', date) <= ?
+        AND strftime('%Y-%m-%d', COALESCE(end_date, date)) >= ?
+        ORDER BY date, created_at
+    `
+
+	rows, err := db.db.QueryContext(ctx, query,
+		endDate.Format("2006-01-02"),
+		startDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+
+	return bookings, rows.Err()
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// AvailabilityCache кэширует количество занятых единиц аппарата на дату.
+// Реализация должна сама обрабатывать свои ошибки: при сбое кэша запрос уходит в БД.
+type AvailabilityCache interface {
+	GetBookedCount(ctx context.Context, itemID int64, date time.Time) (int, bool)
+	SetBookedCount(ctx context.Context, itemID int64, date time.Time, count int)
+	Invalidate(ctx context.Context, itemID int64, dates []time.Time)
+}
+
+// noopAvailabilityCache используется, когда Redis не настроен: все запросы идут в БД
+type noopAvailabilityCache struct{}
+
+func (noopAvailabilityCache) GetBookedCount(context.Context, int64, time.Time) (int, bool) {
+	return 0, false
+}
+
+func (noopAvailabilityCache) SetBookedCount(context.Context, int64, time.Time, int) {}
+
+func (noopAvailabilityCache) Invalidate(context.Context, int64, []time.Time) {}
+
+// SetAvailabilityCache подключает кэш доступности
+func (db *DB) SetAvailabilityCache(cache AvailabilityCache) {
+	db.availabilityCache = cache
+}
+
+// invalidateBookingAvailability сбрасывает кэш всех дней заявки
+func (db *DB) invalidateBookingAvailability(ctx context.Context, booking models.Booking) {
+	var dates []time.Time
+	for date := booking.Date; !date.After(booking.LastDate()); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date)
+	}
+	db.availabilityCache.Invalidate(ctx, booking.ItemID, dates)
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// staleAvailabilityCache всегда отдает устаревшее значение: ни одной занятой единицы
+type staleAvailabilityCache struct{}
+
+func (staleAvailabilityCache) GetBookedCount(context.Context, int64, time.Time) (int, bool) {
+	return 0, true
+}
+
+func (staleAvailabilityCache) SetBookedCount(context.Context, int64, time.Time, int) {}
+
+func (staleAvailabilityCache) Invalidate(context.Context, int64, []time.Time) {}
+
+func TestCheckAvailabilityIgnoresStaleCache(t *testing.T) {
+	db := newTestDB(t, []models.Item{{ID: 1, Name: "Аппарат A", TotalQuantity: 1}})
+	ctx := context.Background()
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	booking := &models.Booking{UserID: 100, UserName: "Клиент", Phone: "+79001234567",
+		ItemID: 1, ItemName: "Аппарат A", Date: date, Status: "confirmed"}
+	if err := db.CreateBooking(ctx, booking); err != nil {
+		t.Fatalf("CreateBooking: %v", err)
+	}
+	db.SetAvailabilityCache(staleAvailabilityCache{})
+
+	available, err := db.CheckAvailability(ctx, 1, date)
+	if err != nil {
+		t.Fatalf("CheckAvailability: %v", err)
+	}
+	if available {
+		t.Error("CheckAvailability must count in SQLite, not trust the cached count")
+	}
+
+	available, err = db.CheckAvailabilityForPeriod(ctx, 1, date, date, 0)
+	if err != nil {
+		t.Fatalf("CheckAvailabilityForPeriod: %v", err)
+	}
+	if available {
+		t.Error("CheckAvailabilityForPeriod must count in SQLite, not trust the cached count")
+	}
+}
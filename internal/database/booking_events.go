@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// bookingChange описывает изменение заявки для журнала booking_events
+type bookingChange struct {
+	managerID int64  // 0 - изменение клиентом или автоматически
+	toStatus  string // пусто, если статус не меняется
+	details   string
+}
+
+// execBookingUpdate выполняет UPDATE заявки и записывает событие в журнал в одной транзакции.
+// При checkVersion отсутствие обновленных строк означает, что заявку уже изменили.
+func (db *DB) execBookingUpdate(ctx context.Context, id int64, query string, args []interface{}, checkVersion bool, change bookingChange) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := scanBooking(tx.QueryRowContext(ctx, `SELECT `+bookingColumns+` FROM bookings WHERE id = ?`, id))
+	if err != nil {
+		return err
+	}
+	fromStatus := before.Status
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if checkVersion {
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrConcurrentModification
+		}
+	}
+
+	toStatus := change.toStatus
+	if toStatus == "" {
+		toStatus = fromStatus
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO booking_events (booking_id, from_status, to_status, manager_id, details, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, id, fromStatus, toStatus, change.managerID, change.details, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Заявка могла сменить статус, даты или аппарат - сбрасываем кэш до и после изменения
+	db.invalidateBookingAvailability(ctx, before)
+	if after, err := db.GetBooking(ctx, id); err == nil {
+		db.invalidateBookingAvailability(ctx, *after)
+	}
+	return nil
+}
+
+// GetBookingHistory возвращает историю изменений заявки в хронологическом порядке
+func (db *DB) GetBookingHistory(ctx context.Context, bookingID int64) ([]models.BookingEvent, error) {
+	query := `
+        SELECT id, booking_id, from_status, to_status, manager_id, details, created_at
+        FROM booking_events
+        WHERE booking_id = ?
+        ORDER BY created_at, id
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.BookingEvent
+	for rows.Next() {
+		var event models.BookingEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.BookingID,
+			&event.FromStatus,
+			&event.ToStatus,
+			&event.ManagerID,
+			&event.Details,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// ErrCheckinTokenNotFound возвращается, если код регистрации не найден, уже использован
+// или заявка больше не подтверждена
+var ErrCheckinTokenNotFound = errors.New("checkin token not found")
+
+// SetBookingCheckinToken сохраняет код регистрации клиента для подтвержденной заявки
+func (db *DB) SetBookingCheckinToken(ctx context.Context, bookingID int64, token string) error {
+	_, err := db.db.ExecContext(ctx, `UPDATE bookings SET checkin_token = ? WHERE id = ?`, token, bookingID)
+	return err
+}
+
+// ConsumeCheckinToken находит подтвержденную заявку по коду регистрации и гасит код,
+// чтобы его нельзя было использовать повторно
+func (db *DB) ConsumeCheckinToken(ctx context.Context, token string) (*models.Booking, error) {
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE checkin_token = ? AND status = 'confirmed'`
+	booking, err := scanBooking(db.db.QueryRowContext(ctx, query, token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCheckinTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Условие по коду защищает от одновременной регистрации по одному коду
+	result, err := db.db.ExecContext(ctx,
+		`UPDATE bookings SET checkin_token = '', updated_at = ? WHERE id = ? AND checkin_token = ?`,
+		time.Now(), booking.ID, token)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrCheckinTokenNotFound
+	}
+
+	return &booking, nil
+}
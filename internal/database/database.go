@@ -3,20 +3,83 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"bronivik/internal/models"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// ErrConcurrentModification возвращается, если заявка была изменена другим запросом
+var ErrConcurrentModification = errors.New("booking was modified concurrently")
+
+// bookingColumns список колонок заявки в порядке сканирования scanBooking
+const bookingColumns = `id, user_id, user_name, user_nickname, phone, secondary_phone, item_id, item_name, date, end_date, status, comment, manager_note, created_by_manager_id, source, group_id, attachment_file_id, attachment_type, price, deposit_paid, reminder_ack_at, first_approved_by, version, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBooking читает заявку из строки результата запроса
+func scanBooking(row rowScanner) (models.Booking, error) {
+	var booking models.Booking
+	var endDate, reminderAckAt sql.NullTime
+	err := row.Scan(
+		&booking.ID,
+		&booking.UserID,
+		&booking.UserName,
+		&booking.UserNickname,
+		&booking.Phone,
+		&booking.SecondaryPhone,
+		&booking.ItemID,
+		&booking.ItemName,
+		&booking.Date,
+		&endDate,
+		&booking.Status,
+		&booking.Comment,
+		&booking.ManagerNote,
+		&booking.CreatedByManagerID,
+		&booking.Source,
+		&booking.GroupID,
+		&booking.AttachmentFileID,
+		&booking.AttachmentType,
+		&booking.Price,
+		&booking.DepositPaid,
+		&reminderAckAt,
+		&booking.FirstApprovedBy,
+		&booking.Version,
+		&booking.CreatedAt,
+		&booking.UpdatedAt,
+	)
+	if endDate.Valid {
+		booking.EndDate = endDate.Time
+	}
+	if reminderAckAt.Valid {
+		booking.ReminderAckAt = reminderAckAt.Time
+	}
+	return booking, err
+}
+
+// nullableEndDate сохраняет нулевой EndDate как NULL
+func nullableEndDate(endDate time.Time) interface{} {
+	if endDate.IsZero() {
+		return nil
+	}
+	return endDate
+}
+
 type DB struct {
-	db          *sql.DB
-	items       map[int64]models.Item
-	sortedItems []models.Item
+	db *sql.DB
+	// items аппараты по ID; SetItems заменяет карту целиком, читать через getItem/itemsSnapshot
+	items             map[int64]models.Item
+	sortedItems       []models.Item
+	itemsMu           sync.RWMutex
+	availabilityCache AvailabilityCache
 }
 
 func NewDB(path string) (*DB, error) {
@@ -41,8 +104,17 @@ func NewDB(path string) (*DB, error) {
 		return nil, fmt.Errorf("failed to create tables: %v", err)
 	}
 
+	if err := migrateTables(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate tables: %v", err)
+	}
+
 	log.Printf("База данных инициализирована: %s", path)
-	return &DB{db: db, items: make(map[int64]models.Item), sortedItems: []models.Item{}}, nil
+	return &DB{
+		db:                db,
+		items:             make(map[int64]models.Item),
+		sortedItems:       []models.Item{},
+		availabilityCache: noopAvailabilityCache{},
+	}, nil
 }
 
 func createTables(db *sql.DB) error {
@@ -74,9 +146,95 @@ func createTables(db *sql.DB) error {
             date DATETIME NOT NULL,
             status TEXT NOT NULL DEFAULT 'pending',
             comment TEXT,
+            version INTEGER NOT NULL DEFAULT 1,
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
             updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
         )`,
+		// Таблица состояний пользователей (шаг диалога и временные данные)
+		`CREATE TABLE IF NOT EXISTS user_states (
+            user_id INTEGER PRIMARY KEY,
+            current_step TEXT NOT NULL,
+            temp_data TEXT NOT NULL DEFAULT '{}',
+            updated_at DATETIME NOT NULL
+        )`,
+
+		// Таблица очереди ожидания на занятые даты
+		`CREATE TABLE IF NOT EXISTS waitlist (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            item_id INTEGER NOT NULL,
+            date DATETIME NOT NULL,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(user_id, item_id, date)
+        )`,
+
+		// Архив завершенных и отмененных бронирований
+		`CREATE TABLE IF NOT EXISTS bookings_archive (
+            id INTEGER PRIMARY KEY,
+            user_id INTEGER NOT NULL,
+            user_name TEXT NOT NULL,
+            user_nickname TEXT,
+            phone TEXT NOT NULL,
+            item_id INTEGER NOT NULL,
+            item_name TEXT NOT NULL,
+            date DATETIME NOT NULL,
+            status TEXT NOT NULL,
+            comment TEXT,
+            manager_note TEXT NOT NULL DEFAULT '',
+            created_by_manager_id INTEGER NOT NULL DEFAULT 0,
+            version INTEGER NOT NULL DEFAULT 1,
+            created_at DATETIME,
+            updated_at DATETIME,
+            archived_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+
+		// Очередь задач синхронизации с Google Sheets
+		`CREATE TABLE IF NOT EXISTS sheet_tasks (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            type TEXT NOT NULL,
+            booking_id INTEGER NOT NULL DEFAULT 0,
+            status TEXT NOT NULL DEFAULT 'pending',
+            payload TEXT NOT NULL DEFAULT '',
+            attempts INTEGER NOT NULL DEFAULT 0,
+            next_retry_at DATETIME NOT NULL,
+            last_error TEXT NOT NULL DEFAULT '',
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+
+		// Журнал изменений заявок
+		`CREATE TABLE IF NOT EXISTS booking_events (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            booking_id INTEGER NOT NULL,
+            from_status TEXT NOT NULL,
+            to_status TEXT NOT NULL,
+            manager_id INTEGER NOT NULL DEFAULT 0,
+            details TEXT NOT NULL DEFAULT '',
+            created_at DATETIME NOT NULL
+        )`,
+
+		// Обслуживание аппаратов: аппарат скрыт для новых заявок до unavailable_until включительно
+		`CREATE TABLE IF NOT EXISTS item_maintenance (
+            item_id INTEGER PRIMARY KEY,
+            unavailable_until DATETIME NOT NULL
+        )`,
+
+		// Уведомления, отложенные до конца тихих часов
+		`CREATE TABLE IF NOT EXISTS deferred_notifications (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            text TEXT NOT NULL,
+            deliver_at DATETIME NOT NULL,
+            created_at DATETIME NOT NULL
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_deferred_notifications_deliver_at ON deferred_notifications(deliver_at)`,
+
+		// Отправленные периодические отчеты: не дают отправить отчет повторно после перезапуска
+		`CREATE TABLE IF NOT EXISTS sent_reports (
+            name TEXT PRIMARY KEY,
+            period TEXT NOT NULL,
+            sent_at DATETIME NOT NULL
+        )`,
 
 		// Индексы для пользователей
 		`CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id)`,
@@ -88,6 +246,11 @@ func createTables(db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_bookings_status ON bookings(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_bookings_item_id ON bookings(item_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_bookings_user_id ON bookings(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_status_date ON bookings(status, date)`,
+		`CREATE INDEX IF NOT EXISTS idx_waitlist_item_date ON waitlist(item_id, date)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_archive_date ON bookings_archive(date)`,
+		`CREATE INDEX IF NOT EXISTS idx_sheet_tasks_status_retry ON sheet_tasks(status, next_retry_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_booking_events_booking_id ON booking_events(booking_id)`,
 	}
 
 	for _, query := range queries {
@@ -98,65 +261,258 @@ func createTables(db *sql.DB) error {
 	return nil
 }
 
-// SetItems устанавливает информацию о позициях для проверки доступности
+// migrateTables добавляет колонки, появившиеся после создания таблиц
+func migrateTables(db *sql.DB) error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"bookings", "version", "INTEGER NOT NULL DEFAULT 1"},
+		{"bookings", "manager_note", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings", "created_by_manager_id", "INTEGER NOT NULL DEFAULT 0"},
+		{"bookings_archive", "created_by_manager_id", "INTEGER NOT NULL DEFAULT 0"},
+		{"users", "blocked_bot", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"bookings", "end_date", "DATETIME"},
+		{"bookings_archive", "end_date", "DATETIME"},
+		{"bookings", "reminded_offsets", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings", "checkin_token", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings", "source", "TEXT NOT NULL DEFAULT 'user'"},
+		{"bookings_archive", "source", "TEXT NOT NULL DEFAULT 'user'"},
+		{"bookings", "group_id", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings_archive", "group_id", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings", "attachment_file_id", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings_archive", "attachment_file_id", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings", "attachment_type", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings_archive", "attachment_type", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings", "price", "INTEGER NOT NULL DEFAULT 0"},
+		{"bookings_archive", "price", "INTEGER NOT NULL DEFAULT 0"},
+		{"bookings", "deposit_paid", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"bookings_archive", "deposit_paid", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"bookings", "reminder_ack_at", "DATETIME"},
+		{"bookings_archive", "reminder_ack_at", "DATETIME"},
+		{"bookings", "first_approved_by", "INTEGER NOT NULL DEFAULT 0"},
+		{"bookings_archive", "first_approved_by", "INTEGER NOT NULL DEFAULT 0"},
+		{"bookings", "secondary_phone", "TEXT NOT NULL DEFAULT ''"},
+		{"bookings_archive", "secondary_phone", "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, c := range columns {
+		if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	// Заявки, оформленные менеджером до появления колонки source, получают источник по created_by_manager_id
+	for _, table := range []string{"bookings", "bookings_archive"} {
+		query := fmt.Sprintf(`UPDATE %s SET source = 'manager' WHERE created_by_manager_id != 0 AND source = 'user'`, table)
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	// До появления created_by_manager_id заявки менеджера хранили его ID в user_id.
+	// Переносим его в created_by_manager_id, чтобы менеджеру не приходили уведомления как клиенту.
+	for _, table := range []string{"bookings", "bookings_archive"} {
+		query := fmt.Sprintf(`UPDATE %s SET created_by_manager_id = user_id WHERE source = 'manager' AND created_by_manager_id = 0 AND user_id != 0`, table)
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	// Индексы создаются после миграции, потому что колонок checkin_token и group_id в старых БД еще нет
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_bookings_checkin_token ON bookings(checkin_token) WHERE checkin_token != ''`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_bookings_group_id ON bookings(group_id) WHERE group_id != ''`)
+	return err
+}
+
+// addColumnIfNotExists добавляет колонку в таблицу, если её ещё нет
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("error executing query %s: %v", query, err)
+	}
+	return nil
+}
+
+// SetItems устанавливает информацию о позициях для проверки доступности.
+// Новая карта собирается целиком и подменяется под блокировкой, чтобы проверки не увидели пустую карту.
 func (db *DB) SetItems(items []models.Item) {
-	db.items = make(map[int64]models.Item)
+	byID := make(map[int64]models.Item, len(items))
 	for _, item := range items {
-		db.items[item.ID] = item
+		byID[item.ID] = item
 	}
+
+	db.itemsMu.Lock()
+	db.items = byID
 	// Сохраняем также отсортированный список для использования в боте
 	db.sortedItems = items
+	db.itemsMu.Unlock()
+}
+
+// getItem возвращает позицию по ID
+func (db *DB) getItem(itemID int64) (models.Item, bool) {
+	db.itemsMu.RLock()
+	defer db.itemsMu.RUnlock()
+	item, ok := db.items[itemID]
+	return item, ok
 }
 
-// CheckAvailability проверяет доступность позиции на указанную дату
+// itemsSnapshot возвращает текущую карту позиций. Карта не меняется после публикации в SetItems.
+func (db *DB) itemsSnapshot() map[int64]models.Item {
+	db.itemsMu.RLock()
+	defer db.itemsMu.RUnlock()
+	return db.items
+}
+
+// CheckAvailability проверяет, что на указанную дату осталась хотя бы одна свободная единица позиции.
+// Считает по SQLite мимо кэша: проверка идет перед созданием и изменением заявки.
 func (db *DB) CheckAvailability(ctx context.Context, itemID int64, date time.Time) (bool, error) {
-	dateStr := date.Format("2006-01-02")
+	// Получаем общее количество из кэша items
+	item, exists := db.getItem(itemID)
+	if !exists {
+		return false, fmt.Errorf("item with ID %d not found", itemID)
+	}
 
-	query := `
-        SELECT COUNT(*) 
-        FROM bookings 
-        WHERE item_id = ? 
-        AND date(date) = date(?)
-        AND status IN ('pending', 'confirmed')
-    `
+	bookedCount, err := db.getBookedCount(ctx, itemID, date, 0)
+	if err != nil {
+		return false, err
+	}
+	if int64(bookedCount) >= item.TotalQuantity {
+		return false, nil
+	}
 
-	var bookedCount int
-	err := db.db.QueryRowContext(ctx, query, itemID, dateStr).Scan(&bookedCount)
+	busy, err := db.sharedResourceBusy(ctx, item, date, 0)
 	if err != nil {
 		return false, err
 	}
+	return !busy, nil
+}
 
-	// Получаем общее количество из кэша items
-	item, exists := db.items[itemID]
+// sharedResourceBusy проверяет, занят ли на дату общий ресурс аппарата заявкой на другой аппарат той же группы.
+// Заявка excludeBookingID не учитывается (0 - учитывать все).
+func (db *DB) sharedResourceBusy(ctx context.Context, item models.Item, date time.Time, excludeBookingID int64) (bool, error) {
+	if item.SharedResource == "" {
+		return false, nil
+	}
+
+	for _, other := range db.itemsSnapshot() {
+		if other.ID == item.ID || other.SharedResource != item.SharedResource {
+			continue
+		}
+
+		bookedCount, err := db.getBookedCount(ctx, other.ID, date, excludeBookingID)
+		if err != nil {
+			return false, err
+		}
+		if bookedCount > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CheckAvailabilityForPeriod проверяет, что аппарат свободен в каждый день с startDate по endDate включительно.
+// Заявка excludeBookingID не учитывается, чтобы при переносе она не занимала сама себя (0 - учитывать все).
+// Как и CheckAvailability, считает по SQLite мимо кэша.
+func (db *DB) CheckAvailabilityForPeriod(ctx context.Context, itemID int64, startDate, endDate time.Time, excludeBookingID int64) (bool, error) {
+	item, exists := db.getItem(itemID)
 	if !exists {
 		return false, fmt.Errorf("item with ID %d not found", itemID)
 	}
 
-	return bookedCount < int(item.TotalQuantity), nil
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		bookedCount, err := db.getBookedCount(ctx, itemID, date, excludeBookingID)
+		if err != nil {
+			return false, err
+		}
+		if int64(bookedCount) >= item.TotalQuantity {
+			return false, nil
+		}
+
+		busy, err := db.sharedResourceBusy(ctx, item, date, excludeBookingID)
+		if err != nil {
+			return false, err
+		}
+		if busy {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
-// GetBookedCount возвращает количество забронированных единиц на дату
+// GetBookedCount возвращает количество забронированных единиц на дату,
+// включая многодневные заявки, которые захватывают эту дату.
+// Значение может браться из кэша и отставать от БД, поэтому подходит только для отображения;
+// перед созданием или изменением заявки используйте CheckAvailability/CheckAvailabilityForPeriod.
 func (db *DB) GetBookedCount(ctx context.Context, itemID int64, date time.Time) (int, error) {
+	if count, ok := db.availabilityCache.GetBookedCount(ctx, itemID, date); ok {
+		return count, nil
+	}
+
+	count, err := db.getBookedCount(ctx, itemID, date, 0)
+	if err != nil {
+		return 0, err
+	}
+	db.availabilityCache.SetBookedCount(ctx, itemID, date, count)
+	return count, nil
+}
+
+func (db *DB) getBookedCount(ctx context.Context, itemID int64, date time.Time, excludeBookingID int64) (int, error) {
 	dateStr := date.Format("2006-01-02")
 
 	query := `
         SELECT COUNT(*) 
         FROM bookings 
         WHERE item_id = ? 
-        AND date(date) = date(?)
-        AND status IN ('pending', 'confirmed')
+        AND date(date) <= date(?)
+        AND date(COALESCE(end_date, date)) >= date(?)
+        AND status IN ('pending', 'awaiting_approval', 'confirmed')
+        AND id != ?
     `
 
 	var count int
-	err := db.db.QueryRowContext(ctx, query, itemID, dateStr).Scan(&count)
+	err := db.db.QueryRowContext(ctx, query, itemID, dateStr, dateStr, excludeBookingID).Scan(&count)
 	return count, err
 }
 
 // CreateBooking создает новое бронирование
 func (db *DB) CreateBooking(ctx context.Context, booking *models.Booking) error {
+	if booking.Source == "" {
+		booking.Source = models.BookingSourceUser
+	}
+
 	query := `
-        INSERT INTO bookings (user_id, user_name, user_nickname, phone, item_id, item_name, date, status, comment, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        INSERT INTO bookings (user_id, user_name, user_nickname, phone, secondary_phone, item_id, item_name, date, end_date, status, comment, created_by_manager_id, source, group_id, attachment_file_id, attachment_type, price, first_approved_by, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
         RETURNING id
     `
 
@@ -165,11 +521,20 @@ func (db *DB) CreateBooking(ctx context.Context, booking *models.Booking) error
 		booking.UserName,
 		booking.UserNickname,
 		booking.Phone,
+		booking.SecondaryPhone,
 		booking.ItemID,
 		booking.ItemName,
 		booking.Date,
+		nullableEndDate(booking.EndDate),
 		booking.Status,
 		booking.Comment,
+		booking.CreatedByManagerID,
+		booking.Source,
+		booking.GroupID,
+		booking.AttachmentFileID,
+		booking.AttachmentType,
+		booking.Price,
+		booking.FirstApprovedBy,
 		booking.CreatedAt,
 		booking.UpdatedAt,
 	)
@@ -184,39 +549,179 @@ func (db *DB) CreateBooking(ctx context.Context, booking *models.Booking) error
 	}
 
 	booking.ID = id
+	db.invalidateBookingAvailability(ctx, *booking)
 	return nil
 }
 
 // UpdateBookingComment обновляет комментарий заявки
 func (db *DB) UpdateBookingComment(ctx context.Context, bookingID int64, comment string) error {
-	query := `UPDATE bookings SET comment = $1, updated_at = $2 WHERE id = $3`
+	query := `UPDATE bookings SET comment = $1, updated_at = $2, version = version + 1 WHERE id = $3`
 	_, err := db.db.ExecContext(ctx, query, comment, time.Now(), bookingID)
 	return err
 }
 
 // GetBooking возвращает бронирование по ID
 func (db *DB) GetBooking(ctx context.Context, id int64) (*models.Booking, error) {
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE id = ?`
+
+	booking, err := scanBooking(db.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &booking, nil
+}
+
+// UpdateBookingStatus обновляет статус бронирования и записывает изменение в историю
+func (db *DB) UpdateBookingStatus(ctx context.Context, id int64, status string, managerID int64) error {
+	query := `UPDATE bookings SET status = ?, updated_at = ?, version = version + 1 WHERE id = ?`
+
+	return db.execBookingUpdate(ctx, id, query, []interface{}{status, time.Now(), id}, false,
+		bookingChange{managerID: managerID, toStatus: status})
+}
+
+// SetBookingManagerNote сохраняет внутреннюю заметку менеджера к заявке
+func (db *DB) SetBookingManagerNote(ctx context.Context, id int64, note string) error {
+	query := `UPDATE bookings SET manager_note = ?, updated_at = ?, version = version + 1 WHERE id = ?`
+	_, err := db.db.ExecContext(ctx, query, note, time.Now(), id)
+	return err
+}
+
+// SetBookingDepositPaid отмечает, что клиент внес залог по заявке
+func (db *DB) SetBookingDepositPaid(ctx context.Context, id int64, managerID int64) error {
+	query := `UPDATE bookings SET deposit_paid = 1, updated_at = ?, version = version + 1 WHERE id = ?`
+
+	return db.execBookingUpdate(ctx, id, query, []interface{}{time.Now(), id}, false,
+		bookingChange{managerID: managerID, details: "залог внесен"})
+}
+
+// SetBookingAwaitingApprovalWithVersion записывает первое подтверждение заявки: она ждет подтверждения
+// другого менеджера (аппараты с requires_dual_approval), если ее версия не изменилась
+func (db *DB) SetBookingAwaitingApprovalWithVersion(ctx context.Context, id int64, version int64, managerID int64) error {
+	query := `UPDATE bookings SET status = 'awaiting_approval', first_approved_by = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?`
+
+	return db.execBookingUpdate(ctx, id, query, []interface{}{managerID, time.Now(), id, version}, true,
+		bookingChange{managerID: managerID, toStatus: "awaiting_approval", details: "первое подтверждение"})
+}
+
+// UpdateBookingStatusWithVersion обновляет статус бронирования, если его версия не изменилась
+func (db *DB) UpdateBookingStatusWithVersion(ctx context.Context, id int64, version int64, status string, managerID int64) error {
+	query := `UPDATE bookings SET status = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?`
+
+	return db.execBookingUpdate(ctx, id, query, []interface{}{status, time.Now(), id, version}, true,
+		bookingChange{managerID: managerID, toStatus: status})
+}
+
+// UpdateBookingDateWithVersion переносит бронирование на другую дату, если его версия не изменилась
+func (db *DB) UpdateBookingDateWithVersion(ctx context.Context, id int64, version int64, date, endDate time.Time, managerID int64) error {
+	// После переноса напоминания отправляются заново
+	query := `UPDATE bookings SET date = ?, end_date = ?, reminded_offsets = '', updated_at = ?, version = version + 1 WHERE id = ? AND version = ?`
+
+	return db.execBookingUpdate(ctx, id, query, []interface{}{date, nullableEndDate(endDate), time.Now(), id, version}, true,
+		bookingChange{managerID: managerID, details: "перенос на " + date.Format("02.01.2006")})
+}
+
+// ReassignBookingWithVersion передает бронирование другому клиенту, если его версия не изменилась.
+// userID - Telegram ID нового клиента или 0, если он неизвестен.
+func (db *DB) ReassignBookingWithVersion(ctx context.Context, id int64, version int64, userID int64, userName, userNickname, phone string, managerID int64) error {
 	query := `
-        SELECT id, user_id, user_name, user_nickname, phone, item_id, item_name, date, status, comment, created_at, updated_at
-        FROM bookings WHERE id = ?
+        UPDATE bookings
+        SET user_id = ?, user_name = ?, user_nickname = ?, phone = ?, updated_at = ?, version = version + 1
+        WHERE id = ? AND version = ?
     `
 
-	var booking models.Booking
-	err := db.db.QueryRowContext(ctx, query, id).Scan(
-		&booking.ID,
-		&booking.UserID,
-		&booking.UserName,
-		&booking.UserNickname,
-		&booking.Phone,
-		&booking.ItemID,
-		&booking.ItemName,
-		&booking.Date,
-		&booking.Status,
-		&booking.Comment,
-		&booking.CreatedAt,
-		&booking.UpdatedAt,
-	)
+	return db.execBookingUpdate(ctx, id, query, []interface{}{userID, userName, userNickname, phone, time.Now(), id, version}, true,
+		bookingChange{managerID: managerID, details: "передана клиенту " + userName + " " + phone})
+}
+
+// CountManagerCreatedBookings возвращает количество заявок, созданных каждым менеджером с указанного момента
+func (db *DB) CountManagerCreatedBookings(ctx context.Context, since time.Time) (map[int64]int, error) {
+	query := `
+        SELECT created_by_manager_id, COUNT(*)
+        FROM bookings
+        WHERE created_by_manager_id != 0 AND created_at >= ?
+        GROUP BY created_by_manager_id
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var managerID int64
+		var count int
+		if err := rows.Scan(&managerID, &count); err != nil {
+			return nil, err
+		}
+		counts[managerID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// GetBookingsByUserID возвращает все заявки пользователя за все время, новые первыми
+func (db *DB) GetBookingsByUserID(ctx context.Context, userID int64) ([]models.Booking, error) {
+	query := `
+        SELECT ` + bookingColumns + `
+        FROM bookings
+        WHERE user_id = ?
+        ORDER BY date DESC, created_at DESC
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+
+	return bookings, rows.Err()
+}
+
+// CountActiveUserBookings возвращает количество активных (pending/awaiting_approval/confirmed) заявок пользователя,
+// которые еще не закончились
+func (db *DB) CountActiveUserBookings(ctx context.Context, userID int64) (int, error) {
+	query := `
+        SELECT COUNT(*)
+        FROM bookings
+        WHERE user_id = ?
+        AND status IN ('pending', 'awaiting_approval', 'confirmed')
+        AND date(COALESCE(end_date, date)) >= date(?)
+    `
+
+	var count int
+	err := db.db.QueryRowContext(ctx, query, userID, time.Now().Format("2006-01-02")).Scan(&count)
+	return count, err
+}
 
+// FindActiveBooking возвращает активную заявку пользователя на аппарат с той же датой начала
+// или nil, если такой нет
+func (db *DB) FindActiveBooking(ctx context.Context, userID, itemID int64, date time.Time) (*models.Booking, error) {
+	query := `
+        SELECT ` + bookingColumns + `
+        FROM bookings
+        WHERE user_id = ? AND item_id = ?
+        AND date(date) = date(?)
+        AND status IN ('pending', 'awaiting_approval', 'confirmed')
+        ORDER BY id
+        LIMIT 1
+    `
+
+	booking, err := scanBooking(db.db.QueryRowContext(ctx, query, userID, itemID, date.Format("2006-01-02")))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -224,31 +729,83 @@ func (db *DB) GetBooking(ctx context.Context, id int64) (*models.Booking, error)
 	return &booking, nil
 }
 
-// UpdateBookingStatus обновляет статус бронирования
-func (db *DB) UpdateBookingStatus(ctx context.Context, id int64, status string) error {
-	query := `UPDATE bookings SET status = ? WHERE id = ?`
+// GaugeCounts текущие значения для метрик Prometheus
+type GaugeCounts struct {
+	PendingBookings  int
+	ConfirmedToday   int
+	ActiveUsers      int
+	BlacklistedUsers int
+}
 
-	_, err := db.db.ExecContext(ctx, query, status, id)
-	return err
+// GetGaugeCounts считает заявки в ожидании, подтвержденные заявки на today,
+// пользователей с активностью после activeSince и заблокированных пользователей
+func (db *DB) GetGaugeCounts(ctx context.Context, today, activeSince time.Time) (GaugeCounts, error) {
+	query := `
+        SELECT
+            (SELECT COUNT(*) FROM bookings WHERE status IN ('pending', 'awaiting_approval')),
+            (SELECT COUNT(*) FROM bookings
+                WHERE status = 'confirmed'
+                AND date(date) <= date(?)
+                AND date(COALESCE(end_date, date)) >= date(?)),
+            (SELECT COUNT(*) FROM users WHERE last_activity >= ?),
+            (SELECT COUNT(*) FROM users WHERE is_blacklisted = 1)
+    `
+
+	todayStr := today.Format("2006-01-02")
+	var counts GaugeCounts
+	err := db.db.QueryRowContext(ctx, query, todayStr, todayStr, activeSince).Scan(
+		&counts.PendingBookings,
+		&counts.ConfirmedToday,
+		&counts.ActiveUsers,
+		&counts.BlacklistedUsers,
+	)
+	return counts, err
+}
+
+// GetStalePendingBookings возвращает заявки в статусе pending, созданные раньше olderThan
+func (db *DB) GetStalePendingBookings(ctx context.Context, olderThan time.Time) ([]models.Booking, error) {
+	query := `
+        SELECT ` + bookingColumns + `
+        FROM bookings
+        WHERE status = 'pending' AND created_at < ?
+        ORDER BY created_at
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, olderThan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+
+	return bookings, rows.Err()
 }
 
-// GetBookingsByDateRange возвращает бронирования за период
+// GetBookingsByDateRange возвращает бронирования, пересекающиеся с периодом
 func (db *DB) GetBookingsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.Booking, error) {
 	log.Printf("GetBookingsByDateRange: запрос от %s до %s",
 		startDate.Format("2006-01-02"),
 		endDate.Format("2006-01-02"))
 
 	query := `
-        SELECT id, user_id, user_name, user_nickname, phone, item_id, item_name, 
-               date, status, comment, created_at, updated_at
+        SELECT ` + bookingColumns + `
         FROM bookings 
-        WHERE strftime('%Y-%m-%d', date) BETWEEN ? AND ?
+        WHERE strftime('%Y-%m-%d', date) <= ?
+        AND strftime('%Y-%m-%d', COALESCE(end_date, date)) >= ?
         ORDER BY date, created_at
     `
 
 	rows, err := db.db.QueryContext(ctx, query,
-		startDate.Format("2006-01-02"),
-		endDate.Format("2006-01-02"))
+		endDate.Format("2006-01-02"),
+		startDate.Format("2006-01-02"))
 	if err != nil {
 		log.Printf("Ошибка в GetBookingsByDateRange: %v", err)
 		return nil, err
@@ -258,21 +815,7 @@ func (db *DB) GetBookingsByDateRange(ctx context.Context, startDate, endDate tim
 	var bookings []models.Booking
 	count := 0
 	for rows.Next() {
-		var booking models.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.UserID,
-			&booking.UserName,
-			&booking.UserNickname,
-			&booking.Phone,
-			&booking.ItemID,
-			&booking.ItemName,
-			&booking.Date,
-			&booking.Status,
-			&booking.Comment,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
+		booking, err := scanBooking(rows)
 		if err != nil {
 			log.Printf("Ошибка при сканировании строки %d: %v", count, err)
 			return nil, err
@@ -292,11 +835,106 @@ func (db *DB) GetBookingsByDateRange(ctx context.Context, startDate, endDate tim
 	return bookings, nil
 }
 
-// GetAvailabilityForPeriod возвращает доступность на период
+// GetBookingsByPhone возвращает все бронирования клиента по номеру телефона (во всех статусах)
+func (db *DB) GetBookingsByPhone(ctx context.Context, phone string) ([]models.Booking, error) {
+	query := `
+        SELECT ` + bookingColumns + `
+        FROM bookings
+        WHERE phone = ?
+        ORDER BY date DESC, created_at DESC
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, phone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// GetBookingsByGroup возвращает заявки группы, созданной менеджером за один раз, по возрастанию даты
+func (db *DB) GetBookingsByGroup(ctx context.Context, groupID string) ([]models.Booking, error) {
+	query := `
+        SELECT ` + bookingColumns + `
+        FROM bookings
+        WHERE group_id = ?
+        ORDER BY date, id
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// CancelBookingGroup отменяет все активные заявки группы и возвращает отмененные.
+// Каждая отмена записывается в историю заявки; при ошибке уже отмененные заявки возвращаются вместе с ней.
+func (db *DB) CancelBookingGroup(ctx context.Context, groupID string, managerID int64) ([]models.Booking, error) {
+	bookings, err := db.GetBookingsByGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE bookings SET status = ?, updated_at = ?, version = version + 1 WHERE id = ? AND status IN ('pending', 'awaiting_approval', 'confirmed', 'changed', 'rescheduled')`
+
+	var cancelled []models.Booking
+	for _, booking := range bookings {
+		if booking.Status == "cancelled" || booking.Status == "completed" {
+			continue
+		}
+
+		err := db.execBookingUpdate(ctx, booking.ID, query, []interface{}{"cancelled", time.Now(), booking.ID}, true,
+			bookingChange{managerID: managerID, toStatus: "cancelled", details: "отмена всей группы"})
+		if errors.Is(err, ErrConcurrentModification) {
+			// Заявку уже отменили или завершили отдельно
+			continue
+		}
+		if err != nil {
+			return cancelled, err
+		}
+
+		booking.Status = "cancelled"
+		cancelled = append(cancelled, booking)
+	}
+
+	return cancelled, nil
+}
+
+// GetAvailabilityForPeriod возвращает доступность на период: сколько единиц занято и сколько осталось на каждый день
 func (db *DB) GetAvailabilityForPeriod(ctx context.Context, itemID int64, startDate time.Time, days int) ([]models.Availability, error) {
 	var availability []models.Availability
 
-	item, exists := db.items[itemID]
+	item, exists := db.getItem(itemID)
 	if !exists {
 		return nil, fmt.Errorf("item with ID %d not found", itemID)
 	}
@@ -308,11 +946,16 @@ func (db *DB) GetAvailabilityForPeriod(ctx context.Context, itemID int64, startD
 			return nil, err
 		}
 
+		available := item.TotalQuantity - int64(booked)
+		if available < 0 {
+			available = 0
+		}
+
 		availability = append(availability, models.Availability{
 			Date:      currentDate,
 			ItemID:    itemID,
 			Booked:    int64(booked),
-			Available: item.TotalQuantity - int64(booked),
+			Available: available,
 		})
 	}
 
@@ -320,11 +963,11 @@ func (db *DB) GetAvailabilityForPeriod(ctx context.Context, itemID int64, startD
 }
 
 // UpdateBookingItem обновляет данные о бронировании товара
-func (db *DB) UpdateBookingItem(ctx context.Context, id int64, itemID int64, itemName string) error {
-	query := `UPDATE bookings SET item_id = ?, item_name = ?, updated_at = ? WHERE id = ?`
+func (db *DB) UpdateBookingItem(ctx context.Context, id int64, itemID int64, itemName string, managerID int64) error {
+	query := `UPDATE bookings SET item_id = ?, item_name = ?, updated_at = ?, version = version + 1 WHERE id = ?`
 
-	_, err := db.db.ExecContext(ctx, query, itemID, itemName, time.Now(), id)
-	return err
+	return db.execBookingUpdate(ctx, id, query, []interface{}{itemID, itemName, time.Now(), id}, false,
+		bookingChange{managerID: managerID, details: "аппарат изменен на " + itemName})
 }
 
 // GetUserBookings возвращает список всех бронирований пользователя
@@ -333,7 +976,7 @@ func (db *DB) GetUserBookings(ctx context.Context, userID int64) ([]models.Booki
 	twoWeeksAgo := time.Now().AddDate(0, 0, -14)
 
 	query := `
-        SELECT id, user_id, user_name, user_nickname, phone, item_id, item_name, date, status, comment, created_at, updated_at
+        SELECT ` + bookingColumns + `
         FROM bookings 
         WHERE user_id = ? AND date >= ?
         ORDER BY created_at DESC
@@ -347,21 +990,7 @@ func (db *DB) GetUserBookings(ctx context.Context, userID int64) ([]models.Booki
 
 	var bookings []models.Booking
 	for rows.Next() {
-		var booking models.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.UserID,
-			&booking.UserName,
-			&booking.UserNickname,
-			&booking.Phone,
-			&booking.ItemID,
-			&booking.ItemName,
-			&booking.Date,
-			&booking.Status,
-			&booking.Comment,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
+		booking, err := scanBooking(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -382,7 +1011,7 @@ func (db *DB) GetBookingWithAvailability(ctx context.Context, bookingID int64, n
 		return nil, false, err
 	}
 
-	available, err := db.CheckAvailability(ctx, newItemID, booking.Date)
+	available, err := db.CheckAvailabilityForPeriod(ctx, newItemID, booking.Date, booking.LastDate(), booking.ID)
 	if err != nil {
 		return nil, false, err
 	}
@@ -390,17 +1019,36 @@ func (db *DB) GetBookingWithAvailability(ctx context.Context, bookingID int64, n
 	return booking, available, nil
 }
 
-// GetDailyBookings возвращает бронирования по дням для периода
-func (db *DB) GetDailyBookings(ctx context.Context, startDate, endDate time.Time) (map[string][]models.Booking, error) {
+// GetDailyBookings возвращает бронирования по дням для периода.
+// При includeArchived в выборку попадают и заявки из архива.
+func (db *DB) GetDailyBookings(ctx context.Context, startDate, endDate time.Time, includeArchived bool) (map[string][]models.Booking, error) {
 	bookings, err := db.GetBookingsByDateRange(ctx, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 
+	if includeArchived {
+		archived, err := db.GetArchivedBookingsByDateRange(ctx, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, archived...)
+	}
+
+	// Многодневная заявка попадает в каждый свой день внутри периода
+	periodStart := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+
 	dailyBookings := make(map[string][]models.Booking)
 	for _, booking := range bookings {
-		dateKey := booking.Date.Format("2006-01-02")
-		dailyBookings[dateKey] = append(dailyBookings[dateKey], booking)
+		for date := booking.Date; !date.After(booking.LastDate()); date = date.AddDate(0, 0, 1) {
+			day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+			if day.Before(periodStart) || day.After(periodEnd) {
+				continue
+			}
+			dateKey := date.Format("2006-01-02")
+			dailyBookings[dateKey] = append(dailyBookings[dateKey], booking)
+		}
 	}
 
 	return dailyBookings, nil
@@ -480,9 +1128,54 @@ func (db *DB) UpdateUserPhone(ctx context.Context, telegramID int64, phone strin
 	return err
 }
 
-// UpdateUserActivity обновляет время последней активности
+// SetUserBlacklist включает или снимает блокировку пользователя.
+// Если пользователь еще не писал боту, для него создается запись.
+func (db *DB) SetUserBlacklist(ctx context.Context, telegramID int64, blacklisted bool) error {
+	query := `
+        INSERT INTO users (telegram_id, username, first_name, last_name, phone, language_code, is_blacklisted, last_activity, created_at, updated_at)
+        VALUES (?, '', '', '', '', '', ?, ?, ?, ?)
+        ON CONFLICT(telegram_id) DO UPDATE SET
+            is_blacklisted = excluded.is_blacklisted,
+            updated_at = excluded.updated_at
+    `
+
+	now := time.Now()
+	_, err := db.db.ExecContext(ctx, query, telegramID, blacklisted, now, now, now)
+	return err
+}
+
+// IsUserBlacklisted проверяет флаг блокировки пользователя в БД
+func (db *DB) IsUserBlacklisted(ctx context.Context, telegramID int64) (bool, error) {
+	var blacklisted bool
+	err := db.db.QueryRowContext(ctx, `SELECT is_blacklisted FROM users WHERE telegram_id = ?`, telegramID).Scan(&blacklisted)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return blacklisted, err
+}
+
+// MarkUserBlockedBot отмечает, что пользователь заблокировал бота, и уведомления ему не отправляются
+func (db *DB) MarkUserBlockedBot(ctx context.Context, telegramID int64) error {
+	query := `UPDATE users SET blocked_bot = 1, updated_at = ? WHERE telegram_id = ?`
+
+	_, err := db.db.ExecContext(ctx, query, time.Now(), telegramID)
+	return err
+}
+
+// IsUserBlockedBot проверяет, заблокировал ли пользователь бота
+func (db *DB) IsUserBlockedBot(ctx context.Context, telegramID int64) (bool, error) {
+	var blocked bool
+	err := db.db.QueryRowContext(ctx, `SELECT blocked_bot FROM users WHERE telegram_id = ?`, telegramID).Scan(&blocked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return blocked, err
+}
+
+// UpdateUserActivity обновляет время последней активности.
+// Пользователь снова пишет боту, поэтому отметка о блокировке бота снимается.
 func (db *DB) UpdateUserActivity(ctx context.Context, telegramID int64) error {
-	query := `UPDATE users SET last_activity = ?, updated_at = ? WHERE telegram_id = ?`
+	query := `UPDATE users SET last_activity = ?, updated_at = ?, blocked_bot = 0 WHERE telegram_id = ?`
 
 	_, err := db.db.ExecContext(ctx, query, time.Now(), time.Now(), telegramID)
 	return err
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// AddDeferredNotification откладывает уведомление пользователю до deliverAt
+func (db *DB) AddDeferredNotification(ctx context.Context, userID int64, text string, deliverAt time.Time) error {
+	query := `INSERT INTO deferred_notifications (user_id, text, deliver_at, created_at) VALUES (?, ?, ?, ?)`
+	_, err := db.db.ExecContext(ctx, query, userID, text, deliverAt.UTC(), time.Now().UTC())
+	return err
+}
+
+// GetDueDeferredNotifications возвращает отложенные уведомления, время доставки которых наступило
+func (db *DB) GetDueDeferredNotifications(ctx context.Context, now time.Time) ([]models.DeferredNotification, error) {
+	query := `
+        SELECT id, user_id, text, deliver_at, created_at
+        FROM deferred_notifications
+        WHERE deliver_at <= ?
+        ORDER BY deliver_at, id
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.DeferredNotification
+	for rows.Next() {
+		var n models.DeferredNotification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Text, &n.DeliverAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// DeleteDeferredNotification удаляет доставленное уведомление
+func (db *DB) DeleteDeferredNotification(ctx context.Context, id int64) error {
+	_, err := db.db.ExecContext(ctx, `DELETE FROM deferred_notifications WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+func TestFindActiveBooking(t *testing.T) {
+	db := newTestDB(t, []models.Item{
+		{ID: 1, Name: "Аппарат A", TotalQuantity: 2},
+		{ID: 2, Name: "Аппарат B", TotalQuantity: 2},
+	})
+	ctx := context.Background()
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	booking := &models.Booking{UserID: 100, UserName: "Клиент", Phone: "+79001234567",
+		ItemID: 1, ItemName: "Аппарат A", Date: date, Status: "pending"}
+	if err := db.CreateBooking(ctx, booking); err != nil {
+		t.Fatalf("CreateBooking: %v", err)
+	}
+
+	duplicate, err := db.FindActiveBooking(ctx, 100, 1, date)
+	if err != nil {
+		t.Fatalf("FindActiveBooking: %v", err)
+	}
+	if duplicate == nil || duplicate.ID != booking.ID {
+		t.Fatalf("FindActiveBooking = %+v, want booking #%d", duplicate, booking.ID)
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		itemID int64
+		date   time.Time
+	}{
+		{"other user", 200, 1, date},
+		{"other item", 100, 2, date},
+		{"other date", 100, 1, date.AddDate(0, 0, 1)},
+	}
+	for _, tt := range tests {
+		found, err := db.FindActiveBooking(ctx, tt.userID, tt.itemID, tt.date)
+		if err != nil {
+			t.Fatalf("%s: FindActiveBooking: %v", tt.name, err)
+		}
+		if found != nil {
+			t.Errorf("%s: found booking #%d, want none", tt.name, found.ID)
+		}
+	}
+
+	// Отмененная заявка не мешает оформить новую на ту же дату
+	if err := db.UpdateBookingStatus(ctx, booking.ID, "cancelled", 0); err != nil {
+		t.Fatalf("UpdateBookingStatus: %v", err)
+	}
+	found, err := db.FindActiveBooking(ctx, 100, 1, date)
+	if err != nil {
+		t.Fatalf("FindActiveBooking after cancel: %v", err)
+	}
+	if found != nil {
+		t.Errorf("cancelled booking #%d must not count as a duplicate", found.ID)
+	}
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// Перезагрузка списка аппаратов не должна мешать параллельным проверкам доступности
+// (запускать с -race)
+func TestSetItemsConcurrentWithAvailabilityChecks(t *testing.T) {
+	items := []models.Item{
+		{ID: 1, Name: "Аппарат A", TotalQuantity: 1, SharedResource: "кабинет 1"},
+		{ID: 2, Name: "Аппарат B", TotalQuantity: 1, SharedResource: "кабинет 1"},
+	}
+	db := newTestDB(t, items)
+	ctx := context.Background()
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			db.SetItems(items)
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		available, err := db.CheckAvailability(ctx, 1, date)
+		if err != nil {
+			t.Fatalf("CheckAvailability during reload: %v", err)
+		}
+		if !available {
+			t.Fatal("item 1 must stay available during reload")
+		}
+	}
+	wg.Wait()
+}
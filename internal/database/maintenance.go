@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// SetItemMaintenance переводит аппарат на обслуживание до указанной даты включительно
+func (db *DB) SetItemMaintenance(ctx context.Context, itemID int64, until time.Time) error {
+	query := `INSERT OR REPLACE INTO item_maintenance (item_id, unavailable_until) VALUES (?, ?)`
+	_, err := db.db.ExecContext(ctx, query, itemID, until)
+	return err
+}
+
+// ClearItemMaintenance возвращает аппарат с обслуживания досрочно
+func (db *DB) ClearItemMaintenance(ctx context.Context, itemID int64) error {
+	_, err := db.db.ExecContext(ctx, `DELETE FROM item_maintenance WHERE item_id = ?`, itemID)
+	return err
+}
+
+// GetItemMaintenance возвращает периоды обслуживания: ID аппарата -> последний день обслуживания
+func (db *DB) GetItemMaintenance(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := db.db.QueryContext(ctx, `SELECT item_id, unavailable_until FROM item_maintenance`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	maintenance := make(map[int64]time.Time)
+	for rows.Next() {
+		var itemID int64
+		var until time.Time
+		if err := rows.Scan(&itemID, &until); err != nil {
+			return nil, err
+		}
+		maintenance[itemID] = until
+	}
+	return maintenance, rows.Err()
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+func TestMigrateBackfillsLegacyManagerBookings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookings.db")
+	db, err := NewDB(path)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	// Заявка менеджера в старом формате: ID менеджера в user_id, created_by_manager_id не заполнен
+	legacy := &models.Booking{UserID: 555, UserName: "Клиент", Phone: "+79001234567",
+		ItemID: 1, ItemName: "Аппарат A", Date: date, Status: "confirmed", Source: models.BookingSourceManager}
+	client := &models.Booking{UserID: 100, UserName: "Клиент", Phone: "+79001234568",
+		ItemID: 1, ItemName: "Аппарат A", Date: date, Status: "confirmed", Source: models.BookingSourceUser}
+	for _, booking := range []*models.Booking{legacy, client} {
+		if err := db.CreateBooking(ctx, booking); err != nil {
+			t.Fatalf("CreateBooking: %v", err)
+		}
+	}
+	db.Close()
+
+	db, err = NewDB(path)
+	if err != nil {
+		t.Fatalf("reopen NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	got, err := db.GetBooking(ctx, legacy.ID)
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if got.CreatedByManagerID != 555 {
+		t.Errorf("legacy manager booking CreatedByManagerID = %d, want 555", got.CreatedByManagerID)
+	}
+
+	got, err = db.GetBooking(ctx, client.ID)
+	if err != nil {
+		t.Fatalf("GetBooking: %v", err)
+	}
+	if got.CreatedByManagerID != 0 {
+		t.Errorf("client booking CreatedByManagerID = %d, want 0", got.CreatedByManagerID)
+	}
+}
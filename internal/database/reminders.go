@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// GetBookingsForReminders возвращает подтвержденные заявки, начинающиеся в период с startDate по endDate
+func (db *DB) GetBookingsForReminders(ctx context.Context, startDate, endDate time.Time) ([]models.Booking, error) {
+	query := `
+        SELECT ` + bookingColumns + `
+        FROM bookings
+        WHERE status = 'confirmed'
+        AND date(date) >= date(?)
+        AND date(date) <= date(?)
+        ORDER BY date
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+// GetRemindedOffsets возвращает смещения напоминаний (например, "24h0m0s"), уже отправленных по заявке
+func (db *DB) GetRemindedOffsets(ctx context.Context, bookingID int64) ([]string, error) {
+	var reminded string
+	err := db.db.QueryRowContext(ctx, `SELECT reminded_offsets FROM bookings WHERE id = ?`, bookingID).Scan(&reminded)
+	if err != nil {
+		return nil, err
+	}
+	if reminded == "" {
+		return nil, nil
+	}
+	return strings.Split(reminded, ","), nil
+}
+
+// AddRemindedOffset отмечает напоминание по заявке отправленным, чтобы после перезапуска оно не повторилось
+func (db *DB) AddRemindedOffset(ctx context.Context, bookingID int64, offset string) error {
+	query := `
+        UPDATE bookings
+        SET reminded_offsets = CASE WHEN reminded_offsets = '' THEN ? ELSE reminded_offsets || ',' || ? END
+        WHERE id = ?
+    `
+	_, err := db.db.ExecContext(ctx, query, offset, offset, bookingID)
+	return err
+}
+
+// AcknowledgeBookingReminder отмечает, что клиент подтвердил явку кнопкой в напоминании
+func (db *DB) AcknowledgeBookingReminder(ctx context.Context, bookingID int64) error {
+	query := `UPDATE bookings SET reminder_ack_at = ?, updated_at = ?, version = version + 1 WHERE id = ?`
+
+	return db.execBookingUpdate(ctx, bookingID, query, []interface{}{time.Now(), time.Now(), bookingID}, false,
+		bookingChange{details: "клиент подтвердил явку по напоминанию"})
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// ClaimReport отмечает отчет name как отправленный за period (например, "2024-W19").
+// Возвращает false, если за этот период отчет уже отправлялся.
+func (db *DB) ClaimReport(ctx context.Context, name, period string) (bool, error) {
+	query := `
+        INSERT INTO sent_reports (name, period, sent_at) VALUES (?, ?, ?)
+        ON CONFLICT(name) DO UPDATE SET period = excluded.period, sent_at = excluded.sent_at
+        WHERE sent_reports.period != excluded.period
+    `
+
+	result, err := db.db.ExecContext(ctx, query, name, period, time.Now())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// newTestDB создает БД во временном каталоге теста
+func newTestDB(t *testing.T, items []models.Item) *DB {
+	t.Helper()
+
+	db, err := NewDB(filepath.Join(t.TempDir(), "bookings.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetItems(items)
+	return db
+}
+
+func TestCheckAvailabilitySharedResource(t *testing.T) {
+	db := newTestDB(t, []models.Item{
+		{ID: 1, Name: "Аппарат A", TotalQuantity: 1, SharedResource: "кабинет 1"},
+		{ID: 2, Name: "Аппарат B", TotalQuantity: 1, SharedResource: "кабинет 1"},
+	})
+	ctx := context.Background()
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+	otherDate := date.AddDate(0, 0, 1)
+
+	booking := &models.Booking{UserID: 100, UserName: "Клиент", Phone: "+79001234567",
+		ItemID: 1, ItemName: "Аппарат A", Date: date, Status: "confirmed"}
+	if err := db.CreateBooking(ctx, booking); err != nil {
+		t.Fatalf("CreateBooking: %v", err)
+	}
+
+	available, err := db.CheckAvailability(ctx, 2, date)
+	if err != nil {
+		t.Fatalf("CheckAvailability: %v", err)
+	}
+	if available {
+		t.Errorf("item 2 must be unavailable on %s: item 1 occupies the shared resource", date.Format("2006-01-02"))
+	}
+
+	available, err = db.CheckAvailability(ctx, 2, otherDate)
+	if err != nil {
+		t.Fatalf("CheckAvailability: %v", err)
+	}
+	if !available {
+		t.Errorf("item 2 must be available on %s", otherDate.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// EnqueueSheetTask добавляет задачу синхронизации в очередь.
+// Если такая же задача (тип и бронирование) уже ждет выполнения, новая не создается.
+// Задачи, взятые воркером (in_progress), не учитываются: он мог уже прочитать старое состояние заявки.
+// Возвращает false, если задача была объединена с существующей.
+func (db *DB) EnqueueSheetTask(ctx context.Context, taskType string, bookingID int64, payload string) (bool, error) {
+	now := time.Now()
+	query := `
+        INSERT INTO sheet_tasks (type, booking_id, status, payload, attempts, next_retry_at, last_error, created_at, updated_at)
+        SELECT ?, ?, 'pending', ?, 0, ?, '', ?, ?
+        WHERE NOT EXISTS (
+            SELECT 1 FROM sheet_tasks WHERE type = ? AND booking_id = ? AND status = 'pending'
+        )
+    `
+
+	result, err := db.db.ExecContext(ctx, query,
+		taskType, bookingID, payload, now, now, now,
+		taskType, bookingID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// GetDueSheetTasks возвращает задачи, время выполнения которых наступило
+func (db *DB) GetDueSheetTasks(ctx context.Context, limit int) ([]models.SheetTask, error) {
+	query := `
+        SELECT id, type, booking_id, status, payload, attempts, next_retry_at, last_error, created_at, updated_at
+        FROM sheet_tasks
+        WHERE status = 'pending' AND next_retry_at <= ?
+        ORDER BY next_retry_at, id
+        LIMIT ?
+    `
+
+	rows, err := db.db.QueryContext(ctx, query, time.Now(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.SheetTask
+	for rows.Next() {
+		var task models.SheetTask
+		if err := rows.Scan(
+			&task.ID,
+			&task.Type,
+			&task.BookingID,
+			&task.Status,
+			&task.Payload,
+			&task.Attempts,
+			&task.NextRetryAt,
+			&task.LastError,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// ClaimSheetTask отмечает задачу выполняемой, чтобы новые изменения заявки ставились в очередь отдельно.
+// Возвращает false, если задача уже не ждет выполнения.
+func (db *DB) ClaimSheetTask(ctx context.Context, id int64) (bool, error) {
+	query := `UPDATE sheet_tasks SET status = 'in_progress', updated_at = ? WHERE id = ? AND status = 'pending'`
+	result, err := db.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ReleaseClaimedSheetTasks возвращает в очередь задачи, выполнение которых прервал перезапуск бота
+func (db *DB) ReleaseClaimedSheetTasks(ctx context.Context) error {
+	query := `UPDATE sheet_tasks SET status = 'pending', updated_at = ? WHERE status = 'in_progress'`
+	_, err := db.db.ExecContext(ctx, query, time.Now())
+	return err
+}
+
+// MarkSheetTaskDone отмечает задачу выполненной
+func (db *DB) MarkSheetTaskDone(ctx context.Context, id int64) error {
+	query := `UPDATE sheet_tasks SET status = 'done', updated_at = ? WHERE id = ?`
+	_, err := db.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+// MarkSheetTaskFailed увеличивает счетчик попыток и откладывает задачу до nextRetryAt.
+// При final задача больше не выполняется.
+func (db *DB) MarkSheetTaskFailed(ctx context.Context, id int64, nextRetryAt time.Time, lastError string, final bool) error {
+	status := "pending"
+	if final {
+		status = "failed"
+	}
+
+	query := `
+        UPDATE sheet_tasks
+        SET status = ?, attempts = attempts + 1, next_retry_at = ?, last_error = ?, updated_at = ?
+        WHERE id = ?
+    `
+	_, err := db.db.ExecContext(ctx, query, status, nextRetryAt, lastError, time.Now(), id)
+	return err
+}
+
+// CountPendingSheetTasks возвращает количество задач, ожидающих выполнения или выполняемых
+func (db *DB) CountPendingSheetTasks(ctx context.Context) (int, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sheet_tasks WHERE status IN ('pending', 'in_progress')`).Scan(&count)
+	return count, err
+}
+
+// DeleteDoneSheetTasks удаляет выполненные задачи, обновленные раньше olderThan
+func (db *DB) DeleteDoneSheetTasks(ctx context.Context, olderThan time.Time) error {
+	_, err := db.db.ExecContext(ctx, `DELETE FROM sheet_tasks WHERE status = 'done' AND updated_at < ?`, olderThan)
+	return err
+}
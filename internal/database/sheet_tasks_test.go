@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEnqueueSheetTaskAfterClaim(t *testing.T) {
+	db := newTestDB(t, nil)
+	ctx := context.Background()
+
+	created, err := db.EnqueueSheetTask(ctx, "upsert_booking", 7, "")
+	if err != nil || !created {
+		t.Fatalf("EnqueueSheetTask = %v, %v; want created", created, err)
+	}
+
+	// Пока задача ждет, повторная постановка сливается с ней
+	if created, err := db.EnqueueSheetTask(ctx, "upsert_booking", 7, ""); err != nil || created {
+		t.Fatalf("duplicate EnqueueSheetTask = %v, %v; want merged", created, err)
+	}
+
+	tasks, err := db.GetDueSheetTasks(ctx, 10)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("GetDueSheetTasks = %d tasks, %v; want 1", len(tasks), err)
+	}
+	claimed, err := db.ClaimSheetTask(ctx, tasks[0].ID)
+	if err != nil || !claimed {
+		t.Fatalf("ClaimSheetTask = %v, %v; want claimed", claimed, err)
+	}
+	if claimed, _ := db.ClaimSheetTask(ctx, tasks[0].ID); claimed {
+		t.Fatal("task must not be claimed twice")
+	}
+
+	// Изменение заявки во время обработки ставит новую задачу
+	created, err = db.EnqueueSheetTask(ctx, "upsert_booking", 7, "")
+	if err != nil || !created {
+		t.Fatalf("EnqueueSheetTask while claimed = %v, %v; want created", created, err)
+	}
+
+	if count, err := db.CountPendingSheetTasks(ctx); err != nil || count != 2 {
+		t.Errorf("CountPendingSheetTasks = %d, %v; want 2", count, err)
+	}
+
+	// После перезапуска прерванная задача снова ждет выполнения
+	if err := db.ReleaseClaimedSheetTasks(ctx); err != nil {
+		t.Fatalf("ReleaseClaimedSheetTasks: %v", err)
+	}
+	tasks, err = db.GetDueSheetTasks(ctx, 10)
+	if err != nil || len(tasks) != 2 {
+		t.Errorf("GetDueSheetTasks after release = %d tasks, %v; want 2", len(tasks), err)
+	}
+}
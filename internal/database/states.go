@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// storedValue значение из TempData вместе с его типом, чтобы после загрузки
+// восстановить именно тот тип, который ожидают обработчики
+type storedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SaveUserState сохраняет состояние пользователя
+func (db *DB) SaveUserState(ctx context.Context, state *models.UserState) error {
+	data, err := encodeTempData(state.TempData)
+	if err != nil {
+		return fmt.Errorf("failed to encode state data: %v", err)
+	}
+
+	query := `
+        INSERT INTO user_states (user_id, current_step, temp_data, updated_at)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET
+            current_step = excluded.current_step,
+            temp_data = excluded.temp_data,
+            updated_at = excluded.updated_at
+    `
+
+	_, err = db.db.ExecContext(ctx, query, state.UserID, state.CurrentStep, data, state.UpdatedAt)
+	return err
+}
+
+// GetUserState возвращает сохраненное состояние пользователя или nil, если его нет
+func (db *DB) GetUserState(ctx context.Context, userID int64) (*models.UserState, error) {
+	query := `SELECT user_id, current_step, temp_data, updated_at FROM user_states WHERE user_id = ?`
+
+	var state models.UserState
+	var data string
+	err := db.db.QueryRowContext(ctx, query, userID).Scan(
+		&state.UserID,
+		&state.CurrentStep,
+		&data,
+		&state.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state.TempData, err = decodeTempData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode state data: %v", err)
+	}
+
+	return &state, nil
+}
+
+// DeleteUserState удаляет состояние пользователя
+func (db *DB) DeleteUserState(ctx context.Context, userID int64) error {
+	_, err := db.db.ExecContext(ctx, `DELETE FROM user_states WHERE user_id = ?`, userID)
+	return err
+}
+
+// encodeTempData сериализует TempData в JSON с сохранением типов значений
+func encodeTempData(tempData map[string]interface{}) (string, error) {
+	stored := make(map[string]storedValue, len(tempData))
+
+	for key, value := range tempData {
+		var typeName string
+		switch value.(type) {
+		case string:
+			typeName = "string"
+		case bool:
+			typeName = "bool"
+		case int:
+			typeName = "int"
+		case int64:
+			typeName = "int64"
+		case float64:
+			typeName = "float64"
+		case time.Time:
+			typeName = "time"
+		case []time.Time:
+			typeName = "times"
+		case models.Item:
+			typeName = "item"
+		default:
+			typeName = "json"
+		}
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("key %s: %v", key, err)
+		}
+		stored[key] = storedValue{Type: typeName, Value: raw}
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeTempData восстанавливает TempData из JSON, созданного encodeTempData
+func decodeTempData(data string) (map[string]interface{}, error) {
+	tempData := make(map[string]interface{})
+	if data == "" {
+		return tempData, nil
+	}
+
+	var stored map[string]storedValue
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, err
+	}
+
+	for key, sv := range stored {
+		var err error
+		switch sv.Type {
+		case "string":
+			var v string
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = v
+		case "bool":
+			var v bool
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = v
+		case "int":
+			var v int
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = v
+		case "int64":
+			var v int64
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = v
+		case "float64":
+			var v float64
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = v
+		case "time":
+			var v time.Time
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = restoreLocation(v)
+		case "times":
+			var v []time.Time
+			err = json.Unmarshal(sv.Value, &v)
+			for i := range v {
+				v[i] = restoreLocation(v[i])
+			}
+			tempData[key] = v
+		case "item":
+			var v models.Item
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = v
+		default:
+			var v interface{}
+			err = json.Unmarshal(sv.Value, &v)
+			tempData[key] = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", key, err)
+		}
+	}
+
+	return tempData, nil
+}
+
+// restoreLocation возвращает дате часовой пояс сервера. JSON хранит только смещение,
+// и без этого время из time.Local после загрузки оказывалось бы в безымянной зоне.
+// Даты в UTC (из parseDate) остаются в UTC.
+func restoreLocation(t time.Time) time.Time {
+	if t.Location() == time.UTC {
+		return t
+	}
+	if local := t.In(time.Local); local.Format("-07:00") == t.Format("-07:00") {
+		return local
+	}
+	return t
+}
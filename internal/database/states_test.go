@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+func TestUserStateRoundTrip(t *testing.T) {
+	db := newTestDB(t, nil)
+	ctx := context.Background()
+
+	loc := time.FixedZone("MSK", 3*60*60)
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, loc)
+	dates := []time.Time{date, date.AddDate(0, 0, 7), date.AddDate(0, 0, 14)}
+	item := models.Item{ID: 5, Name: "Аппарат A", TotalQuantity: 2}
+
+	err := db.SaveUserState(ctx, &models.UserState{
+		UserID:      42,
+		CurrentStep: "manager_waiting_comment",
+		TempData: map[string]interface{}{
+			"start_date":    date,
+			"dates":         dates,
+			"selected_item": item,
+			"booking_id":    int64(17),
+			"client_name":   "Иван",
+		},
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("SaveUserState: %v", err)
+	}
+
+	state, err := db.GetUserState(ctx, 42)
+	if err != nil {
+		t.Fatalf("GetUserState: %v", err)
+	}
+	if state == nil {
+		t.Fatal("state was not restored")
+	}
+	if state.CurrentStep != "manager_waiting_comment" {
+		t.Errorf("CurrentStep = %q", state.CurrentStep)
+	}
+
+	gotDate, ok := state.GetTime("start_date")
+	if !ok || !gotDate.Equal(date) {
+		t.Errorf("GetTime(start_date) = %v, %v; want %v", gotDate, ok, date)
+	}
+
+	gotDates, ok := state.GetDates("dates")
+	if !ok || len(gotDates) != len(dates) {
+		t.Fatalf("GetDates(dates) = %v, %v; want %v", gotDates, ok, dates)
+	}
+	for i := range dates {
+		if !gotDates[i].Equal(dates[i]) {
+			t.Errorf("dates[%d] = %v, want %v", i, gotDates[i], dates[i])
+		}
+	}
+
+	if gotItem, ok := state.TempData["selected_item"].(models.Item); !ok || gotItem.ID != item.ID || gotItem.Name != item.Name {
+		t.Errorf("selected_item = %#v, want %#v", state.TempData["selected_item"], item)
+	}
+	if id, ok := state.GetInt64("booking_id"); !ok || id != 17 {
+		t.Errorf("GetInt64(booking_id) = %d, %v; want 17", id, ok)
+	}
+	if name, ok := state.GetString("client_name"); !ok || name != "Иван" {
+		t.Errorf("GetString(client_name) = %q, %v; want Иван", name, ok)
+	}
+}
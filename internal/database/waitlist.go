@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+// AddToWaitlist ставит пользователя в очередь на позицию и дату.
+// Повторная запись того же пользователя игнорируется.
+func (db *DB) AddToWaitlist(ctx context.Context, userID, itemID int64, date time.Time) error {
+	query := `INSERT OR IGNORE INTO waitlist (user_id, item_id, date) VALUES (?, ?, ?)`
+	_, err := db.db.ExecContext(ctx, query, userID, itemID, date.Format("2006-01-02"))
+	return err
+}
+
+// PopWaitlist извлекает первого пользователя из очереди на позицию и дату.
+// Возвращает nil, если очередь пуста.
+func (db *DB) PopWaitlist(ctx context.Context, itemID int64, date time.Time) (*models.WaitlistEntry, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT id, user_id, item_id, date, created_at
+        FROM waitlist
+        WHERE item_id = ? AND date = ?
+        ORDER BY created_at, id
+        LIMIT 1
+    `
+
+	var entry models.WaitlistEntry
+	err = tx.QueryRowContext(ctx, query, itemID, date.Format("2006-01-02")).Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.ItemID,
+		&entry.Date,
+		&entry.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM waitlist WHERE id = ?`, entry.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,26 @@
+package events
+
+import (
+	"time"
+)
+
+// Типы событий по заявкам
+const (
+	EventBookingCreated     = "booking.created"
+	EventBookingConfirmed   = "booking.confirmed"
+	EventBookingCancelled   = "booking.cancelled"
+	EventBookingCompleted   = "booking.completed"
+	EventBookingRescheduled = "booking.rescheduled"
+)
+
+// Event событие, отправляемое во внешние системы
+type Event struct {
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Payload   interface{} `json:"payload"`
+}
+
+// EventPublisher публикует события. Реализации не должны блокировать вызывающий код.
+type EventPublisher interface {
+	PublishJSON(eventType string, payload interface{}) error
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader заголовок с HMAC-SHA256 подписью тела запроса
+const SignatureHeader = "X-Bronivik-Signature"
+
+// WebhookPublisher отправляет события POST-запросом на внешний URL
+type WebhookPublisher struct {
+	url        string
+	secret     string
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// NewWebhookPublisher создает publisher для webhooks.url.
+// Если secret не пустой, каждый запрос подписывается.
+func NewWebhookPublisher(url, secret string) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:        url,
+		secret:     secret,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		maxRetries: 5,
+		backoff:    time.Second,
+	}
+}
+
+// PublishJSON сериализует событие и отправляет его в фоне с повторными попытками
+func (p *WebhookPublisher) PublishJSON(eventType string, payload interface{}) error {
+	body, err := json.Marshal(Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	go func() {
+		err := retryWithBackoff(p.maxRetries, p.backoff, func() error {
+			return p.send(eventType, body)
+		})
+		if err != nil {
+			log.Printf("Failed to deliver webhook event %s: %v", eventType, err)
+		}
+	}()
+
+	return nil
+}
+
+// send выполняет один POST-запрос
+func (p *WebhookPublisher) send(eventType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Bronivik-Event", eventType)
+	if p.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(p.secret, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign возвращает HMAC-SHA256 подпись тела в hex
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryWithBackoff вызывает fn до attempts раз, удваивая паузу после каждой неудачи
+func retryWithBackoff(attempts int, initial time.Duration, fn func() error) error {
+	var err error
+	delay := initial
+
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if i < attempts-1 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	return err
+}
@@ -3,8 +3,11 @@ package google
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sync"
 	"time"
 
 	"bronivik/internal/models"
@@ -13,10 +16,21 @@ import (
 	"google.golang.org/api/sheets/v4"
 )
 
+// ErrBookingRowNotFound возвращается, если строки с ID заявки нет на листе Bookings
+var ErrBookingRowNotFound = errors.New("booking row not found")
+
+// bookingStatusColumn колонка статуса в строке bookingRow
+const bookingStatusColumn = "G"
+
 type SheetsService struct {
 	service         *sheets.Service
 	usersSheetID    string
 	bookingsSheetID string
+	dryRun          bool
+
+	// knownSheets кэш ID листов, уже найденных или созданных EnsureSheet
+	sheetsMu    sync.Mutex
+	knownSheets map[string]int64
 }
 
 func NewSimpleSheetsService(credentialsFile, usersSheetID, bookingsSheetID string) (*SheetsService, error) {
@@ -47,9 +61,28 @@ func NewSimpleSheetsService(credentialsFile, usersSheetID, bookingsSheetID strin
 		service:         srv,
 		usersSheetID:    usersSheetID,
 		bookingsSheetID: bookingsSheetID,
+		knownSheets:     make(map[string]int64),
 	}, nil
 }
 
+// SetDryRun включает режим, в котором запись в таблицы только логируется
+func (s *SheetsService) SetDryRun(dryRun bool) {
+	s.dryRun = dryRun
+}
+
+// logDryRun выводит в лог данные, которые были бы записаны в таблицу
+func (s *SheetsService) logDryRun(action, rangeData string, values [][]interface{}) {
+	log.Printf("[sheets dry-run] %s %s: %d rows", action, rangeData, len(values))
+
+	preview := len(values)
+	if preview > 3 {
+		preview = 3
+	}
+	for i := 0; i < preview; i++ {
+		log.Printf("[sheets dry-run]   %v", values[i])
+	}
+}
+
 // TestConnection проверяет подключение к таблице
 func (s *SheetsService) TestConnection() error {
 	// Пробуем прочитать первую ячейку таблицы пользователей
@@ -112,6 +145,11 @@ func (s *SheetsService) UpdateUsersSheet(users []*models.User) error {
 	}
 
 	// Используем Overwrite для полной замены данных
+	if s.dryRun {
+		s.logDryRun("update", rangeData, valueRange.Values)
+		return nil
+	}
+
 	_, err := s.service.Spreadsheets.Values.Update(s.usersSheetID, rangeData, valueRange).
 		ValueInputOption("RAW").
 		Do()
@@ -119,85 +157,260 @@ func (s *SheetsService) UpdateUsersSheet(users []*models.User) error {
 	return err
 }
 
-// AppendBooking добавляет новое бронирование
-func (s *SheetsService) AppendBooking(booking *models.Booking) error {
-	row := []interface{}{
-		booking.ID,
-		booking.UserID,
-		booking.ItemID,
-		booking.Date.Format("2006-01-02"),
-		booking.Status,
-		booking.UserName,
-		booking.Phone,
-		booking.ItemName,
-		booking.CreatedAt.Format("2006-01-02 15:04:05"),
-		booking.UpdatedAt.Format("2006-01-02 15:04:05"),
+// UpsertBooking обновляет строку бронирования с тем же ID или добавляет новую,
+// если такой строки еще нет. Повторная синхронизация не создает дубликатов.
+// Заявка пишется на лист года своей даты; если дату перенесли на другой год,
+// строка с листа прежнего года удаляется.
+func (s *SheetsService) UpsertBooking(booking *models.Booking) error {
+	sheetName, err := s.ensureBookingsSheet(booking.Date)
+	if err != nil {
+		return err
+	}
+
+	rowNumber, err := s.findBookingRow(sheetName, booking.ID)
+	if err != nil && !errors.Is(err, ErrBookingRowNotFound) {
+		return err
 	}
 
-	rangeData := "Bookings!A:A"
 	valueRange := &sheets.ValueRange{
-		Values: [][]interface{}{row},
+		Values: [][]interface{}{bookingRow(booking)},
+	}
+
+	if err == nil {
+		rangeData := sheetRange(sheetName, fmt.Sprintf("A%d", rowNumber))
+
+		if s.dryRun {
+			s.logDryRun("update", rangeData, valueRange.Values)
+			return nil
+		}
+
+		_, err = s.service.Spreadsheets.Values.Update(s.bookingsSheetID, rangeData, valueRange).
+			ValueInputOption("RAW").
+			Do()
+		if err != nil {
+			return fmt.Errorf("failed to update booking row: %v", err)
+		}
+		return nil
 	}
 
-	_, err := s.service.Spreadsheets.Values.Append(s.bookingsSheetID, rangeData, valueRange).
+	if s.dryRun {
+		s.logDryRun("append", sheetRange(sheetName, "A:A"), valueRange.Values)
+		return nil
+	}
+
+	_, err = s.service.Spreadsheets.Values.Append(s.bookingsSheetID, sheetRange(sheetName, "A:A"), valueRange).
 		ValueInputOption("RAW").
 		InsertDataOption("INSERT_ROWS").
 		Do()
+	if err != nil {
+		return fmt.Errorf("failed to append booking row: %v", err)
+	}
 
-	return err
+	return s.removeBookingFromOtherSheets(booking.ID, sheetName)
 }
 
-// UpdateBookingsSheet обновляет всю таблицу бронирований
-func (s *SheetsService) UpdateBookingsSheet(bookings []*models.Booking) error {
-	var values [][]interface{}
+// removeBookingFromOtherSheets удаляет строку заявки со всех годовых листов, кроме keepSheet.
+// Строка остается на старом листе, когда дату заявки переносят на другой год.
+func (s *SheetsService) removeBookingFromOtherSheets(bookingID int64, keepSheet string) error {
+	sheetNames, err := s.bookingsSheetNames()
+	if err != nil {
+		return err
+	}
 
-	// Заголовки
-	headers := []interface{}{"ID", "User ID", "Item ID", "Date", "Status", "User Name", "User Phone", "Item Name", "Created At", "Updated At"}
-	values = append(values, headers)
+	for _, sheetName := range sheetNames {
+		if sheetName == keepSheet {
+			continue
+		}
 
-	// Данные бронирований
-	for _, booking := range bookings {
-		row := []interface{}{
-			booking.ID,
-			booking.UserID,
-			booking.ItemID,
-			booking.Date.Format("2006-01-02"),
-			booking.Status,
-			booking.UserName,
-			booking.Phone,
-			booking.ItemName,
-			booking.CreatedAt.Format("2006-01-02 15:04:05"),
-			booking.UpdatedAt.Format("2006-01-02 15:04:05"),
+		rowNumber, err := s.findBookingRow(sheetName, bookingID)
+		if errors.Is(err, ErrBookingRowNotFound) {
+			continue
 		}
-		values = append(values, row)
+		if err != nil {
+			return err
+		}
+
+		if s.dryRun {
+			s.logDryRun("delete", sheetRange(sheetName, fmt.Sprintf("A%d", rowNumber)), nil)
+			continue
+		}
+
+		sheetID, err := s.EnsureSheet(sheetName, nil)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.service.Spreadsheets.BatchUpdate(s.bookingsSheetID, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{{
+				DeleteDimension: &sheets.DeleteDimensionRequest{
+					Range: &sheets.DimensionRange{
+						SheetId:    sheetID,
+						Dimension:  "ROWS",
+						StartIndex: int64(rowNumber - 1),
+						EndIndex:   int64(rowNumber),
+					},
+				},
+			}},
+		}).Do()
+		if err != nil {
+			return fmt.Errorf("failed to delete booking row from %q: %v", sheetName, err)
+		}
+		log.Printf("Booking %d removed from sheet %q after moving to %q", bookingID, sheetName, keepSheet)
 	}
 
-	// Полностью очищаем и перезаписываем лист
-	rangeData := "Bookings!A1:J" + fmt.Sprintf("%d", len(values))
+	return nil
+}
+
+// UpdateBookingStatus обновляет только ячейку статуса в строке заявки на листе года date.
+// Если строки нет, возвращает ErrBookingRowNotFound.
+func (s *SheetsService) UpdateBookingStatus(bookingID int64, date time.Time, status string) error {
+	sheetName := BookingsSheetName(date)
+	rowNumber, err := s.findBookingRow(sheetName, bookingID)
+	if err != nil {
+		return err
+	}
+
+	rangeData := sheetRange(sheetName, fmt.Sprintf("%s%d", bookingStatusColumn, rowNumber))
 	valueRange := &sheets.ValueRange{
-		Values: values,
+		Values: [][]interface{}{{status}},
 	}
 
-	_, err := s.service.Spreadsheets.Values.Update(s.bookingsSheetID, rangeData, valueRange).
+	if s.dryRun {
+		s.logDryRun("update", rangeData, valueRange.Values)
+		return nil
+	}
+
+	_, err = s.service.Spreadsheets.Values.Update(s.bookingsSheetID, rangeData, valueRange).
 		ValueInputOption("RAW").
 		Do()
+	if err != nil {
+		return fmt.Errorf("failed to update booking status: %v", err)
+	}
 
-	return err
+	return nil
 }
 
-// UpdateScheduleSheet обновляет лист с расписанием бронирований в формате таблицы
-func (s *SheetsService) UpdateScheduleSheet(startDate, endDate time.Time, dailyBookings map[string][]models.Booking, items []models.Item) error {
-	// Получаем ID листа "Бронирования"
-	sheetId, err := s.GetSheetIdByName(s.bookingsSheetID, "Бронирования")
+// findBookingRow ищет номер строки (с 1) заявки на листе sheetName по колонке ID
+func (s *SheetsService) findBookingRow(sheetName string, bookingID int64) (int, error) {
+	resp, err := s.service.Spreadsheets.Values.Get(s.bookingsSheetID, sheetRange(sheetName, "A:A")).Do()
 	if err != nil {
-		return fmt.Errorf("unable to get sheet ID: %v", err)
+		if isSheetMissingError(err) {
+			return 0, ErrBookingRowNotFound
+		}
+		return 0, fmt.Errorf("failed to read booking ids: %v", err)
 	}
 
-	// Очищаем весь лист "Бронирования"
-	clearRange := "Бронирования!A:Z"
-	_, err = s.service.Spreadsheets.Values.Clear(s.bookingsSheetID, clearRange, &sheets.ClearValuesRequest{}).Do()
+	id := fmt.Sprintf("%d", bookingID)
+	for i, cells := range resp.Values {
+		if len(cells) > 0 && fmt.Sprintf("%v", cells[0]) == id {
+			return i + 1, nil
+		}
+	}
+
+	return 0, ErrBookingRowNotFound
+}
+
+// bookingRow формирует строку годового листа заявок в том же формате, что и ReplaceBookingsSheet
+func bookingRow(booking *models.Booking) []interface{} {
+	return []interface{}{
+		booking.ID,
+		booking.UserID,
+		booking.UserName,
+		booking.Phone,
+		booking.ItemName,
+		booking.Date.Format("02.01.2006"),
+		booking.Status,
+		booking.Comment,
+		booking.CreatedAt.Format("02.01.2006 15:04"),
+		booking.UpdatedAt.Format("02.01.2006 15:04"),
+		booking.Source,
+		bookingPriceCell(booking.Price),
+		bookingDepositCell(booking),
+		booking.SecondaryPhone,
+		bookingEndDateCell(booking),
+	}
+}
+
+// bookingEndDateCell последний день многодневной заявки; для однодневной ячейка пустая
+func bookingEndDateCell(booking *models.Booking) string {
+	if booking.EndDate.IsZero() || !booking.EndDate.After(booking.Date) {
+		return ""
+	}
+	return booking.EndDate.Format("02.01.2006")
+}
+
+// bookingPriceCell оставляет ячейку цены пустой, если у аппарата нет цены
+func bookingPriceCell(price int64) interface{} {
+	if price == 0 {
+		return ""
+	}
+	return price
+}
+
+// bookingDepositCell отмечает внесенный залог; для заявок без цены ячейка пустая
+func bookingDepositCell(booking *models.Booking) string {
+	switch {
+	case booking.DepositPaid:
+		return "да"
+	case booking.Price > 0:
+		return "нет"
+	default:
+		return ""
+	}
+}
+
+// ScheduleProgress вызывается после каждого месяца синхронизации расписания:
+// сколько дней периода обработано из total
+type ScheduleProgress func(done, total int)
+
+// UpdateScheduleSheet обновляет расписание бронирований в формате таблицы.
+// Каждый месяц периода пишется на свой лист (ScheduleSheetName), недостающие листы создаются.
+// Дни из closedDays (ключ - дата в формате 2006-01-02) без заявок отмечаются как выходные.
+// Ошибка одного месяца не останавливает остальные: возвращается общая ошибка со всеми неудачными листами.
+func (s *SheetsService) UpdateScheduleSheet(startDate, endDate time.Time, dailyBookings map[string][]models.Booking, closedDays map[string]bool, items []models.Item, progress ScheduleProgress) error {
+	total := int(endDate.Sub(startDate).Hours()/24) + 1
+	done := 0
+
+	var errs []error
+	monthStart := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, startDate.Location())
+	for ; !monthStart.After(endDate); monthStart = monthStart.AddDate(0, 1, 0) {
+		from := monthStart
+		if from.Before(startDate) {
+			from = startDate
+		}
+		to := monthStart.AddDate(0, 1, -1)
+		if to.After(endDate) {
+			to = endDate
+		}
+
+		sheetName := ScheduleSheetName(monthStart)
+		if err := s.updateScheduleMonthSheet(sheetName, from, to, dailyBookings, closedDays, items); err != nil {
+			log.Printf("Failed to update schedule sheet %q: %v", sheetName, err)
+			errs = append(errs, fmt.Errorf("%s: %v", sheetName, err))
+		}
+
+		done += int(to.Sub(from).Hours()/24) + 1
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// updateScheduleMonthSheet перезаписывает лист расписания за один месяц
+func (s *SheetsService) updateScheduleMonthSheet(sheetName string, startDate, endDate time.Time, dailyBookings map[string][]models.Booking, closedDays map[string]bool, items []models.Item) error {
+	sheetId, err := s.EnsureSheet(sheetName, nil)
 	if err != nil {
-		return fmt.Errorf("unable to clear sheet: %v", err)
+		return fmt.Errorf("unable to get sheet ID: %v", err)
+	}
+
+	// Очищаем весь лист месяца
+	clearRange := sheetRange(sheetName, "A:Z")
+	if !s.dryRun {
+		_, err = s.service.Spreadsheets.Values.Clear(s.bookingsSheetID, clearRange, &sheets.ClearValuesRequest{}).Do()
+		if err != nil {
+			return fmt.Errorf("unable to clear sheet: %v", err)
+		}
 	}
 
 	var data [][]interface{}
@@ -322,7 +535,7 @@ func (s *SheetsService) UpdateScheduleSheet(startDate, endDate time.Time, dailyB
 					switch booking.Status {
 					case "confirmed", "completed":
 						status = "✅"
-					case "pending", "changed":
+					case "pending", "awaiting_approval", "changed":
 						status = "⏳"
 					case "cancelled":
 						status = "❌"
@@ -341,7 +554,7 @@ func (s *SheetsService) UpdateScheduleSheet(startDate, endDate time.Time, dailyB
 				// Проверяем статусы активных заявок
 				hasUnconfirmed := false
 				for _, booking := range activeBookings {
-					if booking.Status == "pending" || booking.Status == "changed" {
+					if booking.Status == "pending" || booking.Status == "awaiting_approval" || booking.Status == "changed" {
 						hasUnconfirmed = true
 						break
 					}
@@ -379,6 +592,14 @@ func (s *SheetsService) UpdateScheduleSheet(startDate, endDate time.Time, dailyB
 						}
 					}
 				}
+			} else if closedDays[dateKey] {
+				// Выходной день - серая заливка
+				cellValue = "🚫 Выходной"
+				backgroundColor = &sheets.Color{
+					Red:   0.85,
+					Green: 0.85,
+					Blue:  0.85,
+				}
 			} else {
 				// Нет активных заявок - без заливки
 				cellValue = "Свободно\n\nДоступно: " + fmt.Sprintf("%d/%d", item.TotalQuantity, item.TotalQuantity)
@@ -464,11 +685,16 @@ func (s *SheetsService) UpdateScheduleSheet(startDate, endDate time.Time, dailyB
 	}
 
 	// Записываем данные в лист
-	rangeData := "Бронирования!A1"
+	rangeData := sheetRange(sheetName, "A1")
 	valueRange := &sheets.ValueRange{
 		Values: data,
 	}
 
+	if s.dryRun {
+		s.logDryRun("replace", rangeData, data)
+		return nil
+	}
+
 	_, err = s.service.Spreadsheets.Values.Update(s.bookingsSheetID, rangeData, valueRange).
 		ValueInputOption("RAW").
 		Do()
@@ -573,47 +799,134 @@ func (s *SheetsService) GetSheetIdByName(spreadID, sheetName string) (int64, err
 		}
 	}
 
-	return 0, fmt.Errorf("sheet '%s' not found", sheetName)
+	return 0, fmt.Errorf("sheet '%s': %w", sheetName, ErrSheetNotFound)
 }
 
-// ReplaceBookingsSheet полностью перезаписывает лист с заявками
+// ReplaceBookingsSheet обновляет на годовых листах строки переданных заявок.
+// Заявка пишется на лист года своей даты: существующая строка заменяется на месте, новая добавляется в конец.
+// Строки остальных заявок не трогаются, поэтому история за год сохраняется, даже если
+// синхронизируется только часть периода. Строка заявки, перенесенной на другой год, удаляется со старого листа.
 func (s *SheetsService) ReplaceBookingsSheet(bookings []*models.Booking) error {
-	// Очищаем весь лист (кроме заголовков)
-	clearRange := "Bookings!A2:Z" // Предполагая, что заголовки в строке 1
-	clearReq := &sheets.ClearValuesRequest{}
+	batch := bookingsSheetSync{
+		rows:    make(map[string][]interface{}),
+		sheetOf: make(map[string]string),
+	}
 
-	_, err := s.service.Spreadsheets.Values.Clear(s.bookingsSheetID, clearRange, clearReq).Do()
+	var sheetNames []string
+	seen := make(map[string]bool)
+	for _, booking := range bookings {
+		id := fmt.Sprintf("%d", booking.ID)
+		sheetName := BookingsSheetName(booking.Date)
+		if _, ok := batch.rows[id]; !ok {
+			batch.order = append(batch.order, id)
+		}
+		batch.rows[id] = bookingRow(booking)
+		batch.sheetOf[id] = sheetName
+		if !seen[sheetName] {
+			seen[sheetName] = true
+			sheetNames = append(sheetNames, sheetName)
+		}
+	}
+
+	// Остальные годовые листы проверяются только на строки заявок, перенесенных на другой год
+	existing, err := s.bookingsSheetNames()
 	if err != nil {
-		return fmt.Errorf("failed to clear bookings sheet: %v", err)
+		return err
+	}
+	for _, sheetName := range existing {
+		if !seen[sheetName] {
+			seen[sheetName] = true
+			sheetNames = append(sheetNames, sheetName)
+		}
+	}
+
+	for _, sheetName := range sheetNames {
+		if err := s.mergeBookingsYearSheet(sheetName, batch); err != nil {
+			return err
+		}
 	}
 
-	// Подготавливаем данные для записи
+	return nil
+}
+
+// bookingsSheetSync строки заявок для ReplaceBookingsSheet, ключ - ID заявки
+type bookingsSheetSync struct {
+	order   []string
+	rows    map[string][]interface{}
+	sheetOf map[string]string
+}
+
+// merge возвращает строки листа sheetName после замены строк заявок из batch и признак изменений.
+// Строки других заявок сохраняются в прежнем порядке, новые заявки добавляются в конец.
+func (batch bookingsSheetSync) merge(sheetName string, existing [][]interface{}) ([][]interface{}, bool) {
 	var values [][]interface{}
-	for _, booking := range bookings {
-		row := []interface{}{
-			booking.ID,
-			booking.UserID,
-			booking.UserName,
-			booking.Phone,
-			booking.ItemName,
-			booking.Date.Format("02.01.2006"),
-			booking.Status,
-			booking.Comment,
-			booking.CreatedAt.Format("02.01.2006 15:04"),
-			booking.UpdatedAt.Format("02.01.2006 15:04"),
+	written := make(map[string]bool)
+	changed := false
+	for _, cells := range existing {
+		id := ""
+		if len(cells) > 0 {
+			id = fmt.Sprintf("%v", cells[0])
+		}
+
+		target, ok := batch.sheetOf[id]
+		switch {
+		case !ok:
+			values = append(values, cells)
+		case target == sheetName && !written[id]:
+			values = append(values, batch.rows[id])
+			written[id] = true
+			changed = true
+		default:
+			// Заявка перенесена на лист другого года или строка продублирована
+			changed = true
 		}
-		values = append(values, row)
 	}
 
-	// Записываем все данные
-	valueRange := &sheets.ValueRange{
-		Values: values,
+	for _, id := range batch.order {
+		if batch.sheetOf[id] == sheetName && !written[id] {
+			values = append(values, batch.rows[id])
+			changed = true
+		}
+	}
+
+	return values, changed
+}
+
+// mergeBookingsYearSheet заменяет на листе sheetName строки заявок из batch, остальные строки оставляет как есть.
+// Лист перезаписывается, только если в нем что-то изменилось.
+func (s *SheetsService) mergeBookingsYearSheet(sheetName string, batch bookingsSheetSync) error {
+	if _, err := s.EnsureSheet(sheetName, bookingsHeaders); err != nil {
+		return err
+	}
+
+	resp, err := s.service.Spreadsheets.Values.Get(s.bookingsSheetID, sheetRange(sheetName, "A2:Z")).
+		ValueRenderOption("UNFORMATTED_VALUE").Do()
+	if err != nil {
+		return fmt.Errorf("failed to read bookings sheet %q: %v", sheetName, err)
+	}
+
+	values, changed := batch.merge(sheetName, resp.Values)
+	if !changed {
+		return nil
+	}
+
+	if s.dryRun {
+		s.logDryRun("replace", sheetRange(sheetName, "A2"), values)
+		return nil
+	}
+
+	// Строк могло стать меньше, поэтому сначала очищаем лист (кроме заголовков)
+	_, err = s.service.Spreadsheets.Values.Clear(s.bookingsSheetID, sheetRange(sheetName, "A2:Z"), &sheets.ClearValuesRequest{}).Do()
+	if err != nil {
+		return fmt.Errorf("failed to clear bookings sheet %q: %v", sheetName, err)
 	}
 
-	_, err = s.service.Spreadsheets.Values.Update(s.bookingsSheetID, "Bookings!A2", valueRange).
+	// Заголовки пишутся заново, чтобы на старых листах появлялись новые колонки
+	values = append([][]interface{}{bookingsHeaders}, values...)
+	_, err = s.service.Spreadsheets.Values.Update(s.bookingsSheetID, sheetRange(sheetName, "A1"), &sheets.ValueRange{Values: values}).
 		ValueInputOption("RAW").Do()
 	if err != nil {
-		return fmt.Errorf("failed to update bookings sheet: %v", err)
+		return fmt.Errorf("failed to update bookings sheet %q: %v", sheetName, err)
 	}
 
 	return nil
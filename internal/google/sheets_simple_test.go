@@ -0,0 +1,69 @@
+package google
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"bronivik/internal/models"
+)
+
+func TestBookingsSheetSyncMergeKeepsHistory(t *testing.T) {
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+	moved := &models.Booking{ID: 3, Date: time.Date(2031, 1, 5, 0, 0, 0, 0, time.UTC), Status: "confirmed"}
+	updated := &models.Booking{ID: 2, Date: date, Status: "confirmed"}
+	added := &models.Booking{ID: 4, Date: date, Status: "pending"}
+
+	batch := bookingsSheetSync{rows: map[string][]interface{}{}, sheetOf: map[string]string{}}
+	for _, booking := range []*models.Booking{updated, moved, added} {
+		id := fmt.Sprintf("%d", booking.ID)
+		batch.order = append(batch.order, id)
+		batch.rows[id] = bookingRow(booking)
+		batch.sheetOf[id] = BookingsSheetName(booking.Date)
+	}
+
+	// На листе 2030 года: старая заявка вне периода синхронизации, обновляемая и перенесенная на 2031 год
+	existing := [][]interface{}{
+		{float64(1), "история"},
+		{float64(2), "старая версия"},
+		{float64(3), "перенесена"},
+	}
+
+	values, changed := batch.merge(BookingsSheetName(date), existing)
+	if !changed {
+		t.Fatal("merge must report changes")
+	}
+	want := [][]interface{}{
+		{float64(1), "история"},
+		bookingRow(updated),
+		bookingRow(added),
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("merged 2030 rows = %v, want %v", values, want)
+	}
+
+	// Лист без заявок из периода не очищается и не перезаписывается
+	history := [][]interface{}{{float64(10), "2029"}}
+	values, changed = batch.merge(BookingsSheetName(date.AddDate(-1, 0, 0)), history)
+	if changed || !reflect.DeepEqual(values, history) {
+		t.Errorf("unrelated sheet changed: %v, %v", values, changed)
+	}
+}
+
+func TestBookingRowEndDate(t *testing.T) {
+	date := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	row := bookingRow(&models.Booking{ID: 1, Date: date, EndDate: date.AddDate(0, 0, 2)})
+	if len(row) != len(bookingsHeaders) {
+		t.Fatalf("row has %d cells, headers have %d", len(row), len(bookingsHeaders))
+	}
+	if got := row[len(row)-1]; got != "12.03.2030" {
+		t.Errorf("end date cell = %v, want 12.03.2030", got)
+	}
+
+	row = bookingRow(&models.Booking{ID: 2, Date: date})
+	if got := row[len(row)-1]; got != "" {
+		t.Errorf("single-day end date cell = %v, want empty", got)
+	}
+}
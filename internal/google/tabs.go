@@ -0,0 +1,126 @@
+package google
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/sheets/v4"
+)
+
+// ErrSheetNotFound возвращается, если в таблице нет листа с указанным названием
+var ErrSheetNotFound = errors.New("sheet not found")
+
+// bookingsSheetPrefix начало названия годовых листов с заявками: "Bookings 2024"
+const bookingsSheetPrefix = "Bookings "
+
+// bookingsHeaders заголовки годового листа заявок, колонки совпадают с bookingRow
+var bookingsHeaders = []interface{}{"ID", "User ID", "User Name", "User Phone", "Item Name", "Date", "Status", "Comment", "Created At", "Updated At", "Source", "Price", "Deposit Paid", "Secondary Phone", "End Date"}
+
+var scheduleMonthNames = []string{
+	"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь",
+}
+
+// BookingsSheetName возвращает название листа с заявками за год даты бронирования
+func BookingsSheetName(date time.Time) string {
+	return fmt.Sprintf("%s%d", bookingsSheetPrefix, date.Year())
+}
+
+// ScheduleSheetName возвращает название листа расписания за месяц: "Бронирования Май 2024"
+func ScheduleSheetName(date time.Time) string {
+	return fmt.Sprintf("Бронирования %s %d", scheduleMonthNames[date.Month()-1], date.Year())
+}
+
+// sheetRange формирует диапазон в нотации A1; название листа берется в кавычки, так как содержит пробелы
+func sheetRange(sheetName, cells string) string {
+	return fmt.Sprintf("'%s'!%s", strings.ReplaceAll(sheetName, "'", "''"), cells)
+}
+
+// EnsureSheet возвращает ID листа, создавая его при отсутствии.
+// В новый лист записываются заголовки, если они переданы.
+func (s *SheetsService) EnsureSheet(sheetName string, headers []interface{}) (int64, error) {
+	s.sheetsMu.Lock()
+	defer s.sheetsMu.Unlock()
+
+	if sheetID, ok := s.knownSheets[sheetName]; ok {
+		return sheetID, nil
+	}
+
+	sheetID, err := s.GetSheetIdByName(s.bookingsSheetID, sheetName)
+	if err == nil {
+		s.knownSheets[sheetName] = sheetID
+		return sheetID, nil
+	}
+	if !errors.Is(err, ErrSheetNotFound) {
+		return 0, err
+	}
+
+	if s.dryRun {
+		log.Printf("[sheets dry-run] create sheet %q", sheetName)
+		return 0, nil
+	}
+
+	resp, err := s.service.Spreadsheets.BatchUpdate(s.bookingsSheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			AddSheet: &sheets.AddSheetRequest{
+				Properties: &sheets.SheetProperties{Title: sheetName},
+			},
+		}},
+	}).Do()
+	if err != nil {
+		return 0, fmt.Errorf("unable to create sheet %q: %v", sheetName, err)
+	}
+	sheetID = resp.Replies[0].AddSheet.Properties.SheetId
+	log.Printf("Created sheet %q in bookings spreadsheet", sheetName)
+
+	if len(headers) > 0 {
+		_, err = s.service.Spreadsheets.Values.Update(s.bookingsSheetID, sheetRange(sheetName, "A1"),
+			&sheets.ValueRange{Values: [][]interface{}{headers}}).
+			ValueInputOption("RAW").
+			Do()
+		if err != nil {
+			return 0, fmt.Errorf("unable to write headers to sheet %q: %v", sheetName, err)
+		}
+	}
+
+	s.knownSheets[sheetName] = sheetID
+	return sheetID, nil
+}
+
+// ensureBookingsSheet возвращает название годового листа для заявки, создавая лист при необходимости
+func (s *SheetsService) ensureBookingsSheet(date time.Time) (string, error) {
+	sheetName := BookingsSheetName(date)
+	if _, err := s.EnsureSheet(sheetName, bookingsHeaders); err != nil {
+		return "", err
+	}
+	return sheetName, nil
+}
+
+// bookingsSheetNames возвращает названия всех годовых листов с заявками
+func (s *SheetsService) bookingsSheetNames() ([]string, error) {
+	spreadsheet, err := s.service.Spreadsheets.Get(s.bookingsSheetID).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get spreadsheet: %v", err)
+	}
+
+	var names []string
+	for _, sheet := range spreadsheet.Sheets {
+		if strings.HasPrefix(sheet.Properties.Title, bookingsSheetPrefix) {
+			names = append(names, sheet.Properties.Title)
+		}
+	}
+	return names, nil
+}
+
+// isSheetMissingError проверяет ответ Google API на диапазон несуществующего листа
+func isSheetMissingError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == 400 && strings.Contains(apiErr.Message, "Unable to parse range")
+}
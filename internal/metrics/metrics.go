@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Операции синхронизации с Google Sheets
+const (
+	SheetsOperationAppend   = "append"
+	SheetsOperationReplace  = "replace"
+	SheetsOperationSchedule = "schedule"
+	SheetsOperationStatus   = "status"
+)
+
+var (
+	// SheetsSyncDuration длительность операций с Google Sheets
+	SheetsSyncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sheets_sync_duration_seconds",
+		Help:    "Duration of Google Sheets sync operations",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+
+	// SheetsSyncFailures количество неудачных операций с Google Sheets
+	SheetsSyncFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sheets_sync_failures_total",
+		Help: "Total number of failed Google Sheets sync operations",
+	}, []string{"operation"})
+
+	// SheetsQueueDepth количество задач в очереди синхронизации с Google Sheets
+	SheetsQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sheets_queue_depth",
+		Help: "Number of pending Google Sheets sync tasks",
+	})
+
+	// BookingsPending количество заявок, ожидающих решения менеджера
+	BookingsPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "bookings_pending",
+		Help: "Number of bookings waiting for a manager decision",
+	})
+
+	// BookingsConfirmedToday количество подтвержденных заявок на сегодня
+	BookingsConfirmedToday = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "bookings_confirmed_today",
+		Help: "Number of confirmed bookings covering today",
+	})
+
+	// UsersActive количество пользователей, активных за последние 30 дней
+	UsersActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "users_active_30d",
+		Help: "Number of users active in the last 30 days",
+	})
+
+	// UsersBlacklisted количество заблокированных пользователей
+	UsersBlacklisted = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "users_blacklisted",
+		Help: "Number of blacklisted users",
+	})
+
+	registerOnce sync.Once
+)
+
+// Register регистрирует метрики в реестре Prometheus по умолчанию
+func Register() {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(SheetsSyncDuration, SheetsSyncFailures, SheetsQueueDepth,
+			BookingsPending, BookingsConfirmedToday, UsersActive, UsersBlacklisted)
+	})
+}
+
+// ObserveSheetsSync записывает длительность операции и, при ошибке, увеличивает счетчик сбоев
+func ObserveSheetsSync(operation string, start time.Time, err error) {
+	SheetsSyncDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		SheetsSyncFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// Serve запускает HTTP сервер с эндпоинтом /metrics в отдельной горутине
+func Serve(port int) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	addr := fmt.Sprintf(":%d", port)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Prometheus metrics server stopped: %v", err)
+		}
+	}()
+	log.Printf("Prometheus metrics available on %s/metrics", addr)
+}
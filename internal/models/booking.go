@@ -3,16 +3,74 @@ package models
 import "time"
 
 type Booking struct {
-	ID           int64     `json:"id"`
-	UserID       int64     `json:"user_id"`
-	UserName     string    `json:"user_name"`
-	UserNickname string    `json:"user_nickname"`
-	Phone        string    `json:"phone"`
-	ItemID       int64     `json:"item_id"`
-	ItemName     string    `json:"item_name"`
-	Date         time.Time `json:"date"`
-	Status       string    `json:"status"` // pending, confirmed, cancelled, changed, completed
-	Comment      string    `json:"comment"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                 int64     `json:"id"`
+	UserID             int64     `json:"user_id"`
+	UserName           string    `json:"user_name"`
+	UserNickname       string    `json:"user_nickname"`
+	Phone              string    `json:"phone"`
+	SecondaryPhone     string    `json:"secondary_phone,omitempty"` // Запасной номер клиента (необязательный)
+	ItemID             int64     `json:"item_id"`
+	ItemName           string    `json:"item_name"`
+	Date               time.Time `json:"date"`
+	EndDate            time.Time `json:"end_date,omitzero"` // Последний день многодневной заявки (нулевое значение - один день)
+	Status             string    `json:"status"`            // pending, awaiting_approval, confirmed, cancelled, changed, completed
+	Comment            string    `json:"comment"`
+	ManagerNote        string    `json:"-"`                            // Видна только менеджерам, поэтому не попадает в JSON (вебхуки)
+	CreatedByManagerID int64     `json:"created_by_manager_id"`        // Менеджер, оформивший заявку вручную (0 - заявка клиента)
+	Source             string    `json:"source"`                       // Откуда пришла заявка: BookingSourceUser, BookingSourceManager или BookingSourceAPI
+	GroupID            string    `json:"group_id,omitempty"`           // Общий ID заявок, созданных менеджером на несколько дат за один раз
+	AttachmentFileID   string    `json:"attachment_file_id,omitempty"` // Telegram FileID фото или документа, присланного клиентом
+	AttachmentType     string    `json:"attachment_type,omitempty"`    // AttachmentPhoto или AttachmentDocument
+	Price              int64     `json:"price,omitempty"`              // Стоимость заявки по Item.Price на момент создания (0 - цена не задана)
+	DepositPaid        bool      `json:"deposit_paid,omitempty"`       // Клиент внес залог, отмечается менеджером
+	ReminderAckAt      time.Time `json:"reminder_ack_at,omitzero"`     // Когда клиент подтвердил явку кнопкой в напоминании (нулевое значение - не подтверждал)
+	FirstApprovedBy    int64     `json:"first_approved_by,omitempty"`  // Менеджер, первым подтвердивший заявку на аппарат с двойным подтверждением
+	Version            int64     `json:"version"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// Источники заявки для Booking.Source
+const (
+	BookingSourceUser    = "user"    // клиент через бота
+	BookingSourceManager = "manager" // менеджер вручную
+	BookingSourceAPI     = "api"     // внешние интеграции (сайт)
+)
+
+// Типы вложений для Booking.AttachmentType
+const (
+	AttachmentPhoto    = "photo"
+	AttachmentDocument = "document"
+)
+
+// LastDate возвращает последний день заявки: EndDate для многодневной заявки, иначе Date
+func (b Booking) LastDate() time.Time {
+	if b.EndDate.IsZero() || b.EndDate.Before(b.Date) {
+		return b.Date
+	}
+	return b.EndDate
+}
+
+// Days возвращает количество дней заявки
+func (b Booking) Days() int {
+	return int(b.LastDate().Sub(b.Date).Hours()/24) + 1
+}
+
+type WaitlistEntry struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	ItemID    int64     `json:"item_id"`
+	Date      time.Time `json:"date"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookingEvent запись журнала изменений заявки
+type BookingEvent struct {
+	ID         int64     `json:"id"`
+	BookingID  int64     `json:"booking_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ManagerID  int64     `json:"manager_id"` // 0 - клиент или автоматическое действие
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"created_at"`
 }
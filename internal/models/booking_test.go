@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBookingJSONOmitsManagerNote(t *testing.T) {
+	data, err := json.Marshal(Booking{ID: 1, ManagerNote: "клиент просил скидку"})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if strings.Contains(string(data), "manager_note") || strings.Contains(string(data), "скидку") {
+		t.Errorf("manager note leaked into JSON: %s", data)
+	}
+}
+
+func TestBookingJSONOmitsZeroTimes(t *testing.T) {
+	data, err := json.Marshal(Booking{ID: 1})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	for _, field := range []string{"end_date", "reminder_ack_at"} {
+		if strings.Contains(string(data), `"`+field+`"`) {
+			t.Errorf("zero %s must be omitted: %s", field, data)
+		}
+	}
+
+	endDate := time.Date(2030, 3, 12, 0, 0, 0, 0, time.UTC)
+	data, err = json.Marshal(Booking{ID: 1, EndDate: endDate})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"end_date":"2030-03-12T00:00:00Z"`) {
+		t.Errorf("end_date missing from JSON: %s", data)
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DeferredNotification уведомление, отложенное до конца тихих часов (notifications.quiet_hours)
+type DeferredNotification struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Text      string    `json:"text"`
+	DeliverAt time.Time `json:"deliver_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
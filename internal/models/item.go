@@ -1,9 +1,32 @@
 package models
 
+import "time"
+
 type Item struct {
 	ID            int64  `yaml:"id"`
 	Name          string `yaml:"name"`
 	Description   string `yaml:"description"`
 	TotalQuantity int64  `yaml:"total_quantity"`
 	Order         int    `yaml:"order" json:"order"`
+	PhotoURL      string `yaml:"photo_url" json:"photo_url"`
+	Category      string `yaml:"category" json:"category,omitempty"`
+	// Price стоимость аренды за день, 0 - цена не указана и в заявках не показывается
+	Price int64 `yaml:"price" json:"price,omitempty"`
+	// SharedResource общий ресурс (например, кабинет): заявка на любой аппарат группы занимает дату для остальных
+	SharedResource string `yaml:"shared_resource" json:"shared_resource,omitempty"`
+	// RequiresDualApproval заявку подтверждают два разных менеджера
+	RequiresDualApproval bool `yaml:"requires_dual_approval" json:"requires_dual_approval,omitempty"`
+	// MinAdvanceDays и MaxAdvanceDays переопределяют booking.min_advance_days/max_advance_days для аппарата
+	MinAdvanceDays *int `yaml:"min_advance_days" json:"min_advance_days,omitempty"`
+	MaxAdvanceDays *int `yaml:"max_advance_days" json:"max_advance_days,omitempty"`
+	// UnavailableUntil последний день обслуживания аппарата, задается командой /item_maintenance
+	UnavailableUntil time.Time `yaml:"-" json:"unavailable_until,omitzero"`
+}
+
+// InMaintenance проверяет, что дата попадает в период обслуживания аппарата
+func (i Item) InMaintenance(date time.Time) bool {
+	if i.UnavailableUntil.IsZero() {
+		return false
+	}
+	return date.Format("2006-01-02") <= i.UnavailableUntil.Format("2006-01-02")
 }
@@ -6,6 +6,41 @@ type UserState struct {
 	UserID      int64
 	CurrentStep string
 	TempData    map[string]interface{}
+	UpdatedAt   time.Time
+}
+
+// GetTime возвращает дату из TempData; false, если ключа нет или там значение другого типа
+func (s *UserState) GetTime(key string) (time.Time, bool) {
+	value, ok := s.TempData[key].(time.Time)
+	return value, ok
+}
+
+// GetInt64 возвращает число из TempData; false, если ключа нет или там не целое число.
+// Принимает и float64: так выглядят числа, восстановленные из JSON без сохраненного типа.
+func (s *UserState) GetInt64(key string) (int64, bool) {
+	switch value := s.TempData[key].(type) {
+	case int64:
+		return value, true
+	case int:
+		return int64(value), true
+	case float64:
+		if value == float64(int64(value)) {
+			return int64(value), true
+		}
+	}
+	return 0, false
+}
+
+// GetString возвращает строку из TempData; false, если ключа нет или там значение другого типа
+func (s *UserState) GetString(key string) (string, bool) {
+	value, ok := s.TempData[key].(string)
+	return value, ok
+}
+
+// GetDates возвращает список дат из TempData; false, если ключа нет, он пуст или там значение другого типа
+func (s *UserState) GetDates(key string) ([]time.Time, bool) {
+	value, ok := s.TempData[key].([]time.Time)
+	return value, ok && len(value) > 0
 }
 
 type Availability struct {
@@ -0,0 +1,47 @@
+package models
+
+import "testing"
+
+func TestUserStateGetInt64(t *testing.T) {
+	state := &UserState{TempData: map[string]interface{}{
+		"int64":    int64(42),
+		"int":      7,
+		"float":    float64(123),
+		"fraction": 1.5,
+		"string":   "42",
+	}}
+
+	tests := []struct {
+		key    string
+		want   int64
+		wantOK bool
+	}{
+		{"int64", 42, true},
+		{"int", 7, true},
+		{"float", 123, true},
+		{"fraction", 0, false},
+		{"string", 0, false},
+		{"missing", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := state.GetInt64(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetInt64(%q) = %d, %v; want %d, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestUserStateGetString(t *testing.T) {
+	state := &UserState{TempData: map[string]interface{}{"name": "Иван", "id": int64(1)}}
+
+	if got, ok := state.GetString("name"); !ok || got != "Иван" {
+		t.Errorf("GetString(name) = %q, %v; want Иван, true", got, ok)
+	}
+	if _, ok := state.GetString("id"); ok {
+		t.Error("GetString(id) must fail for a non-string value")
+	}
+	if _, ok := state.GetString("missing"); ok {
+		t.Error("GetString(missing) must fail")
+	}
+}
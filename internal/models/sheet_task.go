@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SheetTask задача синхронизации с Google Sheets из очереди sheet_tasks
+type SheetTask struct {
+	ID          int64     `json:"id"`
+	Type        string    `json:"type"` // upsert_booking, sync_bookings, sync_schedule
+	BookingID   int64     `json:"booking_id"`
+	Status      string    `json:"status"` // pending, in_progress, done, failed
+	Payload     string    `json:"payload"`
+	Attempts    int       `json:"attempts"`
+	NextRetryAt time.Time `json:"next_retry_at"`
+	LastError   string    `json:"last_error"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
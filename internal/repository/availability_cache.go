@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisAvailabilityCache хранит количество занятых единиц аппарата на дату в Redis.
+// TTL короткий: даже если инвалидация не дошла, устаревшие данные быстро истекут.
+type RedisAvailabilityCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisAvailabilityCache создает кэш доступности поверх клиента Redis
+func NewRedisAvailabilityCache(client *redis.Client, ttl time.Duration) *RedisAvailabilityCache {
+	return &RedisAvailabilityCache{client: client, ttl: ttl}
+}
+
+func availabilityKey(itemID int64, date time.Time) string {
+	return fmt.Sprintf("availability:%d:%s", itemID, date.Format("2006-01-02"))
+}
+
+// GetBookedCount возвращает закэшированное значение; false - значения нет или Redis недоступен
+func (c *RedisAvailabilityCache) GetBookedCount(ctx context.Context, itemID int64, date time.Time) (int, bool) {
+	value, err := c.client.Get(ctx, availabilityKey(itemID, date)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Error reading availability cache: %v", err)
+		}
+		return 0, false
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return count, true
+}
+
+// SetBookedCount сохраняет количество занятых единиц на время TTL
+func (c *RedisAvailabilityCache) SetBookedCount(ctx context.Context, itemID int64, date time.Time, count int) {
+	if err := c.client.Set(ctx, availabilityKey(itemID, date), count, c.ttl).Err(); err != nil {
+		log.Printf("Error writing availability cache: %v", err)
+	}
+}
+
+// Invalidate удаляет закэшированные значения аппарата на указанные даты
+func (c *RedisAvailabilityCache) Invalidate(ctx context.Context, itemID int64, dates []time.Time) {
+	if len(dates) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(dates))
+	for _, date := range dates {
+		keys = append(keys, availabilityKey(itemID, date))
+	}
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Error invalidating availability cache: %v", err)
+	}
+}